@@ -8,8 +8,12 @@ import (
 	"website-scraper/internal/config"
 	"website-scraper/internal/crawler"
 	"website-scraper/internal/downloader"
+	"website-scraper/internal/monitor"
 	"website-scraper/internal/parser"
+	"website-scraper/internal/proxy"
 	"website-scraper/internal/repo"
+	"website-scraper/internal/retention"
+	"website-scraper/internal/storage"
 	"website-scraper/internal/templates"
 )
 
@@ -21,8 +25,12 @@ func main() {
 		repo.Module,
 		templates.Module,
 		parser.Module,
+		proxy.Module,
+		storage.Module,
 		downloader.Module,
 		crawler.Module,
+		monitor.Module,
+		retention.Module,
 		routes.Module,
 		app.Module,
 	)
@@ -2,12 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net/http"
-	"os"
-	"path/filepath"
+	"net/url"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -16,11 +18,17 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/xuri/excelize/v2"
 
+	"website-scraper/internal/auth"
 	"website-scraper/internal/config"
 	"website-scraper/internal/crawler"
 	"website-scraper/internal/downloader"
 	"website-scraper/internal/models"
+	"website-scraper/internal/monitor"
 	"website-scraper/internal/parser"
+	"website-scraper/internal/proxy"
+	"website-scraper/internal/repo"
+	"website-scraper/internal/retention"
+	"website-scraper/internal/storage"
 )
 
 // Handlers представляет набор всех обработчиков
@@ -28,40 +36,103 @@ type Handlers struct {
 	config         *config.Config
 	parserService  parser.ParserService
 	crawlerService crawler.CrawlerService
+	monitorService monitor.MonitorService
+	retention      retention.RetentionService
+	downloader     *downloader.Downloader
+	storage        storage.Storage
 }
 
 // NewHandlers создает новый экземпляр Handlers
-func NewHandlers(cfg *config.Config, parserService parser.ParserService, crawlerService crawler.CrawlerService) *Handlers {
+func NewHandlers(cfg *config.Config, parserService parser.ParserService, crawlerService crawler.CrawlerService, monitorService monitor.MonitorService, retentionService retention.RetentionService, downloader *downloader.Downloader, files storage.Storage) *Handlers {
 	return &Handlers{
 		config:         cfg,
 		parserService:  parserService,
 		crawlerService: crawlerService,
+		monitorService: monitorService,
+		retention:      retentionService,
+		downloader:     downloader,
+		storage:        files,
 	}
 }
 
-// ParseURL обрабатывает запрос на парсинг URL
-func (h *Handlers) ParseURL(w http.ResponseWriter, r *http.Request) {
-	var req models.ParseURLRequest
+// maxUploadSize ограничивает размер страницы или архива, переданного в /parse
+const maxUploadSize = 50 << 20
 
+// ParseURL обрабатывает запрос на парсинг URL или переданной страницы
+func (h *Handlers) ParseURL(w http.ResponseWriter, r *http.Request) {
 	// Декодируем тело запроса
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		RespondWithError(w, http.StatusBadRequest, "Некорректное тело запроса")
+	req, upload, err := decodeParseRequest(w, r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Некорректное тело запроса: "+err.Error())
 		return
 	}
 
+	// При воспроизведении HAR адрес страницы можно взять из записи
+	if upload == nil && req.ReplayHAR != nil {
+		har, err := h.downloader.LoadHAR(r.Context(), *req.ReplayHAR)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, "Ошибка воспроизведения HAR: "+err.Error())
+			return
+		}
+		if req.URL == "" {
+			req.URL = har.PageURL()
+		}
+	}
+
 	// Проверяем URL
-	if req.URL == "" {
+	if upload == nil && req.URL == "" {
 		RespondWithError(w, http.StatusBadRequest, "URL обязателен")
 		return
 	}
 
-	// Вызываем сервис для парсинга URL
-	operationID, err := h.parserService.ParseURL(r.Context(), req.URL)
-	if err != nil {
-		RespondWithError(w, http.StatusInternalServerError, "Ошибка при парсинге URL: "+err.Error())
+	// Логин и пароль из URL не должны попасть в операцию
+	req.URL, req.Auth = auth.StripCredentials(req.URL, req.Auth)
+	if err := auth.Validate(req.Auth); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Некорректные учетные данные: "+err.Error())
+		return
+	}
+	if req.Auth != nil && req.Auth.Login != nil && req.FetchStrategy == models.FetchHTTP {
+		RespondWithError(w, http.StatusBadRequest, "Сценарий входа выполняется только в браузере (fetch_strategy browser или auto)")
+		return
+	}
+
+	if err := proxy.Validate(req.Proxy); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Некорректный прокси: "+err.Error())
+		return
+	}
+
+	// Проверяем способ загрузки страницы
+	if req.FetchStrategy != "" && !downloader.ValidFetchStrategy(req.FetchStrategy) {
+		RespondWithError(w, http.StatusBadRequest, "Неизвестный способ загрузки: "+string(req.FetchStrategy)+" (допустимо: http, browser, auto)")
 		return
 	}
 
+	if err := downloader.ValidWaitOptions(req.Wait); err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var operationID uuid.UUID
+	if upload != nil {
+		// Вызываем сервис для разбора переданной страницы
+		operationID, err = h.parserService.ParseUpload(r.Context(), *upload, req.ParseOptions)
+		if errors.Is(err, parser.ErrInvalidUpload) {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "Ошибка при разборе страницы: "+err.Error())
+			return
+		}
+	} else {
+		// Вызываем сервис для парсинга URL
+		operationID, err = h.parserService.ParseURL(r.Context(), req.URL, req.ParseOptions)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "Ошибка при парсинге URL: "+err.Error())
+			return
+		}
+	}
+
 	// Формируем ответ
 	response := models.ParseURLResponse{
 		OperationID: operationID,
@@ -75,6 +146,9 @@ func (h *Handlers) ParseURL(w http.ResponseWriter, r *http.Request) {
 		"save_blocks": "/api/v1/operations/" + operationID.String() + "/blocks/save",
 		"blocks_list": "/api/v1/operations/" + operationID.String() + "/blocks",
 	}
+	if req.RecordHAR {
+		links["har"] = "/api/v1/operations/" + operationID.String() + "/har"
+	}
 
 	// Расширенный ответ
 	extendedResponse := struct {
@@ -90,6 +164,150 @@ func (h *Handlers) ParseURL(w http.ResponseWriter, r *http.Request) {
 	RespondWithJSON(w, http.StatusOK, extendedResponse)
 }
 
+// decodeParseRequest читает запрос на парсинг. Кроме JSON с URL принимаются HTML в поле html,
+// файл страницы или ZIP-архив сохраненного сайта в multipart-форме (поле file), а также
+// HTML или ZIP непосредственно в теле запроса с адресом страницы в параметре url
+func decodeParseRequest(w http.ResponseWriter, r *http.Request) (*models.ParseURLRequest, *models.UploadedPage, error) {
+	var req models.ParseURLRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	query := r.URL.Query()
+
+	switch mediaType {
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+			return nil, nil, err
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return nil, nil, fmt.Errorf("не передан файл в поле file")
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		req.URL = r.FormValue("url")
+		if req.ParseOptions, err = uploadParseOptions(r.FormValue); err != nil {
+			return nil, nil, err
+		}
+		return &req, &models.UploadedPage{
+			Filename: header.Filename,
+			Data:     data,
+			URL:      req.URL,
+			Entry:    r.FormValue("entry"),
+		}, nil
+
+	case "text/html", "application/xhtml+xml", "application/zip", "application/x-zip-compressed":
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		req.URL = query.Get("url")
+		if req.ParseOptions, err = uploadParseOptions(query.Get); err != nil {
+			return nil, nil, err
+		}
+		return &req, &models.UploadedPage{
+			Data:  data,
+			URL:   req.URL,
+			Entry: query.Get("entry"),
+		}, nil
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, nil, err
+	}
+
+	if req.HTML != "" {
+		return &req, &models.UploadedPage{Data: []byte(req.HTML), URL: req.URL}, nil
+	}
+
+	return &req, nil, nil
+}
+
+// uploadParseOptions читает параметры разбора страницы, переданной файлом или телом запроса:
+// все параметры /parse в JSON из поля options и, для краткости, флаг mirror_assets
+func uploadParseOptions(value func(string) string) (models.ParseOptions, error) {
+	var opts models.ParseOptions
+	if raw := value("options"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts); err != nil {
+			return opts, fmt.Errorf("некорректные параметры options: %w", err)
+		}
+	}
+	if mirror, err := strconv.ParseBool(value("mirror_assets")); err == nil {
+		opts.MirrorAssets = mirror
+	}
+	return opts, nil
+}
+
+// defaultOperationsPage и maxOperationsPage задают число операций на странице списка
+const (
+	defaultOperationsPage = 50
+	maxOperationsPage     = 200
+)
+
+// ListOperations возвращает страницу списка операций с фильтрами по статусу, адресу, сайту,
+// платформе и времени создания. Следующая страница запрашивается с курсором next_cursor
+func (h *Handlers) ListOperations(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := models.OperationListQuery{
+		Status:   models.OperationStatus(params.Get("status")),
+		URL:      params.Get("url"),
+		Domain:   params.Get("domain"),
+		Platform: models.Platform(params.Get("platform")),
+		Sort:     models.OperationSort(params.Get("sort")),
+		Limit:    defaultOperationsPage,
+	}
+
+	switch query.Status {
+	case "", models.StatusPending, models.StatusProcessing, models.StatusCompleted, models.StatusError:
+	default:
+		RespondWithError(w, http.StatusBadRequest, "Неверный статус: "+string(query.Status)+" (допустимо: pending, processing, completed, error)")
+		return
+	}
+
+	switch query.Sort {
+	case "", models.SortCreatedDesc, models.SortCreatedAsc, models.SortURLAsc, models.SortURLDesc:
+	default:
+		RespondWithError(w, http.StatusBadRequest, "Неверная сортировка: "+string(query.Sort)+" (допустимо: -created_at, created_at, url, -url)")
+		return
+	}
+
+	var err error
+	if value := params.Get("limit"); value != "" {
+		query.Limit, err = strconv.Atoi(value)
+		if err != nil || query.Limit <= 0 || query.Limit > maxOperationsPage {
+			RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("limit должен быть от 1 до %d", maxOperationsPage))
+			return
+		}
+	}
+	if query.From, err = parseSearchTime(params.Get("from"), false); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Неверная дата from: укажите YYYY-MM-DD или RFC 3339")
+		return
+	}
+	if query.To, err = parseSearchTime(params.Get("to"), true); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Неверная дата to: укажите YYYY-MM-DD или RFC 3339")
+		return
+	}
+
+	response, err := h.parserService.ListOperations(r.Context(), query, params.Get("cursor"))
+	if err != nil {
+		if errors.Is(err, parser.ErrInvalidCursor) {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка при получении операций: "+err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
 // GetOperationResult обрабатывает запрос на получение результатов операции
 func (h *Handlers) GetOperationResult(w http.ResponseWriter, r *http.Request) {
 	// Получаем ID операции из URL
@@ -116,6 +334,8 @@ func (h *Handlers) GetOperationResult(w http.ResponseWriter, r *http.Request) {
 		"download":    "/api/v1/download/" + operationID.String(),
 		"save_blocks": "/api/v1/operations/" + operationID.String() + "/blocks/save",
 		"blocks_list": "/api/v1/operations/" + operationID.String() + "/blocks",
+		"products":    "/api/v1/operations/" + operationID.String() + "/products/export",
+		"assets":      "/api/v1/operations/" + operationID.String() + "/assets",
 	}
 
 	// Расширенный ответ
@@ -130,6 +350,330 @@ func (h *Handlers) GetOperationResult(w http.ResponseWriter, r *http.Request) {
 	RespondWithJSON(w, http.StatusOK, extendedResponse)
 }
 
+// GetOperationAssets обрабатывает запрос на получение ресурсов блоков операции
+func (h *Handlers) GetOperationAssets(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID операции из URL
+	vars := mux.Vars(r)
+	operationIDStr := vars["id"]
+
+	// Проверяем ID операции
+	operationID, err := uuid.Parse(operationIDStr)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Неверный ID операции")
+		return
+	}
+
+	result, err := h.parserService.GetOperationAssets(r.Context(), operationID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка при получении ресурсов операции: "+err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, result)
+}
+
+// ReparseOperation обрабатывает запрос на повторный разбор сохраненного HTML операции
+func (h *Handlers) ReparseOperation(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID операции из URL
+	vars := mux.Vars(r)
+	operationIDStr := vars["id"]
+
+	// Проверяем ID операции
+	operationID, err := uuid.Parse(operationIDStr)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Неверный ID операции")
+		return
+	}
+
+	// Тело запроса необязательно: из параметров парсинга используется mirror_assets
+	var opts models.ParseOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil && err != io.EOF {
+		RespondWithError(w, http.StatusBadRequest, "Некорректное тело запроса")
+		return
+	}
+
+	newOperationID, err := h.parserService.ReparseOperation(r.Context(), operationID, models.ParseOptions{MirrorAssets: opts.MirrorAssets})
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка при повторном разборе операции: "+err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"operation_id": newOperationID,
+		"parent_id":    operationID,
+		"links": map[string]string{
+			"get_result":  "/api/v1/operations/" + newOperationID.String(),
+			"blocks_list": "/api/v1/operations/" + newOperationID.String() + "/blocks",
+			"parent":      "/api/v1/operations/" + operationID.String(),
+		},
+		"message": "Повторный разбор сохраненного HTML запущен",
+	})
+}
+
+// CompareLayouts обрабатывает запрос на сравнение блоков страницы на двух устройствах.
+// Вторая операция передается параметром with
+func (h *Handlers) CompareLayouts(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID операции из URL
+	vars := mux.Vars(r)
+	operationIDStr := vars["id"]
+
+	// Проверяем ID операций
+	operationID, err := uuid.Parse(operationIDStr)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Неверный ID операции")
+		return
+	}
+
+	otherID, err := uuid.Parse(r.URL.Query().Get("with"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Укажите ID второй операции в параметре with")
+		return
+	}
+
+	comparison, err := h.parserService.CompareLayouts(r.Context(), operationID, otherID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка при сравнении операций: "+err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, comparison)
+}
+
+// DiffOperations обрабатывает запрос на сравнение блоков двух операций одной или разных страниц.
+// Параметр format=html возвращает отчет в стиле сводки блоков вместо JSON
+func (h *Handlers) DiffOperations(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	// Проверяем ID операций
+	oldID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Неверный ID операции")
+		return
+	}
+	newID, err := uuid.Parse(vars["other_id"])
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Неверный ID второй операции")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "html" {
+		RespondWithError(w, http.StatusBadRequest, "Неподдерживаемый формат: "+format+" (допустимо: json, html)")
+		return
+	}
+
+	diff, err := h.parserService.DiffOperations(r.Context(), oldID, newID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repo.ErrNotFound):
+			RespondWithError(w, http.StatusNotFound, "Операция не найдена")
+		case errors.Is(err, parser.ErrOperationNotCompleted):
+			RespondWithError(w, http.StatusConflict, err.Error())
+		default:
+			RespondWithError(w, http.StatusInternalServerError, "Ошибка при сравнении операций: "+err.Error())
+		}
+		return
+	}
+
+	if format != "html" {
+		RespondWithJSON(w, http.StatusOK, diff)
+		return
+	}
+
+	oldBlocks, err := h.parserService.GetBlocksByOperationID(r.Context(), oldID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка при получении блоков: "+err.Error())
+		return
+	}
+	newBlocks, err := h.parserService.GetBlocksByOperationID(r.Context(), newID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка при получении блоков: "+err.Error())
+		return
+	}
+
+	// HTML блоков взят с чужих сайтов: отчет запрещает скрипты, а блоки показываются в изолированных фреймах
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", downloader.DiffReportCSP)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(downloader.CreateDiffReport(diff, oldBlocks, newBlocks)))
+}
+
+// GetDuplicateReport возвращает отчет о повторяющихся блоках сайта: общие для сайта блоки,
+// блоки нескольких страниц и блоки одной страницы
+func (h *Handlers) GetDuplicateReport(w http.ResponseWriter, r *http.Request) {
+	domain := mux.Vars(r)["domain"]
+
+	report, err := h.parserService.DuplicateReport(r.Context(), domain)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка при построении отчета: "+err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, report)
+}
+
+// defaultSearchBlocks и maxSearchBlocks задают число блоков на странице результатов поиска
+const (
+	defaultSearchBlocks = 20
+	maxSearchBlocks     = 100
+)
+
+// SearchBlocks ищет блоки всех операций по тексту и фильтрам: платформе, типу блока,
+// названию шаблона, сайту и времени сохранения
+func (h *Handlers) SearchBlocks(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := models.BlockSearchQuery{
+		Query:        params.Get("q"),
+		Platform:     models.Platform(params.Get("platform")),
+		BlockType:    models.BlockType(params.Get("block_type")),
+		TemplateName: params.Get("template"),
+		Domain:       params.Get("domain"),
+		Limit:        defaultSearchBlocks,
+	}
+
+	var err error
+	if value := params.Get("limit"); value != "" {
+		query.Limit, err = strconv.Atoi(value)
+		if err != nil || query.Limit <= 0 || query.Limit > maxSearchBlocks {
+			RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("limit должен быть от 1 до %d", maxSearchBlocks))
+			return
+		}
+	}
+	if value := params.Get("offset"); value != "" {
+		query.Offset, err = strconv.Atoi(value)
+		if err != nil || query.Offset < 0 {
+			RespondWithError(w, http.StatusBadRequest, "offset должен быть неотрицательным числом")
+			return
+		}
+	}
+
+	if query.From, err = parseSearchTime(params.Get("from"), false); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Неверная дата from: укажите YYYY-MM-DD или RFC 3339")
+		return
+	}
+	if query.To, err = parseSearchTime(params.Get("to"), true); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Неверная дата to: укажите YYYY-MM-DD или RFC 3339")
+		return
+	}
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		RespondWithError(w, http.StatusBadRequest, "Дата from должна быть раньше to")
+		return
+	}
+
+	response, err := h.parserService.SearchBlocks(r.Context(), query)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка при поиске блоков: "+err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
+// parseSearchTime разбирает границу периода поиска, пустое значение не ограничивает период.
+// Дата без времени в конце периода означает весь этот день, поэтому граница сдвигается на начало следующего
+func parseSearchTime(value string, end bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if moment, err := time.Parse(time.RFC3339, value); err == nil {
+		return &moment, nil
+	}
+
+	day, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	if end {
+		day = day.AddDate(0, 0, 1)
+	}
+	return &day, nil
+}
+
+// Параметры поиска структурно похожих блоков: расстояние в битах подписи и число блоков в ответе.
+// Наибольшее расстояние задает parser.MaxStructureDistance
+const (
+	defaultStructureDistance = 4
+	defaultSimilarBlocks     = 20
+	maxSimilarBlocks         = 100
+)
+
+// FindSimilarBlocks находит блоки всех сохраненных операций с той же структурой тегов и классов,
+// что и у указанного блока, независимо от текста и изображений
+func (h *Handlers) FindSimilarBlocks(w http.ResponseWriter, r *http.Request) {
+	blockID, err := uuid.Parse(mux.Vars(r)["block_id"])
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Неверный ID блока")
+		return
+	}
+
+	params := r.URL.Query()
+	query := models.SimilarBlocksQuery{
+		MaxDistance: defaultStructureDistance,
+		Limit:       defaultSimilarBlocks,
+		BlockType:   models.BlockType(params.Get("block_type")),
+		OtherSites:  params.Get("other_sites") == "true",
+	}
+
+	if value := params.Get("max_distance"); value != "" {
+		query.MaxDistance, err = strconv.Atoi(value)
+		if err != nil || query.MaxDistance < 0 || query.MaxDistance > parser.MaxStructureDistance {
+			RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("max_distance должен быть от 0 до %d", parser.MaxStructureDistance))
+			return
+		}
+	}
+	if value := params.Get("limit"); value != "" {
+		query.Limit, err = strconv.Atoi(value)
+		if err != nil || query.Limit <= 0 || query.Limit > maxSimilarBlocks {
+			RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("limit должен быть от 1 до %d", maxSimilarBlocks))
+			return
+		}
+	}
+
+	response, err := h.parserService.SimilarBlocks(r.Context(), blockID, query)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			RespondWithError(w, http.StatusNotFound, "Блок не найден")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка при поиске похожих блоков: "+err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
+// DownloadHAR обрабатывает запрос на скачивание HAR загрузки страницы операции
+func (h *Handlers) DownloadHAR(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID операции из URL
+	vars := mux.Vars(r)
+	operationIDStr := vars["id"]
+
+	// Проверяем ID операции
+	operationID, err := uuid.Parse(operationIDStr)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Неверный ID операции")
+		return
+	}
+
+	key := downloader.HARKey(operationID)
+	if _, err := h.storage.Stat(r.Context(), key); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			RespondWithError(w, http.StatusNotFound, "HAR операции не найден. Запустите парсинг с параметром record_har")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка чтения HAR: "+err.Error())
+		return
+	}
+
+	filename := operationID.String() + ".har"
+	if wantsLink(r) {
+		h.respondWithLink(w, r, key, filename)
+		return
+	}
+	h.sendFile(w, r, key, filename, "application/json")
+}
+
 // ExportOperation обрабатывает запрос на экспорт результатов операции
 func (h *Handlers) ExportOperation(w http.ResponseWriter, r *http.Request) {
 	// Получаем ID операции из URL
@@ -386,6 +930,12 @@ func (h *Handlers) ExportOperation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// При link=true выгрузка сохраняется в хранилище и возвращается ссылка на нее
+	if wantsLink(r) {
+		h.storeAndLink(w, r, downloader.ExportKey(operationID, filename), filename, content, getContentType(format))
+		return
+	}
+
 	// Устанавливаем заголовки для скачивания файла
 	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
 	w.Header().Set("Content-Type", getContentType(format))
@@ -396,6 +946,58 @@ func (h *Handlers) ExportOperation(w http.ResponseWriter, r *http.Request) {
 	w.Write(content)
 }
 
+// ExportProducts обрабатывает запрос на экспорт товаров одной или нескольких операций. У обхода нет
+// своего идентификатора, поэтому товары обхода выгружаются по ID операций его разобранных страниц
+func (h *Handlers) ExportProducts(w http.ResponseWriter, r *http.Request) {
+	// Собираем ID операций из URL или из query параметров
+	var operationIDs []uuid.UUID
+	operationIDStrs := r.URL.Query()["operation_id"]
+	if id, ok := mux.Vars(r)["id"]; ok {
+		operationIDStrs = []string{id}
+	}
+
+	for _, idStr := range operationIDStrs {
+		for _, part := range strings.Split(idStr, ",") {
+			operationID, err := uuid.Parse(strings.TrimSpace(part))
+			if err != nil {
+				RespondWithError(w, http.StatusBadRequest, "Неверный ID операции: "+part)
+				return
+			}
+			operationIDs = append(operationIDs, operationID)
+		}
+	}
+
+	if len(operationIDs) == 0 {
+		RespondWithError(w, http.StatusBadRequest, "Не указан ID операции")
+		return
+	}
+
+	// Получаем формат экспорта из query параметров
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv" // По умолчанию CSV
+	}
+
+	if format != "csv" && format != "excel" {
+		RespondWithError(w, http.StatusBadRequest, "Неверный формат. Поддерживаемые форматы: csv, excel")
+		return
+	}
+
+	content, filename, err := h.parserService.ExportProducts(r.Context(), operationIDs, format)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка при экспорте товаров: "+err.Error())
+		return
+	}
+
+	// Устанавливаем заголовки для скачивания файла
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	w.Header().Set("Content-Type", getContentType(format))
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
 // CrawlURL обрабатывает запрос на обход URL и сбор ссылок
 func (h *Handlers) CrawlURL(w http.ResponseWriter, r *http.Request) {
 	var req models.CrawlURLRequest
@@ -412,6 +1014,22 @@ func (h *Handlers) CrawlURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Обход выполняется HTTP-клиентом, сценарий входа в нем недоступен
+	req.URL, req.Auth = auth.StripCredentials(req.URL, req.Auth)
+	if err := auth.Validate(req.Auth); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Некорректные учетные данные: "+err.Error())
+		return
+	}
+	if req.Auth != nil && req.Auth.Login != nil {
+		RespondWithError(w, http.StatusBadRequest, "Сценарий входа не поддерживается при обходе, передайте cookies сессии")
+		return
+	}
+
+	if err := proxy.Validate(req.Proxy); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Некорректный прокси: "+err.Error())
+		return
+	}
+
 	// Устанавливаем глубину обхода
 	maxDepth := h.config.Scraper.MaxDepth // По умолчанию из конфига
 	if req.MaxDepth > 0 {
@@ -448,7 +1066,7 @@ func (h *Handlers) CrawlURL(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Обходим URL
-	links, err := h.crawlerService.CrawlURL(r.Context(), req.URL, maxDepth)
+	links, err := h.crawlerService.CrawlURL(r.Context(), req.URL, maxDepth, req.CrawlOptions)
 	if err != nil {
 		RespondWithError(w, http.StatusInternalServerError, "Ошибка при обходе URL: "+err.Error())
 		return
@@ -524,6 +1142,11 @@ func (h *Handlers) DownloadByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if wantsLink(r) {
+		h.storeAndLink(w, r, downloader.ExportKey(operationID, filename), filename, content, contentType)
+		return
+	}
+
 	// Устанавливаем заголовки для скачивания файла
 	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
 	w.Header().Set("Content-Type", contentType)
@@ -582,7 +1205,11 @@ func (h *Handlers) DownloadBlock(w http.ResponseWriter, r *http.Request) {
 	// Получаем блок из базы данных
 	block, err := h.parserService.GetBlockByID(r.Context(), blockID)
 	if err != nil {
-		RespondWithError(w, http.StatusNotFound, "Блок не найден: "+err.Error())
+		if errors.Is(err, repo.ErrNotFound) {
+			RespondWithError(w, http.StatusNotFound, "Блок не найден")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка получения блока: "+err.Error())
 		return
 	}
 
@@ -592,75 +1219,11 @@ func (h *Handlers) DownloadBlock(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var data []byte
-	var filename string
-	var contentType string
-
-	if format == "html" {
-		// Создаем полный HTML документ
-		htmlContent := fmt.Sprintf(`<!DOCTYPE html>
-<html lang="ru">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Блок %s - %s</title>
-    <style>
-        body { font-family: Arial, sans-serif; line-height: 1.6; padding: 20px; }
-        .metadata { background: #f5f5f5; padding: 15px; margin-bottom: 20px; border-radius: 5px; }
-        .content { border: 1px solid #ddd; padding: 15px; margin-top: 20px; }
-        pre { background: #f8f8f8; padding: 10px; overflow-x: auto; }
-    </style>
-</head>
-<body>
-    <div class="metadata">
-        <h2>Информация о блоке</h2>
-        <ul>
-            <li><strong>ID блока:</strong> %s</li>
-            <li><strong>ID операции:</strong> %s</li>
-            <li><strong>Тип блока:</strong> %s</li>
-            <li><strong>Платформа:</strong> %s</li>
-            <li><strong>Создан:</strong> %s</li>
-        </ul>
-        <h3>Контент (JSON):</h3>
-        <pre>%s</pre>
-    </div>
-    <div class="content">
-        <h2>HTML содержимое блока</h2>
-        %s
-    </div>
-</body>
-</html>`,
-			block.BlockType, block.ID.String(),
-			block.ID.String(),
-			block.OperationID.String(),
-			block.BlockType,
-			block.Platform,
-			block.CreatedAt.Format("2006-01-02 15:04:05"),
-			jsonPretty(block.Content),
-			block.HTML)
-
-		data = []byte(htmlContent)
-		filename = fmt.Sprintf("block_%s_%s.html", block.BlockType, block.ID.String())
-		contentType = "text/html"
-	} else {
-		// JSON формат
-		jsonData, err := json.MarshalIndent(map[string]interface{}{
-			"id":           block.ID,
-			"operation_id": block.OperationID,
-			"block_type":   block.BlockType,
-			"platform":     block.Platform,
-			"created_at":   block.CreatedAt,
-			"content":      block.Content,
-			"html":         block.HTML,
-		}, "", "  ")
-		if err != nil {
-			RespondWithError(w, http.StatusInternalServerError, "Ошибка сериализации данных")
-			return
-		}
-
-		data = jsonData
-		filename = fmt.Sprintf("block_%s_%s.json", block.BlockType, block.ID.String())
-		contentType = "application/json"
+	// Файл формируется по записи в БД, сохранять блоки заранее не нужно
+	data, filename, contentType, err := h.downloader.DownloadBlock(block, format)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка формирования файла блока: "+err.Error())
+		return
 	}
 
 	// Отправляем файл
@@ -672,15 +1235,6 @@ func (h *Handlers) DownloadBlock(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
-// Вспомогательная функция для красивого форматирования JSON
-func jsonPretty(v interface{}) string {
-	b, err := json.MarshalIndent(v, "", "  ")
-	if err != nil {
-		return fmt.Sprintf("%v", v)
-	}
-	return string(b)
-}
-
 // GetBlockFiles возвращает список файлов блоков для операции
 func (h *Handlers) GetBlockFiles(w http.ResponseWriter, r *http.Request) {
 	// Получаем ID операции из URL
@@ -694,11 +1248,8 @@ func (h *Handlers) GetBlockFiles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Создаем экземпляр downloader
-	downloader := downloader.NewDownloader(h.config)
-
 	// Получаем список файлов
-	files, err := downloader.GetBlockFiles(operationID)
+	files, err := h.downloader.GetBlockFiles(r.Context(), operationID)
 	if err != nil {
 		RespondWithError(w, http.StatusNotFound, "Файлы блоков не найдены: "+err.Error())
 		return
@@ -731,72 +1282,69 @@ func (h *Handlers) DownloadAllBlocks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Сначала получаем все блоки для этой операции
-	blocks, err := h.parserService.GetBlocksByOperationID(r.Context(), operationID)
-	if err != nil {
-		RespondWithError(w, http.StatusInternalServerError, "Ошибка получения блоков: "+err.Error())
-		return
-	}
-
-	// Создаем экземпляр downloader
-	downloader := downloader.NewDownloader(h.config)
-
-	// Убеждаемся, что блоки сохранены на диске
-	if err := downloader.SaveBlocks(blocks); err != nil {
-		RespondWithError(w, http.StatusInternalServerError, "Ошибка сохранения блоков: "+err.Error())
-		return
+	// Блоки отбираются по типам, платформам и шаблонам; параметры можно повторять или перечислять через запятую
+	params := r.URL.Query()
+	filter := models.BlockFilter{Templates: queryList(params, "template")}
+	for _, blockType := range queryList(params, "block_type") {
+		filter.BlockTypes = append(filter.BlockTypes, models.BlockType(blockType))
 	}
-
-	// Создаем сводный файл, который перечисляет все шапки и подвалы по платформам
-	summaryPath, err := downloader.CreateBlocksSummary(operationID, blocks)
-	if err != nil {
-		log.Printf("Предупреждение: Не удалось создать сводку блоков: %v", err)
-		// Продолжаем, даже если создание сводки не удалось
+	for _, platform := range queryList(params, "platform") {
+		filter.Platforms = append(filter.Platforms, models.Platform(platform))
 	}
 
-	// Создаем архив
-	archivePath, err := downloader.ZipBlocks(operationID)
+	allBlocks, err := h.parserService.GetBlocksByOperationID(r.Context(), operationID)
 	if err != nil {
-		RespondWithError(w, http.StatusInternalServerError, "Ошибка создания архива: "+err.Error())
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка получения блоков: "+err.Error())
 		return
 	}
 
-	// Открываем архив
-	file, err := os.Open(archivePath)
-	if err != nil {
-		RespondWithError(w, http.StatusInternalServerError, "Ошибка открытия архива: "+err.Error())
+	var blocks []models.Block
+	for _, block := range allBlocks {
+		if filter.Matches(block) {
+			blocks = append(blocks, block)
+		}
+	}
+	if len(blocks) == 0 {
+		RespondWithError(w, http.StatusNotFound, "Блоки операции не найдены")
 		return
 	}
-	defer file.Close()
 
-	// Получаем информацию о файле
-	fileInfo, err := file.Stat()
-	if err != nil {
-		RespondWithError(w, http.StatusInternalServerError, "Ошибка получения информации о файле: "+err.Error())
+	filename := fmt.Sprintf("blocks_%s.zip", operationID.String())
+
+	// При link=true архив сохраняется среди выгрузок операции, клиент получает ссылку на него
+	if wantsLink(r) {
+		archiveKey, err := h.downloader.ZipBlocks(r.Context(), operationID, blocks)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "Ошибка создания архива: "+err.Error())
+			return
+		}
+		h.respondWithLink(w, r, archiveKey, filename)
 		return
 	}
 
-	// Устанавливаем заголовки
-	filename := filepath.Base(archivePath)
+	// Архив пишется прямо в ответ, поэтому его размер заранее неизвестен
 	w.Header().Set("Content-Type", "application/zip")
 	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
-	w.Header().Set("Content-Length", strconv.FormatInt(fileInfo.Size(), 10))
-
-	// Отправляем файл
 	w.WriteHeader(http.StatusOK)
-	io.Copy(w, file)
 
-	// Очистка
-	defer func() {
-		if err := os.Remove(archivePath); err != nil {
-			log.Printf("Ошибка удаления архива %s: %v", archivePath, err)
-		}
-		if summaryPath != "" {
-			if err := os.Remove(summaryPath); err != nil {
-				log.Printf("Ошибка удаления файла сводки %s: %v", summaryPath, err)
+	// Заголовки уже отправлены, ошибку можно только записать в журнал: клиент получит неполный архив
+	if err := h.downloader.WriteBlocksZip(r.Context(), w, operationID, blocks); err != nil {
+		log.Printf("Ошибка отправки архива блоков операции %s: %v", operationID, err)
+	}
+}
+
+// queryList возвращает значения параметра запроса: параметр можно повторять
+// или перечислять значения через запятую
+func queryList(params url.Values, name string) []string {
+	var values []string
+	for _, value := range params[name] {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
 			}
 		}
-	}()
+	}
+	return values
 }
 
 // SaveBlocksEndpoint сохраняет блоки операции в файлы
@@ -819,27 +1367,36 @@ func (h *Handlers) SaveBlocksEndpoint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Создаем экземпляр downloader
-	downloader := downloader.NewDownloader(h.config)
-
 	// Сохраняем блоки
-	if err := downloader.SaveBlocks(blocks); err != nil {
+	if err := h.downloader.SaveBlocks(r.Context(), blocks); err != nil {
 		RespondWithError(w, http.StatusInternalServerError, "Ошибка сохранения блоков: "+err.Error())
 		return
 	}
 
+	// При mirror=true дополнительно сохраняем автономные копии блоков с локальными ресурсами
+	mirrored := 0
+	if mirror, _ := strconv.ParseBool(r.URL.Query().Get("mirror")); mirror {
+		mirrored, err = h.parserService.MirrorBlocks(r.Context(), operationID)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "Ошибка сохранения ресурсов блоков: "+err.Error())
+			return
+		}
+	}
+
 	// Формируем ответ
 	response := struct {
-		OperationID string            `json:"operation_id"`
-		BlocksCount int               `json:"blocks_count"`
-		Message     string            `json:"message"`
-		Directory   string            `json:"directory"`
-		Links       map[string]string `json:"links"`
+		OperationID   string            `json:"operation_id"`
+		BlocksCount   int               `json:"blocks_count"`
+		MirroredCount int               `json:"mirrored_count,omitempty"`
+		Message       string            `json:"message"`
+		Directory     string            `json:"directory"`
+		Links         map[string]string `json:"links"`
 	}{
-		OperationID: operationID.String(),
-		BlocksCount: len(blocks),
-		Message:     "Блоки успешно сохранены",
-		Directory:   filepath.Join(h.config.Downloader.OutputDir, "blocks", operationID.String()),
+		OperationID:   operationID.String(),
+		BlocksCount:   len(blocks),
+		MirroredCount: mirrored,
+		Message:       "Блоки успешно сохранены",
+		Directory:     "blocks/" + operationID.String() + "/",
 		Links: map[string]string{
 			"list_files":     "/api/v1/operations/" + operationID.String() + "/blocks",
 			"download_all":   "/api/v1/operations/" + operationID.String() + "/blocks/download",
@@ -857,6 +1414,8 @@ func getContentType(format string) string {
 		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
 	case "text":
 		return "text/plain"
+	case "csv":
+		return "text/csv; charset=utf-8"
 	default:
 		return "application/octet-stream"
 	}
@@ -875,3 +1434,317 @@ func getFormatsList(formats []string) string {
 
 	return result
 }
+
+// GetProxies возвращает состояние прокси пула: доступность, ошибки и закрепленные домены
+func (h *Handlers) GetProxies(w http.ResponseWriter, r *http.Request) {
+	statuses := h.downloader.ProxyStatus()
+
+	response := struct {
+		Proxies []models.ProxyStatus `json:"proxies"`
+		Count   int                  `json:"count"`
+	}{
+		Proxies: statuses,
+		Count:   len(statuses),
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
+// defaultMonitorChanges и maxMonitorChanges задают число записей истории изменений монитора
+const (
+	defaultMonitorChanges = 20
+	maxMonitorChanges     = 100
+)
+
+// CreateMonitor обрабатывает запрос на создание монитора страницы
+func (h *Handlers) CreateMonitor(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateMonitorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Некорректное тело запроса")
+		return
+	}
+
+	created, err := h.monitorService.CreateMonitor(r.Context(), req)
+	if err != nil {
+		respondMonitorError(w, err, "Ошибка при создании монитора")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, created)
+}
+
+// ListMonitors возвращает все мониторы страниц
+func (h *Handlers) ListMonitors(w http.ResponseWriter, r *http.Request) {
+	monitors, err := h.monitorService.ListMonitors(r.Context())
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка при получении мониторов: "+err.Error())
+		return
+	}
+
+	response := struct {
+		Monitors []models.Monitor `json:"monitors"`
+		Count    int              `json:"count"`
+	}{
+		Monitors: monitors,
+		Count:    len(monitors),
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
+// GetMonitor возвращает монитор по ID
+func (h *Handlers) GetMonitor(w http.ResponseWriter, r *http.Request) {
+	monitorID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Неверный ID монитора")
+		return
+	}
+
+	found, err := h.monitorService.GetMonitor(r.Context(), monitorID)
+	if err != nil {
+		respondMonitorError(w, err, "Ошибка при получении монитора")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, found)
+}
+
+// UpdateMonitor обрабатывает запрос на изменение расписания, параметров или включенности монитора
+func (h *Handlers) UpdateMonitor(w http.ResponseWriter, r *http.Request) {
+	monitorID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Неверный ID монитора")
+		return
+	}
+
+	var req models.UpdateMonitorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Некорректное тело запроса")
+		return
+	}
+
+	updated, err := h.monitorService.UpdateMonitor(r.Context(), monitorID, req)
+	if err != nil {
+		respondMonitorError(w, err, "Ошибка при изменении монитора")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, updated)
+}
+
+// DeleteMonitor обрабатывает запрос на удаление монитора вместе с историей изменений
+func (h *Handlers) DeleteMonitor(w http.ResponseWriter, r *http.Request) {
+	monitorID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Неверный ID монитора")
+		return
+	}
+
+	if err := h.monitorService.DeleteMonitor(r.Context(), monitorID); err != nil {
+		respondMonitorError(w, err, "Ошибка при удалении монитора")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunMonitor обрабатывает запрос на внеочередную проверку монитора
+func (h *Handlers) RunMonitor(w http.ResponseWriter, r *http.Request) {
+	monitorID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Неверный ID монитора")
+		return
+	}
+
+	if err := h.monitorService.RunMonitor(r.Context(), monitorID); err != nil {
+		respondMonitorError(w, err, "Ошибка при запуске проверки")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+		"monitor_id": monitorID,
+		"links": map[string]string{
+			"monitor": "/api/v1/monitors/" + monitorID.String(),
+			"changes": "/api/v1/monitors/" + monitorID.String() + "/changes",
+		},
+		"message": "Проверка монитора запущена",
+	})
+}
+
+// GetMonitorChanges возвращает историю изменений страницы, начиная с последних.
+// Число записей задается параметром limit
+func (h *Handlers) GetMonitorChanges(w http.ResponseWriter, r *http.Request) {
+	monitorID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Неверный ID монитора")
+		return
+	}
+
+	limit := defaultMonitorChanges
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxMonitorChanges {
+			RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("limit должен быть от 1 до %d", maxMonitorChanges))
+			return
+		}
+	}
+
+	changes, err := h.monitorService.GetMonitorChanges(r.Context(), monitorID, limit)
+	if err != nil {
+		respondMonitorError(w, err, "Ошибка при получении изменений")
+		return
+	}
+
+	response := struct {
+		MonitorID uuid.UUID              `json:"monitor_id"`
+		Changes   []models.MonitorChange `json:"changes"`
+		Count     int                    `json:"count"`
+	}{
+		MonitorID: monitorID,
+		Changes:   changes,
+		Count:     len(changes),
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
+// respondMonitorError отправляет ошибку сервиса мониторов с подходящим статусом
+func respondMonitorError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, monitor.ErrInvalidMonitor):
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, monitor.ErrMonitorRunning):
+		RespondWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, repo.ErrNotFound):
+		RespondWithError(w, http.StatusNotFound, "Монитор не найден")
+	default:
+		RespondWithError(w, http.StatusInternalServerError, message+": "+err.Error())
+	}
+}
+
+// DeleteOperation обрабатывает запрос на удаление операции вместе с блоками и файлами в хранилище
+func (h *Handlers) DeleteOperation(w http.ResponseWriter, r *http.Request) {
+	operationID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Неверный ID операции")
+		return
+	}
+
+	purged, err := h.retention.DeleteOperation(r.Context(), operationID)
+	if err != nil {
+		switch {
+		case errors.Is(err, retention.ErrOperationRunning):
+			RespondWithError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, repo.ErrNotFound):
+			RespondWithError(w, http.StatusNotFound, "Операция не найдена")
+		default:
+			RespondWithError(w, http.StatusInternalServerError, "Ошибка при удалении операции: "+err.Error())
+		}
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, purged)
+}
+
+// GetRetentionReport показывает, какие операции удалит политика хранения, ничего не удаляя
+func (h *Handlers) GetRetentionReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.retention.Apply(r.Context(), true)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка при построении отчета: "+err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, report)
+}
+
+// RunRetention применяет политику хранения немедленно, не дожидаясь фоновой очистки
+func (h *Handlers) RunRetention(w http.ResponseWriter, r *http.Request) {
+	report, err := h.retention.Apply(r.Context(), false)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка при очистке: "+err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, report)
+}
+
+// ServeFile отдает файл локального хранилища по подписанной ссылке. Ссылки S3 ведут
+// прямо в хранилище, поэтому для него маршрут не используется
+func (h *Handlers) ServeFile(w http.ResponseWriter, r *http.Request) {
+	verifier, ok := h.storage.(storage.LinkVerifier)
+	if !ok {
+		RespondWithError(w, http.StatusNotFound, "Файлы выдаются хранилищем по ссылке")
+		return
+	}
+
+	key := mux.Vars(r)["key"]
+	filename, err := verifier.VerifyLink(key, r.URL.Query())
+	if err != nil {
+		RespondWithError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h.sendFile(w, r, key, filename, contentType)
+}
+
+// wantsLink проверяет, что клиент просит ссылку на файл вместо самого файла
+func wantsLink(r *http.Request) bool {
+	link, _ := strconv.ParseBool(r.URL.Query().Get("link"))
+	return link
+}
+
+// sendFile отправляет файл из хранилища как вложение
+func (h *Handlers) sendFile(w http.ResponseWriter, r *http.Request, key, filename, contentType string) {
+	body, err := h.storage.Get(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			RespondWithError(w, http.StatusNotFound, "Файл не найден")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка чтения файла: "+err.Error())
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Header().Set("Content-Type", contentType)
+	if object, err := h.storage.Stat(r.Context(), key); err == nil {
+		w.Header().Set("Content-Length", strconv.FormatInt(object.Size, 10))
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, body); err != nil {
+		log.Printf("Ошибка отправки файла %s: %v", key, err)
+	}
+}
+
+// respondWithLink отправляет ссылку на скачивание файла из хранилища
+func (h *Handlers) respondWithLink(w http.ResponseWriter, r *http.Request, key, filename string) {
+	expires := h.config.Storage.LinkExpiry
+
+	link, err := h.storage.PresignGet(r.Context(), key, expires, filename)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка создания ссылки на файл: "+err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, models.DownloadLink{
+		URL:       link,
+		Key:       key,
+		Filename:  filename,
+		ExpiresAt: time.Now().Add(expires),
+	})
+}
+
+// storeAndLink сохраняет сформированный файл в хранилище и отправляет ссылку на него
+func (h *Handlers) storeAndLink(w http.ResponseWriter, r *http.Request, key, filename string, content []byte, contentType string) {
+	if err := storage.PutBytes(r.Context(), h.storage, key, content, contentType); err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Ошибка сохранения файла: "+err.Error())
+		return
+	}
+	h.respondWithLink(w, r, key, filename)
+}
@@ -20,15 +20,42 @@ func SetupRouter(handlers *handlers.Handlers) *mux.Router {
 
 	// Регистрируем маршруты парсера
 	apiRouter.HandleFunc("/parse", handlers.ParseURL).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/operations", handlers.ListOperations).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/operations/{id}", handlers.GetOperationResult).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/operations/{id}", handlers.DeleteOperation).Methods(http.MethodDelete)
 	apiRouter.HandleFunc("/operations/{id}/export", handlers.ExportOperation).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/operations/{id}/products/export", handlers.ExportProducts).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/operations/{id}/assets", handlers.GetOperationAssets).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/operations/{id}/har", handlers.DownloadHAR).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/operations/{id}/reparse", handlers.ReparseOperation).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/operations/{id}/compare", handlers.CompareLayouts).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/operations/{id}/diff/{other_id}", handlers.DiffOperations).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/products/export", handlers.ExportProducts).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/sites/{domain}/duplicates", handlers.GetDuplicateReport).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/blocks/search", handlers.SearchBlocks).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/blocks/{block_id}/similar", handlers.FindSimilarBlocks).Methods(http.MethodGet)
 
 	// Регистрируем маршруты загрузчика
 	apiRouter.HandleFunc("/download/{id}", handlers.DownloadByID).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/formats", handlers.GetFormats).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/files/{key:.+}", handlers.ServeFile).Methods(http.MethodGet)
 
 	// Регистрируем маршруты краулера
 	apiRouter.HandleFunc("/crawl", handlers.CrawlURL).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/proxies", handlers.GetProxies).Methods(http.MethodGet)
+
+	// Регистрируем маршруты мониторов страниц
+	apiRouter.HandleFunc("/monitors", handlers.CreateMonitor).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/monitors", handlers.ListMonitors).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/monitors/{id}", handlers.GetMonitor).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/monitors/{id}", handlers.UpdateMonitor).Methods(http.MethodPatch)
+	apiRouter.HandleFunc("/monitors/{id}", handlers.DeleteMonitor).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/monitors/{id}/run", handlers.RunMonitor).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/monitors/{id}/changes", handlers.GetMonitorChanges).Methods(http.MethodGet)
+
+	// Регистрируем маршруты политики хранения
+	apiRouter.HandleFunc("/retention/report", handlers.GetRetentionReport).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/retention/run", handlers.RunRetention).Methods(http.MethodPost)
 
 	// Добавьте эти строки в функцию SetupRouter
 	apiRouter.HandleFunc("/operations/{operation_id}/blocks/save", handlers.SaveBlocksEndpoint).Methods(http.MethodPost)
@@ -58,6 +85,8 @@ func SetupRouter(handlers *handlers.Handlers) *mux.Router {
 					.method { display: inline-block; padding: 5px 10px; border-radius: 3px; color: white; font-weight: bold; margin-right: 10px; }
 					.get { background-color: #61affe; }
 					.post { background-color: #49cc90; }
+					.patch { background-color: #50e3c2; }
+					.delete { background-color: #f93e3e; }
 					.endpoint-url { font-family: monospace; }
 				</style>
 			</head>
@@ -73,22 +102,88 @@ func SetupRouter(handlers *handlers.Handlers) *mux.Router {
 					<p>Парсит указанный URL и возвращает ID операции.</p>
 				</div>
 				
+				<div class="endpoint">
+					<span class="method get">GET</span>
+					<span class="endpoint-url">/api/v1/operations</span>
+					<p>Возвращает список операций с фильтрами, сортировкой, постраничным курсором и числом блоков.</p>
+				</div>
+				
 				<div class="endpoint">
 					<span class="method get">GET</span>
 					<span class="endpoint-url">/api/v1/operations/{id}</span>
 					<p>Возвращает результаты операции парсинга по ID.</p>
 				</div>
 				
+				<div class="endpoint">
+					<span class="method delete">DELETE</span>
+					<span class="endpoint-url">/api/v1/operations/{id}</span>
+					<p>Удаляет операцию вместе с блоками, ссылками и файлами в хранилище.</p>
+				</div>
+				
 				<div class="endpoint">
 					<span class="method get">GET</span>
 					<span class="endpoint-url">/api/v1/operations/{id}/export</span>
-					<p>Экспортирует результаты операции в указанном формате.</p>
+					<p>Экспортирует результаты операции в указанном формате. С параметром link=true сохраняет файл в хранилище и возвращает ссылку на скачивание.</p>
+				</div>
+				
+				<div class="endpoint">
+					<span class="method get">GET</span>
+					<span class="endpoint-url">/api/v1/operations/{id}/products/export</span>
+					<p>Экспортирует найденные товары операции в CSV или Excel.</p>
+				</div>
+				
+				<div class="endpoint">
+					<span class="method get">GET</span>
+					<span class="endpoint-url">/api/v1/operations/{id}/assets</span>
+					<p>Возвращает ресурсы каждого блока (изображения, шрифты, стили, скрипты, видео) и сводку по операции.</p>
+				</div>
+				
+				<div class="endpoint">
+					<span class="method get">GET</span>
+					<span class="endpoint-url">/api/v1/operations/{id}/compare?with={other_id}</span>
+					<p>Сравнивает блоки страницы на двух устройствах: скрытые, пропавшие и переставленные блоки.</p>
+				</div>
+				
+				<div class="endpoint">
+					<span class="method get">GET</span>
+					<span class="endpoint-url">/api/v1/operations/{id}/diff/{other_id}?format=json|html</span>
+					<p>Сравнивает блоки двух операций: добавленные, удаленные, перемещенные и измененные блоки с различиями в тексте и HTML.</p>
+				</div>
+				
+				<div class="endpoint">
+					<span class="method get">GET</span>
+					<span class="endpoint-url">/api/v1/products/export?operation_id={id}</span>
+					<p>Экспортирует товары нескольких операций в один файл. Для товаров обхода передайте ID операций всех разобранных страниц, найденных /crawl: у обхода нет своего идентификатора.</p>
+				</div>
+				
+				<div class="endpoint">
+					<span class="method get">GET</span>
+					<span class="endpoint-url">/api/v1/sites/{domain}/duplicates</span>
+					<p>Показывает повторяющиеся блоки сайта: общие для всех страниц, повторяющиеся на нескольких и уникальные для страницы.</p>
+				</div>
+				
+				<div class="endpoint">
+					<span class="method get">GET</span>
+					<span class="endpoint-url">/api/v1/blocks/search?q={query}</span>
+					<p>Ищет блоки всех операций по тексту с фильтрами по платформе, типу блока, шаблону, сайту и дате.</p>
+				</div>
+				
+				<div class="endpoint">
+					<span class="method get">GET</span>
+					<span class="endpoint-url">/api/v1/blocks/{block_id}/similar?other_sites=true</span>
+					<p>Находит блоки всех операций с той же структурой тегов и классов, например блоки одного шаблона на разных сайтах.</p>
 				</div>
 				
 				<div class="endpoint">
 					<span class="method get">GET</span>
 					<span class="endpoint-url">/api/v1/download/{id}</span>
-					<p>Загружает файлы по ID операции в указанном формате.</p>
+					<p>Загружает файлы по ID операции в указанном формате. С параметром link=true возвращает ссылку на скачивание.</p>
+				</div>
+				
+				<div class="endpoint">
+					<span class="method get">GET</span>
+					<span class="endpoint-url">/api/v1/files/{key}?expires={t}&signature={s}</span>
+					<p>Отдает файл локального хранилища по подписанной ссылке.</p>
 				</div>
 				
 				<div class="endpoint">
@@ -103,6 +198,66 @@ func SetupRouter(handlers *handlers.Handlers) *mux.Router {
 					<p>Обходит указанный URL и собирает ссылки.</p>
 				</div>
 				
+				<div class="endpoint">
+					<span class="method get">GET</span>
+					<span class="endpoint-url">/api/v1/proxies</span>
+					<p>Возвращает состояние прокси пула.</p>
+				</div>
+				
+				<div class="endpoint">
+					<span class="method post">POST</span>
+					<span class="endpoint-url">/api/v1/monitors</span>
+					<p>Создает монитор, по расписанию заново разбирающий страницу и сравнивающий блоки с прошлой проверкой.</p>
+				</div>
+				
+				<div class="endpoint">
+					<span class="method get">GET</span>
+					<span class="endpoint-url">/api/v1/monitors</span>
+					<p>Возвращает список мониторов страниц.</p>
+				</div>
+				
+				<div class="endpoint">
+					<span class="method get">GET</span>
+					<span class="endpoint-url">/api/v1/monitors/{id}</span>
+					<p>Возвращает монитор: расписание, последнюю проверку и ошибку.</p>
+				</div>
+				
+				<div class="endpoint">
+					<span class="method patch">PATCH</span>
+					<span class="endpoint-url">/api/v1/monitors/{id}</span>
+					<p>Изменяет расписание, параметры разбора или включает и выключает монитор.</p>
+				</div>
+				
+				<div class="endpoint">
+					<span class="method delete">DELETE</span>
+					<span class="endpoint-url">/api/v1/monitors/{id}</span>
+					<p>Удаляет монитор вместе с историей изменений.</p>
+				</div>
+				
+				<div class="endpoint">
+					<span class="method post">POST</span>
+					<span class="endpoint-url">/api/v1/monitors/{id}/run</span>
+					<p>Запускает внеочередную проверку монитора.</p>
+				</div>
+				
+				<div class="endpoint">
+					<span class="method get">GET</span>
+					<span class="endpoint-url">/api/v1/monitors/{id}/changes?limit={n}</span>
+					<p>Возвращает историю изменений страницы: добавленные, удаленные и измененные блоки с различиями в тексте и структуре.</p>
+				</div>
+				
+				<div class="endpoint">
+					<span class="method get">GET</span>
+					<span class="endpoint-url">/api/v1/retention/report</span>
+					<p>Показывает, какие операции удалит политика хранения по сроку и месту в хранилище, ничего не удаляя.</p>
+				</div>
+				
+				<div class="endpoint">
+					<span class="method post">POST</span>
+					<span class="endpoint-url">/api/v1/retention/run</span>
+					<p>Применяет политику хранения немедленно и возвращает удаленные операции.</p>
+				</div>
+				
 				<div class="endpoint">
 					<span class="method post">POST</span>
 					<span class="endpoint-url">/api/v1/operations/{operation_id}/blocks/save</span>
@@ -124,7 +279,7 @@ func SetupRouter(handlers *handlers.Handlers) *mux.Router {
 				<div class="endpoint">
 					<span class="method get">GET</span>
 					<span class="endpoint-url">/api/v1/operations/{operation_id}/blocks/download</span>
-					<p>Создает и загружает архив со всеми блоками операции.</p>
+					<p>Отдает архив с блоками операции, собирая его на лету. Параметры block_type, platform и template фильтруют блоки. С параметром link=true возвращает ссылку на архив в хранилище.</p>
 				</div>
 				
 				<p>Документация API доступна по ссылке: <a href="/swagger/">/swagger/</a></p>
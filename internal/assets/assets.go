@@ -0,0 +1,385 @@
+package assets
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/publicsuffix"
+
+	"website-scraper/internal/models"
+)
+
+var (
+	// cssURLRegexp находит ссылки url(...) в CSS
+	cssURLRegexp = regexp.MustCompile(`url\(\s*['"]?([^'")]+?)['"]?\s*\)`)
+
+	// fontFaceRegexp находит правила @font-face в CSS
+	fontFaceRegexp = regexp.MustCompile(`(?s)@font-face\s*\{[^}]*\}`)
+
+	// fontFamilyRegexp находит объявления font-family в CSS, имена семейств могут быть в кавычках
+	fontFamilyRegexp = regexp.MustCompile(`font-family\s*:\s*((?:"[^"]*"|'[^']*'|[^;}"'])+)`)
+)
+
+// videoHosts содержит домены видеохостингов, iframe которых считаются видео
+var videoHosts = []string{
+	"youtube.com",
+	"youtube-nocookie.com",
+	"youtu.be",
+	"vimeo.com",
+	"rutube.ru",
+	"vk.com/video_ext",
+	"dzen.ru",
+	"kinescope.io",
+}
+
+// fontExtensions содержит расширения файлов шрифтов
+var fontExtensions = []string{".woff2", ".woff", ".ttf", ".otf", ".eot"}
+
+// Extract собирает все ресурсы, на которые ссылается HTML блока, с абсолютными URL
+func Extract(blockHTML, pageURL string) *models.BlockAssets {
+	result := &models.BlockAssets{
+		Items:  []models.Asset{},
+		Counts: make(map[models.AssetType]int),
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(blockHTML))
+	if err != nil {
+		return result
+	}
+
+	base, _ := url.Parse(pageURL)
+	seen := make(map[string]bool)
+	fontFamilies := make(map[string]bool)
+
+	add := func(assetType models.AssetType, ref, source string) {
+		absolute := Resolve(base, ref)
+		if absolute == "" {
+			return
+		}
+
+		key := string(assetType) + "|" + absolute
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+
+		asset := models.Asset{
+			Type:     assetType,
+			URL:      absolute,
+			Source:   source,
+			External: IsExternal(base, absolute),
+		}
+
+		result.Items = append(result.Items, asset)
+		result.Counts[assetType]++
+		if asset.External {
+			result.External++
+		}
+	}
+
+	// Изображения, включая отложенную загрузку
+	doc.Find("img").Each(func(i int, img *goquery.Selection) {
+		for _, attr := range []string{"src", "data-src", "data-original", "data-lazy-src"} {
+			if src, exists := img.Attr(attr); exists {
+				add(models.AssetTypeImage, src, "img["+attr+"]")
+			}
+		}
+		for _, attr := range []string{"srcset", "data-srcset"} {
+			if srcset, exists := img.Attr(attr); exists {
+				for _, src := range ParseSrcset(srcset) {
+					add(models.AssetTypeImage, src, "img["+attr+"]")
+				}
+			}
+		}
+	})
+
+	doc.Find("picture source[srcset], picture source[data-srcset]").Each(func(i int, source *goquery.Selection) {
+		srcset := source.AttrOr("srcset", source.AttrOr("data-srcset", ""))
+		for _, src := range ParseSrcset(srcset) {
+			add(models.AssetTypeImage, src, "source[srcset]")
+		}
+	})
+
+	// Фоновые изображения в inline-стилях и lazy-атрибутах (Tilda хранит фон в data-original)
+	doc.Find("[style]").Each(func(i int, el *goquery.Selection) {
+		style := el.AttrOr("style", "")
+		for _, ref := range CSSURLs(style) {
+			add(models.AssetTypeBackgroundImage, ref, "style")
+		}
+		for _, family := range fontFamilyRegexp.FindAllStringSubmatch(style, -1) {
+			addFontFamilies(fontFamilies, family[1])
+		}
+	})
+
+	doc.Find("[data-bg], [data-background], .t-bgimg[data-original]").Each(func(i int, el *goquery.Selection) {
+		for _, attr := range []string{"data-bg", "data-background"} {
+			if ref, exists := el.Attr(attr); exists {
+				add(models.AssetTypeBackgroundImage, ref, attr)
+			}
+		}
+		if el.Is("img") {
+			return
+		}
+		if ref, exists := el.Attr("data-original"); exists {
+			add(models.AssetTypeBackgroundImage, ref, "data-original")
+		}
+	})
+
+	// Встроенные SVG и внешние SVG через use/image
+	result.InlineSVG = doc.Find("svg").Length()
+	doc.Find("svg use, svg image").Each(func(i int, el *goquery.Selection) {
+		for _, attr := range []string{"href", "xlink:href"} {
+			if ref, exists := el.Attr(attr); exists && !strings.HasPrefix(ref, "#") {
+				add(models.AssetTypeSVG, ref, goquery.NodeName(el)+"["+attr+"]")
+			}
+		}
+	})
+	doc.Find("object[data], embed[src]").Each(func(i int, el *goquery.Selection) {
+		ref := el.AttrOr("data", el.AttrOr("src", ""))
+		if strings.HasSuffix(strings.ToLower(stripQuery(ref)), ".svg") {
+			add(models.AssetTypeSVG, ref, goquery.NodeName(el))
+		}
+	})
+
+	// Фреймы и видео
+	doc.Find("iframe").Each(func(i int, iframe *goquery.Selection) {
+		src := iframe.AttrOr("src", iframe.AttrOr("data-src", ""))
+		if isVideoHost(src) {
+			add(models.AssetTypeVideo, src, "iframe[src]")
+		} else {
+			add(models.AssetTypeIframe, src, "iframe[src]")
+		}
+	})
+
+	doc.Find("video, video source").Each(func(i int, el *goquery.Selection) {
+		if src, exists := el.Attr("src"); exists {
+			add(models.AssetTypeVideo, src, goquery.NodeName(el)+"[src]")
+		}
+		if poster, exists := el.Attr("poster"); exists {
+			add(models.AssetTypeImage, poster, "video[poster]")
+		}
+	})
+
+	doc.Find("audio, audio source").Each(func(i int, el *goquery.Selection) {
+		if src, exists := el.Attr("src"); exists {
+			add(models.AssetTypeAudio, src, goquery.NodeName(el)+"[src]")
+		}
+	})
+
+	// Стили, шрифты и скрипты
+	doc.Find("link[href]").Each(func(i int, link *goquery.Selection) {
+		href := link.AttrOr("href", "")
+		rel := strings.ToLower(link.AttrOr("rel", ""))
+
+		switch {
+		case link.AttrOr("as", "") == "font" || isFontURL(href):
+			add(models.AssetTypeFont, href, "link[href]")
+		case strings.Contains(rel, "stylesheet"):
+			add(models.AssetTypeStylesheet, href, "link[href]")
+		}
+	})
+
+	doc.Find("style").Each(func(i int, style *goquery.Selection) {
+		css := style.Text()
+		for _, fontFace := range fontFaceRegexp.FindAllString(css, -1) {
+			for _, ref := range CSSURLs(fontFace) {
+				add(models.AssetTypeFont, ref, "@font-face")
+			}
+		}
+		for _, ref := range CSSURLs(fontFaceRegexp.ReplaceAllString(css, "")) {
+			if isFontURL(ref) {
+				add(models.AssetTypeFont, ref, "style")
+			} else {
+				add(models.AssetTypeBackgroundImage, ref, "style")
+			}
+		}
+		for _, family := range fontFamilyRegexp.FindAllStringSubmatch(css, -1) {
+			addFontFamilies(fontFamilies, family[1])
+		}
+	})
+
+	doc.Find("script[src]").Each(func(i int, script *goquery.Selection) {
+		add(models.AssetTypeScript, script.AttrOr("src", ""), "script[src]")
+	})
+
+	for family := range fontFamilies {
+		result.FontFamilies = append(result.FontFamilies, family)
+	}
+	sort.Strings(result.FontFamilies)
+
+	return result
+}
+
+// Totals суммирует инвентаризацию ресурсов по нескольким блокам
+func Totals(items []*models.BlockAssets) *models.AssetTotals {
+	totals := &models.AssetTotals{
+		ByType:        make(map[models.AssetType]int),
+		ExternalHosts: make(map[string]int),
+	}
+
+	unique := make(map[string]bool)
+	fontFamilies := make(map[string]bool)
+
+	for _, blockAssets := range items {
+		if blockAssets == nil {
+			continue
+		}
+
+		totals.InlineSVG += blockAssets.InlineSVG
+		for _, asset := range blockAssets.Items {
+			totals.Total++
+			totals.ByType[asset.Type]++
+			unique[asset.URL] = true
+
+			if asset.External {
+				totals.External++
+				if parsed, err := url.Parse(asset.URL); err == nil {
+					totals.ExternalHosts[parsed.Hostname()]++
+				}
+			}
+		}
+		for _, family := range blockAssets.FontFamilies {
+			fontFamilies[family] = true
+		}
+	}
+
+	totals.Unique = len(unique)
+	for family := range fontFamilies {
+		totals.FontFamilies = append(totals.FontFamilies, family)
+	}
+	sort.Strings(totals.FontFamilies)
+
+	if len(totals.ExternalHosts) == 0 {
+		totals.ExternalHosts = nil
+	}
+
+	return totals
+}
+
+// Resolve приводит ссылку к абсолютному URL. Пустые ссылки, якоря и data: URI пропускаются
+func Resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	lower := strings.ToLower(ref)
+	if ref == "" || strings.HasPrefix(ref, "#") || strings.HasPrefix(lower, "data:") ||
+		strings.HasPrefix(lower, "javascript:") || strings.HasPrefix(lower, "about:") {
+		return ""
+	}
+
+	parsed, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+
+	if base == nil {
+		return parsed.String()
+	}
+
+	return base.ResolveReference(parsed).String()
+}
+
+// IsExternal проверяет, загружается ли ресурс с домена, отличного от домена страницы
+func IsExternal(base *url.URL, assetURL string) bool {
+	if base == nil || base.Hostname() == "" {
+		return false
+	}
+
+	parsed, err := url.Parse(assetURL)
+	if err != nil || parsed.Hostname() == "" {
+		return false
+	}
+
+	return registrableDomain(parsed.Hostname()) != registrableDomain(base.Hostname())
+}
+
+// ParseSrcset извлекает URL из атрибута srcset
+func ParseSrcset(srcset string) []string {
+	var urls []string
+	for _, candidate := range strings.Split(srcset, ",") {
+		fields := strings.Fields(strings.TrimSpace(candidate))
+		if len(fields) > 0 {
+			urls = append(urls, fields[0])
+		}
+	}
+	return urls
+}
+
+// CSSURLs извлекает все ссылки url(...) из CSS
+func CSSURLs(css string) []string {
+	var urls []string
+	for _, match := range cssURLRegexp.FindAllStringSubmatch(css, -1) {
+		urls = append(urls, strings.TrimSpace(match[1]))
+	}
+	return urls
+}
+
+// RewriteCSSURLs заменяет каждую ссылку url(...) в CSS на результат rewrite. Если rewrite
+// возвращает пустую строку, ссылка остается прежней
+func RewriteCSSURLs(css string, rewrite func(ref string) string) string {
+	return cssURLRegexp.ReplaceAllStringFunc(css, func(match string) string {
+		ref := strings.TrimSpace(cssURLRegexp.FindStringSubmatch(match)[1])
+		replacement := rewrite(ref)
+		if replacement == "" {
+			return match
+		}
+		return "url('" + replacement + "')"
+	})
+}
+
+// registrableDomain возвращает регистрируемый домен по списку публичных суффиксов, чтобы поддомены
+// сайта не считались внешними, а разные сайты в зонах вроде co.uk не считались одним
+func registrableDomain(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if net.ParseIP(strings.Trim(host, "[]")) != nil {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		// localhost и сами публичные суффиксы сравниваем целиком
+		return strings.TrimPrefix(host, "www.")
+	}
+	return domain
+}
+
+// isVideoHost проверяет, ведет ли ссылка на видеохостинг
+func isVideoHost(src string) bool {
+	for _, host := range videoHosts {
+		if strings.Contains(src, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// isFontURL проверяет, является ли ссылка файлом шрифта или сервисом шрифтов
+func isFontURL(ref string) bool {
+	lower := strings.ToLower(stripQuery(ref))
+	for _, ext := range fontExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return strings.Contains(lower, "fonts.googleapis.com") || strings.Contains(lower, "fonts.gstatic.com")
+}
+
+// addFontFamilies добавляет семейства шрифтов из значения font-family
+func addFontFamilies(families map[string]bool, value string) {
+	for _, family := range strings.Split(value, ",") {
+		family = strings.Trim(strings.TrimSpace(family), `'"`)
+		family = strings.TrimSpace(strings.TrimSuffix(family, "!important"))
+		if family != "" && !strings.HasPrefix(family, "var(") && !strings.HasPrefix(family, "inherit") {
+			families[family] = true
+		}
+	}
+}
+
+// stripQuery удаляет query и фрагмент из ссылки
+func stripQuery(ref string) string {
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		return ref[:i]
+	}
+	return ref
+}
@@ -0,0 +1,259 @@
+package assets
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var (
+	// cssCommentRegexp находит комментарии в CSS
+	cssCommentRegexp = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+	// pseudoRegexp находит псевдоклассы и псевдоэлементы вместе с аргументами
+	pseudoRegexp = regexp.MustCompile(`::?[a-zA-Z-]+(\([^)]*\))?`)
+
+	// attributeSelectorRegexp находит селекторы атрибутов
+	attributeSelectorRegexp = regexp.MustCompile(`\[[^\]]*\]`)
+
+	classSelectorRegexp = regexp.MustCompile(`\.([a-zA-Z0-9_-]+)`)
+	idSelectorRegexp    = regexp.MustCompile(`#([a-zA-Z0-9_-]+)`)
+	tagSelectorRegexp   = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9-]*`)
+
+	// fontFaceFamilyRegexp извлекает имя семейства из правила @font-face
+	fontFaceFamilyRegexp = regexp.MustCompile(`font-family\s*:\s*['"]?([^;'"}]+)`)
+)
+
+// globalSelectors содержит селекторы, правила которых нужны любому фрагменту страницы
+var globalSelectors = map[string]bool{
+	"html":  true,
+	"body":  true,
+	":root": true,
+	"*":     true,
+}
+
+// UsedSelectors содержит теги, классы и идентификаторы, встречающиеся в HTML блока
+type UsedSelectors struct {
+	Tags    map[string]bool
+	Classes map[string]bool
+	IDs     map[string]bool
+}
+
+// cssRule представляет правило CSS верхнего уровня
+type cssRule struct {
+	prelude string
+	body    string
+	block   bool
+}
+
+// CollectSelectors собирает теги, классы и идентификаторы из HTML блока
+func CollectSelectors(html string) *UsedSelectors {
+	used := &UsedSelectors{
+		Tags:    make(map[string]bool),
+		Classes: make(map[string]bool),
+		IDs:     make(map[string]bool),
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return used
+	}
+
+	doc.Find("body *").Each(func(i int, el *goquery.Selection) {
+		used.Tags[goquery.NodeName(el)] = true
+		for _, class := range strings.Fields(el.AttrOr("class", "")) {
+			used.Classes[class] = true
+		}
+		if id, exists := el.Attr("id"); exists && id != "" {
+			used.IDs[id] = true
+		}
+	})
+
+	return used
+}
+
+// CriticalCSS оставляет из таблицы стилей только правила, применимые к элементам блока.
+// Совпадение проверяется по последнему составному селектору: предки блока остаются за пределами
+// его HTML, поэтому требовать их наличия нельзя. @font-face и @keyframes сохраняются,
+// если на них ссылаются оставленные правила
+func CriticalCSS(css string, used *UsedSelectors) string {
+	css = cssCommentRegexp.ReplaceAllString(css, "")
+
+	kept := filterRules(parseRules(css), used)
+
+	var builder strings.Builder
+	for _, rule := range kept {
+		builder.WriteString(rule)
+		builder.WriteString("\n")
+	}
+	keptCSS := builder.String()
+
+	// Добавляем шрифты и анимации, на которые ссылаются оставленные правила
+	for _, rule := range parseRules(css) {
+		name := strings.ToLower(strings.TrimSpace(rule.prelude))
+		switch {
+		case strings.HasPrefix(name, "@font-face"):
+			match := fontFaceFamilyRegexp.FindStringSubmatch(rule.body)
+			if len(match) > 1 && strings.Contains(keptCSS, strings.TrimSpace(match[1])) {
+				builder.WriteString(rule.prelude + "{" + rule.body + "}\n")
+			}
+		case strings.HasPrefix(name, "@keyframes"), strings.HasPrefix(name, "@-webkit-keyframes"):
+			fields := strings.Fields(rule.prelude)
+			if len(fields) > 1 && strings.Contains(keptCSS, fields[1]) {
+				builder.WriteString(rule.prelude + "{" + rule.body + "}\n")
+			}
+		}
+	}
+
+	return builder.String()
+}
+
+// filterRules отбирает правила, подходящие к блоку, рекурсивно обрабатывая условные at-правила
+func filterRules(rules []cssRule, used *UsedSelectors) []string {
+	var kept []string
+
+	for _, rule := range rules {
+		prelude := strings.TrimSpace(rule.prelude)
+		if !rule.block {
+			continue
+		}
+
+		if strings.HasPrefix(prelude, "@") {
+			name := strings.ToLower(strings.Fields(prelude)[0])
+			switch name {
+			case "@media", "@supports", "@layer", "@container", "@document":
+				inner := filterRules(parseRules(rule.body), used)
+				if len(inner) > 0 {
+					kept = append(kept, prelude+"{"+strings.Join(inner, "\n")+"}")
+				}
+			}
+			continue
+		}
+
+		var selectors []string
+		for _, selector := range strings.Split(prelude, ",") {
+			selector = strings.TrimSpace(selector)
+			if selector != "" && selectorMatches(selector, used) {
+				selectors = append(selectors, selector)
+			}
+		}
+
+		if len(selectors) > 0 {
+			kept = append(kept, strings.Join(selectors, ",")+"{"+rule.body+"}")
+		}
+	}
+
+	return kept
+}
+
+// selectorMatches проверяет, может ли селектор относиться к элементу блока
+func selectorMatches(selector string, used *UsedSelectors) bool {
+	if globalSelectors[selector] {
+		return true
+	}
+
+	// Берем последний составной селектор
+	normalized := strings.NewReplacer(">", " ", "+", " ", "~", " ").Replace(selector)
+	compounds := strings.Fields(normalized)
+	if len(compounds) == 0 {
+		return false
+	}
+	subject := compounds[len(compounds)-1]
+
+	if globalSelectors[subject] {
+		return true
+	}
+
+	subject = pseudoRegexp.ReplaceAllString(subject, "")
+	subject = attributeSelectorRegexp.ReplaceAllString(subject, "")
+	if subject == "" || subject == "*" {
+		// Селектор вида [data-x] или :hover без уточнения
+		return len(compounds) > 1
+	}
+
+	for _, match := range classSelectorRegexp.FindAllStringSubmatch(subject, -1) {
+		if !used.Classes[match[1]] {
+			return false
+		}
+	}
+	for _, match := range idSelectorRegexp.FindAllStringSubmatch(subject, -1) {
+		if !used.IDs[match[1]] {
+			return false
+		}
+	}
+	if tag := tagSelectorRegexp.FindString(subject); tag != "" {
+		tag = strings.ToLower(tag)
+		if !used.Tags[tag] && !globalSelectors[tag] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseRules разбивает CSS на правила верхнего уровня с учетом вложенных скобок и строк
+func parseRules(css string) []cssRule {
+	var rules []cssRule
+	start := 0
+	i := 0
+
+	for i < len(css) {
+		switch css[i] {
+		case '"', '\'':
+			i = skipString(css, i)
+			continue
+		case ';':
+			// At-правило без блока (@import, @charset)
+			rules = append(rules, cssRule{prelude: strings.TrimSpace(css[start:i])})
+			start = i + 1
+		case '{':
+			end := matchBrace(css, i)
+			rules = append(rules, cssRule{
+				prelude: strings.TrimSpace(css[start:i]),
+				body:    css[i+1 : end],
+				block:   true,
+			})
+			i = end + 1
+			start = i
+			continue
+		}
+		i++
+	}
+
+	return rules
+}
+
+// matchBrace возвращает позицию закрывающей скобки для открывающей в позиции open.
+// Для незакрытого блока возвращается длина CSS
+func matchBrace(css string, open int) int {
+	depth := 0
+	for i := open; i < len(css); i++ {
+		switch css[i] {
+		case '"', '\'':
+			i = skipString(css, i) - 1
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(css)
+}
+
+// skipString возвращает позицию сразу после строкового литерала, начинающегося в позиции start
+func skipString(css string, start int) int {
+	quote := css[start]
+	for i := start + 1; i < len(css); i++ {
+		switch css[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+	return len(css)
+}
@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"website-scraper/internal/models"
+)
+
+// maxRedirects повторяет ограничение числа перенаправлений стандартного HTTP-клиента
+const maxRedirects = 10
+
+// Validate проверяет учетные данные и сценарий входа
+func Validate(opts *models.AuthOptions) error {
+	if opts == nil {
+		return nil
+	}
+
+	for name := range opts.Headers {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("имя заголовка не может быть пустым")
+		}
+	}
+
+	for _, cookie := range opts.Cookies {
+		if cookie.Name == "" {
+			return fmt.Errorf("имя cookie не может быть пустым")
+		}
+	}
+
+	if opts.BasicAuth != nil && opts.BasicAuth.Username == "" {
+		return fmt.Errorf("для basic auth нужно указать username")
+	}
+
+	if opts.Login != nil {
+		if len(opts.Login.Steps) == 0 {
+			return fmt.Errorf("сценарий входа не содержит шагов")
+		}
+		if opts.Login.URL != "" {
+			if parsed, err := url.Parse(opts.Login.URL); err != nil || parsed.Host == "" {
+				return fmt.Errorf("некорректный URL страницы входа")
+			}
+		}
+
+		for i, step := range opts.Login.Steps {
+			switch step.Action {
+			case models.LoginFill, models.LoginClick:
+				if step.Selector == "" {
+					return fmt.Errorf("шаг входа %d (%s): нужно указать selector", i+1, step.Action)
+				}
+			case models.LoginWait:
+				if step.Selector == "" && step.TimeoutMS <= 0 {
+					return fmt.Errorf("шаг входа %d (wait): нужно указать selector или timeout_ms", i+1)
+				}
+			default:
+				return fmt.Errorf("шаг входа %d: неизвестное действие %q (допустимо: fill, click, wait)", i+1, step.Action)
+			}
+			if step.TimeoutMS < 0 {
+				return fmt.Errorf("шаг входа %d: время ожидания не может быть отрицательным", i+1)
+			}
+		}
+	}
+
+	return nil
+}
+
+// StripCredentials убирает логин и пароль из URL, чтобы они не попали в операцию и логи.
+// Указанные в URL учетные данные переносятся в basic auth, если она не задана явно
+func StripCredentials(rawURL string, opts *models.AuthOptions) (string, *models.AuthOptions) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.User == nil {
+		return rawURL, opts
+	}
+
+	username := parsed.User.Username()
+	password, _ := parsed.User.Password()
+	parsed.User = nil
+
+	if opts == nil {
+		opts = &models.AuthOptions{}
+	}
+	if opts.BasicAuth == nil && username != "" {
+		opts.BasicAuth = &models.BasicAuth{Username: username, Password: password}
+	}
+
+	return parsed.String(), opts
+}
+
+// Scope ограничивает передачу учетных данных сайтом страницы и страницей входа,
+// чтобы заголовки и пароли не уходили сторонним ресурсам страницы
+type Scope struct {
+	opts  *models.AuthOptions
+	hosts map[string]bool
+}
+
+// NewScope создает область действия учетных данных для страницы. Возвращает nil без учетных данных
+func NewScope(pageURL string, opts *models.AuthOptions) *Scope {
+	if opts == nil {
+		return nil
+	}
+
+	scope := &Scope{opts: opts, hosts: make(map[string]bool)}
+	for _, rawURL := range []string{pageURL, loginURL(opts)} {
+		if parsed, err := url.Parse(rawURL); err == nil && parsed.Host != "" {
+			scope.hosts[strings.ToLower(parsed.Hostname())] = true
+		}
+	}
+
+	return scope
+}
+
+// loginURL возвращает адрес страницы входа, если он задан
+func loginURL(opts *models.AuthOptions) string {
+	if opts.Login == nil {
+		return ""
+	}
+	return opts.Login.URL
+}
+
+// Options возвращает учетные данные области
+func (s *Scope) Options() *models.AuthOptions {
+	return s.opts
+}
+
+// Allowed проверяет, можно ли передавать учетные данные по адресу
+func (s *Scope) Allowed(rawURL string) bool {
+	if s == nil {
+		return false
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return s.hosts[strings.ToLower(parsed.Hostname())]
+}
+
+// Headers возвращает заголовки, добавляемые к запросам сайта страницы
+func (s *Scope) Headers() map[string]string {
+	if s == nil {
+		return nil
+	}
+	return s.opts.Headers
+}
+
+// BasicAuth возвращает учетные данные basic auth или nil
+func (s *Scope) BasicAuth() *models.BasicAuth {
+	if s == nil {
+		return nil
+	}
+	return s.opts.BasicAuth
+}
+
+// Cookies возвращает cookies с доменом и путем по умолчанию для страницы
+func (s *Scope) Cookies(pageURL string) []models.AuthCookie {
+	host := ""
+	if parsed, err := url.Parse(pageURL); err == nil {
+		host = parsed.Hostname()
+	}
+
+	cookies := make([]models.AuthCookie, 0, len(s.opts.Cookies))
+	for _, cookie := range s.opts.Cookies {
+		if cookie.Domain == "" {
+			cookie.Domain = host
+		}
+		if cookie.Path == "" {
+			cookie.Path = "/"
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies
+}
+
+// SensitiveHeaders возвращает имена заголовков, значения которых нужно скрывать в записях запросов
+func (s *Scope) SensitiveHeaders() map[string]bool {
+	if s == nil {
+		return nil
+	}
+
+	names := make(map[string]bool, len(s.opts.Headers))
+	for name := range s.opts.Headers {
+		names[strings.ToLower(name)] = true
+	}
+	return names
+}
+
+// ApplyHTTP добавляет учетные данные к запросу HTTP-клиента, если адрес входит в область
+func (s *Scope) ApplyHTTP(req *http.Request) {
+	if !s.hosts[strings.ToLower(req.URL.Hostname())] {
+		return
+	}
+
+	for name, value := range s.opts.Headers {
+		req.Header.Set(name, value)
+	}
+
+	if basic := s.opts.BasicAuth; basic != nil {
+		req.SetBasicAuth(basic.Username, basic.Password)
+	}
+
+	for _, cookie := range s.Cookies(req.URL.String()) {
+		if cookieMatches(cookie, req.URL) {
+			req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+		}
+	}
+}
+
+// CheckRedirect применяется к перенаправлениям HTTP-клиента: при переходе на другой сайт
+// учетные данные удаляются из запроса, при возврате на сайт страницы добавляются снова
+func (s *Scope) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return errors.New("stopped after 10 redirects")
+	}
+
+	for name := range s.opts.Headers {
+		req.Header.Del(name)
+	}
+	req.Header.Del("Authorization")
+	req.Header.Del("Cookie")
+
+	s.ApplyHTTP(req)
+	return nil
+}
+
+// cookieMatches проверяет, что cookie отправляется по адресу
+func cookieMatches(cookie models.AuthCookie, target *url.URL) bool {
+	host := strings.ToLower(target.Hostname())
+	domain := strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+
+	if host != domain && !strings.HasSuffix(host, "."+domain) {
+		return false
+	}
+	if cookie.Secure && target.Scheme != "https" {
+		return false
+	}
+
+	path := target.Path
+	if path == "" {
+		path = "/"
+	}
+	return strings.HasPrefix(path, cookie.Path)
+}
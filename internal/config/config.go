@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,6 +12,10 @@ type Config struct {
 	Database   DatabaseConfig
 	Scraper    ScraperConfig
 	Downloader DownloaderConfig
+	Proxy      ProxyConfig
+	Monitor    MonitorConfig
+	Retention  RetentionConfig
+	Storage    StorageConfig
 }
 
 type ServerConfig struct {
@@ -35,10 +40,96 @@ type ScraperConfig struct {
 	AllowedDomains []string
 	Concurrency    int
 	CrawlDelay     time.Duration
+	// FetchStrategy задает способ загрузки страниц по умолчанию: http, browser или auto
+	FetchStrategy string
 }
 
 type DownloaderConfig struct {
 	OutputDir string
+	// Screenshots включает снимки страницы и блоков в браузере
+	Screenshots bool
+	// BrowserPoolSize задает число одновременно запущенных экземпляров Chrome
+	BrowserPoolSize int
+	// MaxTabs ограничивает число одновременно открытых вкладок во всех браузерах
+	MaxTabs int
+	// BrowserRecycleAfter задает число вкладок, после которого браузер перезапускается
+	BrowserRecycleAfter int
+	// HealthCheckInterval задает период проверки простаивающих браузеров
+	HealthCheckInterval time.Duration
+
+	// WaitStrategy задает способ ожидания готовности страницы: network_idle, dom_idle, selector или fixed
+	WaitStrategy string
+	// WaitSelector задает селектор для стратегии selector
+	WaitSelector string
+	// WaitIdle задает время без сетевой активности или изменений DOM
+	WaitIdle time.Duration
+	// WaitMax ограничивает общее время ожидания готовности страницы
+	WaitMax time.Duration
+	// AutoScroll включает прокрутку страницы для загрузки ленивых секций
+	AutoScroll bool
+}
+
+type ProxyConfig struct {
+	// URLs задает пул прокси: http://, https:// или socks5://, логин и пароль указываются в адресе
+	URLs []string
+	// Rotate включает поочередное использование прокси пула, иначе используется первый доступный
+	Rotate bool
+	// PinDomains закрепляет за доменом прокси, через который он загружался впервые
+	PinDomains bool
+	// MaxFailures задает число ошибок подряд, после которого прокси исключается из пула до проверки
+	MaxFailures int
+	// CheckInterval задает период проверки доступности прокси
+	CheckInterval time.Duration
+	// CheckURL задает адрес, запрашиваемый через прокси при проверке
+	CheckURL string
+}
+
+type MonitorConfig struct {
+	// PollInterval задает период поиска мониторов, время проверки которых наступило
+	PollInterval time.Duration
+	// Concurrency ограничивает число одновременных проверок
+	Concurrency int
+	// RunTimeout ограничивает время ожидания операции разбора при проверке
+	RunTimeout time.Duration
+	// MinInterval задает наименьший допустимый промежуток между проверками одного монитора
+	MinInterval time.Duration
+}
+
+type RetentionConfig struct {
+	// Interval задает период применения политики хранения, 0 отключает фоновую очистку
+	Interval time.Duration
+	// MaxAge задает срок хранения операций, 0 не ограничивает срок
+	MaxAge time.Duration
+	// MaxDiskMB ограничивает место, занятое файлами, в мегабайтах: при превышении удаляются
+	// самые старые операции. 0 не ограничивает место
+	MaxDiskMB int
+	// DryRun включает пробный режим фоновой очистки: операции только записываются в журнал
+	DryRun bool
+}
+
+type StorageConfig struct {
+	// Backend задает хранилище файлов: local (директория DOWNLOADER_OUTPUT_DIR) или s3
+	Backend string
+	// LinkExpiry задает срок действия ссылок на скачивание файлов
+	LinkExpiry time.Duration
+	// PublicURL задает адрес API для ссылок локального хранилища, без него ссылки относительные
+	PublicURL string
+	// SigningKey подписывает ссылки локального хранилища. Без ключа он создается при запуске
+	// и ссылки перестают действовать после перезапуска
+	SigningKey string
+
+	// S3Endpoint задает адрес S3-совместимого хранилища, например http://localhost:9000 для MinIO
+	S3Endpoint string
+	// S3Region задает регион для подписи запросов
+	S3Region string
+	// S3Bucket задает бакет для файлов
+	S3Bucket string
+	// S3Prefix задает префикс ключей в бакете, чтобы несколько установок делили один бакет
+	S3Prefix    string
+	S3AccessKey string
+	S3SecretKey string
+	// S3PathStyle включает адреса вида endpoint/bucket/key, которые нужны MinIO
+	S3PathStyle bool
 }
 
 func getEnv(key, defaultValue string) string {
@@ -48,6 +139,16 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 func getEnvInt(key string, defaultValue int) int {
 	if value, exists := os.LookupEnv(key); exists {
 		intValue, err := strconv.Atoi(value)
@@ -58,6 +159,16 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		boolValue, err := strconv.ParseBool(value)
+		if err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value, exists := os.LookupEnv(key); exists {
 		duration, err := time.ParseDuration(value)
@@ -89,6 +200,8 @@ func New() *Config {
 			MaxDepth:    getEnvInt("SCRAPER_MAX_DEPTH", 2),
 			Concurrency: getEnvInt("SCRAPER_CONCURRENCY", 5),
 			CrawlDelay:  getEnvDuration("SCRAPER_CRAWL_DELAY", 1*time.Second),
+
+			FetchStrategy: getEnv("SCRAPER_FETCH_STRATEGY", "browser"),
 			AllowedDomains: []string{
 				"botcreators.ru",
 				"structura.app",
@@ -98,7 +211,53 @@ func New() *Config {
 			},
 		},
 		Downloader: DownloaderConfig{
-			OutputDir: getEnv("DOWNLOADER_OUTPUT_DIR", "./downloads"),
+			OutputDir:   getEnv("DOWNLOADER_OUTPUT_DIR", "./downloads"),
+			Screenshots: getEnvBool("DOWNLOADER_SCREENSHOTS", true),
+
+			BrowserPoolSize:     getEnvInt("BROWSER_POOL_SIZE", 2),
+			MaxTabs:             getEnvInt("BROWSER_MAX_TABS", 4),
+			BrowserRecycleAfter: getEnvInt("BROWSER_RECYCLE_AFTER", 50),
+			HealthCheckInterval: getEnvDuration("BROWSER_HEALTH_CHECK_INTERVAL", 30*time.Second),
+
+			WaitStrategy: getEnv("DOWNLOADER_WAIT_STRATEGY", "network_idle"),
+			WaitSelector: getEnv("DOWNLOADER_WAIT_SELECTOR", ""),
+			WaitIdle:     getEnvDuration("DOWNLOADER_WAIT_IDLE", 500*time.Millisecond),
+			WaitMax:      getEnvDuration("DOWNLOADER_WAIT_MAX", 15*time.Second),
+			AutoScroll:   getEnvBool("DOWNLOADER_AUTO_SCROLL", true),
+		},
+		Proxy: ProxyConfig{
+			URLs:          getEnvList("PROXY_URLS"),
+			Rotate:        getEnvBool("PROXY_ROTATE", true),
+			PinDomains:    getEnvBool("PROXY_PIN_DOMAINS", true),
+			MaxFailures:   getEnvInt("PROXY_MAX_FAILURES", 3),
+			CheckInterval: getEnvDuration("PROXY_CHECK_INTERVAL", time.Minute),
+			CheckURL:      getEnv("PROXY_CHECK_URL", "https://www.gstatic.com/generate_204"),
+		},
+		Monitor: MonitorConfig{
+			PollInterval: getEnvDuration("MONITOR_POLL_INTERVAL", 30*time.Second),
+			Concurrency:  getEnvInt("MONITOR_CONCURRENCY", 2),
+			RunTimeout:   getEnvDuration("MONITOR_RUN_TIMEOUT", 10*time.Minute),
+			MinInterval:  getEnvDuration("MONITOR_MIN_INTERVAL", 5*time.Minute),
+		},
+		Retention: RetentionConfig{
+			Interval:  getEnvDuration("RETENTION_INTERVAL", time.Hour),
+			MaxAge:    getEnvDuration("RETENTION_MAX_AGE", 0),
+			MaxDiskMB: getEnvInt("RETENTION_MAX_DISK_MB", 0),
+			DryRun:    getEnvBool("RETENTION_DRY_RUN", false),
+		},
+		Storage: StorageConfig{
+			Backend:    getEnv("STORAGE_BACKEND", "local"),
+			LinkExpiry: getEnvDuration("STORAGE_LINK_EXPIRY", 15*time.Minute),
+			PublicURL:  getEnv("STORAGE_PUBLIC_URL", ""),
+			SigningKey: getEnv("STORAGE_SIGNING_KEY", ""),
+
+			S3Endpoint:  getEnv("STORAGE_S3_ENDPOINT", ""),
+			S3Region:    getEnv("STORAGE_S3_REGION", "us-east-1"),
+			S3Bucket:    getEnv("STORAGE_S3_BUCKET", ""),
+			S3Prefix:    getEnv("STORAGE_S3_PREFIX", ""),
+			S3AccessKey: getEnv("STORAGE_S3_ACCESS_KEY", ""),
+			S3SecretKey: getEnv("STORAGE_S3_SECRET_KEY", ""),
+			S3PathStyle: getEnvBool("STORAGE_S3_PATH_STYLE", true),
 		},
 	}
 }
@@ -14,13 +14,16 @@ import (
 	"github.com/PuerkitoBio/goquery"
 	"github.com/pkg/errors"
 
+	"website-scraper/internal/auth"
 	"website-scraper/internal/config"
+	"website-scraper/internal/models"
+	"website-scraper/internal/proxy"
 )
 
 // CrawlerService интерфейс для сервиса краулера
 type CrawlerService interface {
-	// CrawlURL обходит URL и собирает ссылки
-	CrawlURL(ctx context.Context, url string, maxDepth int) ([]string, error)
+	// CrawlURL обходит URL и собирает ссылки. Учетные данные передаются только сайту начального URL
+	CrawlURL(ctx context.Context, url string, maxDepth int, opts models.CrawlOptions) ([]string, error)
 
 	// IsAllowedDomain проверяет, разрешен ли домен для обхода
 	IsAllowedDomain(url string) bool
@@ -43,10 +46,11 @@ type crawlerService struct {
 	concurrency     int
 	crawlDelay      time.Duration
 	domainLastVisit sync.Map // Для отслеживания времени последнего посещения домена
+	proxies         *proxy.Pool
 }
 
 // NewCrawlerService создает новый экземпляр CrawlerService
-func NewCrawlerService(cfg *config.Config) CrawlerService {
+func NewCrawlerService(cfg *config.Config, proxies *proxy.Pool) CrawlerService {
 	return &crawlerService{
 		config:         cfg,
 		userAgent:      cfg.Scraper.UserAgent,
@@ -59,11 +63,12 @@ func NewCrawlerService(cfg *config.Config) CrawlerService {
 		domainLastVisit: sync.Map{},
 		concurrency:     cfg.Scraper.Concurrency,
 		crawlDelay:      cfg.Scraper.CrawlDelay,
+		proxies:         proxies,
 	}
 }
 
 // CrawlURL обходит URL и собирает ссылки
-func (s *crawlerService) CrawlURL(ctx context.Context, urlStr string, maxDepth int) ([]string, error) {
+func (s *crawlerService) CrawlURL(ctx context.Context, urlStr string, maxDepth int, opts models.CrawlOptions) ([]string, error) {
 	// Парсим начальный URL
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
@@ -80,6 +85,20 @@ func (s *crawlerService) CrawlURL(ctx context.Context, urlStr string, maxDepth i
 		s.maxDepth = maxDepth
 	}
 
+	// Клиент с областью учетных данных, чтобы они не уходили на другие сайты при перенаправлениях
+	scope := auth.NewScope(urlStr, opts.Auth)
+	client := s.client
+	if scope != nil {
+		scoped := *s.client
+		scoped.CheckRedirect = scope.CheckRedirect
+		client = &scoped
+	}
+
+	// Прокси запроса проверяем сразу, чтобы не начинать обход с некорректным адресом
+	if err := proxy.Validate(opts.Proxy); err != nil {
+		return nil, err
+	}
+
 	// Создаем канал для результатов
 	results := make(chan string, 100)
 	resultList := []string{}
@@ -93,7 +112,7 @@ func (s *crawlerService) CrawlURL(ctx context.Context, urlStr string, maxDepth i
 	// Начинаем с начального URL
 	wg.Add(1)
 	go func() {
-		s.crawlRecursive(ctx, urlStr, 0, results, &wg, sem)
+		s.crawlRecursive(ctx, client, scope, opts.Proxy, urlStr, 0, results, &wg, sem)
 	}()
 
 	// Создаем горутину для закрытия канала результатов когда обход закончен
@@ -113,6 +132,9 @@ func (s *crawlerService) CrawlURL(ctx context.Context, urlStr string, maxDepth i
 // crawlRecursive рекурсивно обходит URLs до указанной глубины
 func (s *crawlerService) crawlRecursive(
 	ctx context.Context,
+	client *http.Client,
+	scope *auth.Scope,
+	proxyOverride string,
 	urlStr string,
 	depth int,
 	results chan<- string,
@@ -158,9 +180,19 @@ func (s *crawlerService) crawlRecursive(
 		return
 	}
 	req.Header.Set("User-Agent", s.userAgent)
+	if scope != nil {
+		scope.ApplyHTTP(req)
+	}
+
+	// Прокси выбирается для каждого адреса, чтобы учитывать закрепление доменов
+	proxied, err := s.proxies.Select(normalizedURL, proxyOverride)
+	if err != nil {
+		return
+	}
 
 	// Выполняем запрос
-	resp, err := s.client.Do(req)
+	resp, err := s.proxies.Client(client, proxied).Do(req)
+	s.proxies.Report(proxied, proxy.ResponseError(resp, err))
 	if err != nil {
 		return
 	}
@@ -188,7 +220,7 @@ func (s *crawlerService) crawlRecursive(
 		if s.IsAllowedDomain(link) {
 			wg.Add(1)
 			go func(url string) {
-				s.crawlRecursive(ctx, url, depth+1, results, wg, sem)
+				s.crawlRecursive(ctx, client, scope, proxyOverride, url, depth+1, results, wg, sem)
 			}(link)
 		}
 	}
@@ -0,0 +1,207 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+
+	"website-scraper/internal/auth"
+	"website-scraper/internal/models"
+	"website-scraper/internal/proxy"
+)
+
+// defaultLoginStepTimeout ограничивает ожидание элемента в шаге входа
+const defaultLoginStepTimeout = 10 * time.Second
+
+// authScope возвращает область действия учетных данных загрузки. При воспроизведении HAR
+// обращений к сети нет, и учетные данные не используются
+func (d *Downloader) authScope(pageURL string, opts models.ParseOptions) *auth.Scope {
+	if opts.ReplayHAR != nil {
+		return nil
+	}
+	return auth.NewScope(pageURL, opts.Auth)
+}
+
+// applyAuth готовит вкладку к загрузке закрытой страницы: устанавливает cookies, включает
+// перехват запросов для заголовков, basic auth и авторизации на прокси и выполняет сценарий входа.
+// Вызывается до перехода на страницу; значения учетных данных в логи не пишутся
+func (d *Downloader) applyAuth(taskCtx context.Context, pageURL string, scope *auth.Scope, proxied *proxy.Proxy) error {
+	_, _, proxyAuth := proxied.Credentials()
+	if scope == nil && !proxyAuth {
+		return nil
+	}
+
+	var actions []chromedp.Action
+
+	if scope != nil {
+		for _, cookie := range scope.Cookies(pageURL) {
+			actions = append(actions, network.SetCookie(cookie.Name, cookie.Value).
+				WithDomain(cookie.Domain).
+				WithPath(cookie.Path).
+				WithSecure(cookie.Secure).
+				WithHTTPOnly(cookie.HTTPOnly))
+		}
+	}
+
+	if proxyAuth || len(scope.Headers()) > 0 || scope.BasicAuth() != nil {
+		actions = append(actions, interceptAuth(taskCtx, scope, proxied))
+	}
+
+	if err := chromedp.Run(taskCtx, actions...); err != nil {
+		return fmt.Errorf("ошибка установки учетных данных: %w", err)
+	}
+
+	if scope != nil && scope.Options().Login != nil {
+		return runLogin(taskCtx, pageURL, scope.Options().Login)
+	}
+
+	return nil
+}
+
+// interceptAuth перехватывает запросы вкладки и добавляет заголовки и basic auth только
+// к запросам сайта страницы, а на запрос авторизации прокси отвечает его логином и паролем.
+// Возвращает действие, включающее перехват
+func interceptAuth(ctx context.Context, scope *auth.Scope, proxied *proxy.Proxy) chromedp.Action {
+	basic := scope.BasicAuth()
+	proxyUser, proxyPassword, proxyAuth := proxied.Credentials()
+
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *fetch.EventRequestPaused:
+			// Команды нельзя выполнять в обработчике событий, он блокирует их получение
+			go func() {
+				executor := cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Target)
+
+				next := fetch.ContinueRequest(ev.RequestID)
+				if scope.Allowed(ev.Request.URL) && len(scope.Headers()) > 0 {
+					next = next.WithHeaders(mergeHeaders(ev.Request.Headers, scope.Headers()))
+				}
+
+				if err := next.Do(executor); err != nil && ctx.Err() == nil {
+					log.Printf("Ошибка продолжения запроса %s: %v", ev.Request.URL, err)
+				}
+			}()
+
+		case *fetch.EventAuthRequired:
+			go func() {
+				executor := cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Target)
+
+				response := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseCancelAuth}
+				switch {
+				case ev.AuthChallenge.Source == fetch.AuthChallengeSourceProxy:
+					if proxyAuth {
+						response = &fetch.AuthChallengeResponse{
+							Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+							Username: proxyUser,
+							Password: proxyPassword,
+						}
+					}
+				case basic != nil && scope.Allowed(ev.Request.URL):
+					response = &fetch.AuthChallengeResponse{
+						Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+						Username: basic.Username,
+						Password: basic.Password,
+					}
+				}
+
+				if err := fetch.ContinueWithAuth(ev.RequestID, response).Do(executor); err != nil && ctx.Err() == nil {
+					log.Printf("Ошибка ответа на запрос авторизации %s: %v", ev.Request.URL, err)
+				}
+			}()
+		}
+	})
+
+	return fetch.Enable().WithHandleAuthRequests(basic != nil || proxyAuth)
+}
+
+// mergeHeaders дополняет заголовки запроса браузера заголовками из учетных данных.
+// Имена заголовков сравниваются без учета регистра: user-agent заменяет User-Agent браузера
+func mergeHeaders(original network.Headers, extra map[string]string) []*fetch.HeaderEntry {
+	headers := make([]*fetch.HeaderEntry, 0, len(original)+len(extra))
+	for name, value := range original {
+		if hasHeader(extra, name) {
+			continue
+		}
+		headers = append(headers, &fetch.HeaderEntry{Name: name, Value: fmt.Sprint(value)})
+	}
+	for name, value := range extra {
+		headers = append(headers, &fetch.HeaderEntry{Name: name, Value: value})
+	}
+	return headers
+}
+
+// hasHeader проверяет, есть ли заголовок среди заданных, без учета регистра имени
+func hasHeader(headers map[string]string, name string) bool {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// runLogin открывает страницу входа и выполняет шаги сценария. Сессия сохраняется
+// в контексте браузера вкладки и используется при загрузке страницы
+func runLogin(ctx context.Context, pageURL string, login *models.LoginScript) error {
+	loginURL := login.URL
+	if loginURL == "" {
+		loginURL = pageURL
+	}
+
+	if err := chromedp.Run(ctx,
+		chromedp.Navigate(loginURL),
+		chromedp.WaitReady("body", chromedp.ByQuery),
+	); err != nil {
+		return fmt.Errorf("ошибка загрузки страницы входа: %w", err)
+	}
+
+	for i, step := range login.Steps {
+		if err := runLoginStep(ctx, step); err != nil {
+			// Значение поля в ошибку не попадает: это может быть пароль
+			return fmt.Errorf("шаг входа %d (%s %s): %w", i+1, step.Action, step.Selector, err)
+		}
+	}
+
+	log.Printf("Сценарий входа выполнен: шагов %d", len(login.Steps))
+	return nil
+}
+
+// runLoginStep выполняет один шаг сценария входа
+func runLoginStep(ctx context.Context, step models.LoginStep) error {
+	timeout := defaultLoginStepTimeout
+	if step.TimeoutMS > 0 {
+		timeout = time.Duration(step.TimeoutMS) * time.Millisecond
+	}
+
+	if step.Action == models.LoginWait && step.Selector == "" {
+		return chromedp.Run(ctx, chromedp.Sleep(timeout))
+	}
+
+	stepCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch step.Action {
+	case models.LoginFill:
+		return chromedp.Run(stepCtx,
+			chromedp.WaitVisible(step.Selector, chromedp.ByQuery),
+			chromedp.SetValue(step.Selector, "", chromedp.ByQuery),
+			chromedp.SendKeys(step.Selector, step.Value, chromedp.ByQuery),
+		)
+	case models.LoginClick:
+		return chromedp.Run(stepCtx,
+			chromedp.WaitVisible(step.Selector, chromedp.ByQuery),
+			chromedp.Click(step.Selector, chromedp.ByQuery),
+		)
+	case models.LoginWait:
+		return chromedp.Run(stepCtx, chromedp.WaitVisible(step.Selector, chromedp.ByQuery))
+	}
+
+	return fmt.Errorf("неизвестное действие")
+}
@@ -0,0 +1,127 @@
+package downloader
+
+import (
+	"fmt"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+
+	"website-scraper/internal/models"
+)
+
+const (
+	// minDeviceSize и maxDeviceSize ограничивают размеры окна эмулируемого устройства
+	minDeviceSize = 200
+	maxDeviceSize = 7680
+	// maxDeviceScaleFactor ограничивает плотность пикселей, чтобы снимки не занимали гигабайты
+	maxDeviceScaleFactor = 4
+)
+
+// devicePresets содержит параметры стандартных устройств. Пустой User-Agent десктопа
+// заменяется User-Agent из конфигурации
+var devicePresets = map[models.DevicePreset]models.DeviceOptions{
+	models.DeviceDesktop: {
+		Preset:      models.DeviceDesktop,
+		Width:       1920,
+		Height:      1080,
+		ScaleFactor: 1,
+	},
+	models.DeviceTablet: {
+		Preset:      models.DeviceTablet,
+		Width:       800,
+		Height:      1280,
+		ScaleFactor: 2,
+		Mobile:      true,
+		UserAgent:   "Mozilla/5.0 (Linux; Android 14; SM-X710) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	},
+	models.DeviceMobile: {
+		Preset:      models.DeviceMobile,
+		Width:       412,
+		Height:      915,
+		ScaleFactor: 2.625,
+		Mobile:      true,
+		UserAgent:   "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+	},
+}
+
+// ResolveDevice дополняет параметры устройства значениями пресета и проверяет их.
+// Без пресета с заданными размерами устройство считается custom
+func ResolveDevice(device *models.DeviceOptions) (*models.DeviceOptions, error) {
+	if device == nil {
+		return nil, nil
+	}
+
+	resolved := *device
+	if resolved.Preset == "" {
+		resolved.Preset = models.DeviceCustom
+		if resolved.Width == 0 && resolved.Height == 0 {
+			resolved.Preset = models.DeviceDesktop
+		}
+	}
+
+	if resolved.Preset != models.DeviceCustom {
+		preset, ok := devicePresets[resolved.Preset]
+		if !ok {
+			return nil, fmt.Errorf("неизвестное устройство: %s (допустимо: desktop, tablet, mobile, custom)", resolved.Preset)
+		}
+
+		// Размеры и User-Agent пресета можно переопределить, мобильный режим задается пресетом
+		resolved.Mobile = preset.Mobile
+		if resolved.Width == 0 {
+			resolved.Width = preset.Width
+		}
+		if resolved.Height == 0 {
+			resolved.Height = preset.Height
+		}
+		if resolved.ScaleFactor == 0 {
+			resolved.ScaleFactor = preset.ScaleFactor
+		}
+		if resolved.UserAgent == "" {
+			resolved.UserAgent = preset.UserAgent
+		}
+	}
+
+	if resolved.ScaleFactor == 0 {
+		resolved.ScaleFactor = 1
+	}
+
+	if resolved.Width < minDeviceSize || resolved.Width > maxDeviceSize ||
+		resolved.Height < minDeviceSize || resolved.Height > maxDeviceSize {
+		return nil, fmt.Errorf("размеры устройства должны быть от %d до %d пикселей", minDeviceSize, maxDeviceSize)
+	}
+	if resolved.ScaleFactor < 0 || resolved.ScaleFactor > maxDeviceScaleFactor {
+		return nil, fmt.Errorf("scale_factor должен быть от 0 до %d", maxDeviceScaleFactor)
+	}
+
+	return &resolved, nil
+}
+
+// Viewport возвращает отметку устройства для блоков страницы
+func Viewport(device *models.DeviceOptions) models.DevicePreset {
+	if device == nil || device.Preset == "" {
+		return models.DeviceDesktop
+	}
+	return device.Preset
+}
+
+// userAgent возвращает User-Agent загрузки с учетом эмулируемого устройства
+func (d *Downloader) userAgent(device *models.DeviceOptions) string {
+	if device != nil && device.UserAgent != "" {
+		return device.UserAgent
+	}
+	return d.cfg.Scraper.UserAgent
+}
+
+// emulateDevice задает вкладке размеры экрана, плотность пикселей, касания и User-Agent устройства.
+// Выполняется до перехода на страницу, чтобы сайт сразу отдал нужную верстку
+func (d *Downloader) emulateDevice(device *models.DeviceOptions) chromedp.Action {
+	if device == nil {
+		return chromedp.Tasks{}
+	}
+
+	return chromedp.Tasks{
+		emulation.SetDeviceMetricsOverride(int64(device.Width), int64(device.Height), device.ScaleFactor, device.Mobile),
+		emulation.SetTouchEmulationEnabled(device.Mobile),
+		emulation.SetUserAgentOverride(d.userAgent(device)),
+	}
+}
@@ -0,0 +1,166 @@
+package downloader
+
+import (
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"website-scraper/internal/models"
+)
+
+// diffReportStyles дополняет стили сводки блоков разметкой изменений
+const diffReportStyles = `        .change-added { border-left: 5px solid #4caf50; }
+        .change-removed { border-left: 5px solid #f44336; }
+        .change-modified { border-left: 5px solid #ff9800; }
+        .change-moved { border-left: 5px solid #2196f3; }
+        .diff-lines { font-family: monospace; font-size: 13px; white-space: pre-wrap; margin: 10px 0; background: white; border: 1px solid #ccc; padding: 5px; max-height: 300px; overflow: auto; }
+        .diff-insert { background: #e6ffed; }
+        .diff-delete { background: #ffeef0; }
+        .diff-summary td { border: 1px solid #ddd; padding: 3px 12px; }
+        .block-preview { width: 100%; height: 300px; border: 1px solid #ccc; background: white; }
+`
+
+// DiffReportCSP задает политику безопасности для отчета о сравнении: скрипты запрещены,
+// стили и изображения блоков разрешены. Фреймы srcdoc наследуют политику отчета
+const DiffReportCSP = "default-src 'none'; style-src 'unsafe-inline' *; img-src * data:; font-src * data:; frame-src 'self'"
+
+// diffChangeTitles содержит заголовки изменений блоков в отчете
+var diffChangeTitles = map[models.BlockChangeType]string{
+	models.BlockAdded:    "Добавлен",
+	models.BlockRemoved:  "Удален",
+	models.BlockModified: "Изменен",
+	models.BlockMoved:    "Перемещен",
+}
+
+// CreateDiffReport формирует HTML-отчет о сравнении двух операций в стиле сводки блоков:
+// сводку изменений и карточку каждого добавленного, удаленного, измененного или перемещенного блока
+func CreateDiffReport(diff *models.OperationDiff, oldBlocks, newBlocks []models.Block) string {
+	blocks := make(map[uuid.UUID]models.Block, len(oldBlocks)+len(newBlocks))
+	for _, list := range [][]models.Block{oldBlocks, newBlocks} {
+		for _, block := range list {
+			blocks[block.ID] = block
+		}
+	}
+
+	title := "Сравнение операций " + diff.OldOperationID.String() + " и " + diff.NewOperationID.String()
+
+	report := `<!DOCTYPE html>
+<html lang="ru">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>` + title + `</title>
+    <style>
+` + summaryStyles + diffReportStyles + `    </style>
+</head>
+<body>
+    <h1>` + title + `</h1>
+    <div class="toc">
+        <strong>Было:</strong> ` + html.EscapeString(diff.OldURL) + ` (` + diff.OldOperationID.String() + `)<br>
+        <strong>Стало:</strong> ` + html.EscapeString(diff.NewURL) + ` (` + diff.NewOperationID.String() + `)
+        <table class="diff-summary">
+            <tr><td>Добавлено</td><td>` + strconv.Itoa(diff.Summary.Added) + `</td></tr>
+            <tr><td>Удалено</td><td>` + strconv.Itoa(diff.Summary.Removed) + `</td></tr>
+            <tr><td>Изменено</td><td>` + strconv.Itoa(diff.Summary.Modified) + `</td></tr>
+            <tr><td>Перемещено</td><td>` + strconv.Itoa(diff.Summary.Moved) + `</td></tr>
+            <tr><td>Без изменений</td><td>` + strconv.Itoa(diff.Summary.Unchanged) + `</td></tr>
+        </table>
+    </div>`
+
+	if len(diff.Changes) == 0 {
+		report += `
+    <p>Блоки операций совпадают.</p>`
+	}
+
+	for _, change := range diff.Changes {
+		report += diffChangeHTML(change, blocks)
+	}
+
+	report += `
+</body>
+</html>`
+
+	return report
+}
+
+// diffChangeHTML формирует карточку изменения блока
+func diffChangeHTML(change models.BlockChange, blocks map[uuid.UUID]models.Block) string {
+	title := diffChangeTitles[change.Change]
+	if change.Moved {
+		title += " и перемещен"
+	}
+	title += ": " + string(change.BlockType)
+	if change.TemplateName != "" {
+		title += " (" + html.EscapeString(change.TemplateName) + ")"
+	}
+
+	card := `
+    <div class="platform-section change-` + string(change.Change) + `">
+        <h3>` + title + `</h3>
+        <div class="block-info">`
+
+	if change.OldBlockID != nil {
+		card += `
+            <strong>Было:</strong> блок ` + change.OldBlockID.String() + `, позиция ` + strconv.Itoa(change.OldPosition) + `<br>`
+	}
+	if change.NewBlockID != nil {
+		card += `
+            <strong>Стало:</strong> блок ` + change.NewBlockID.String() + `, позиция ` + strconv.Itoa(change.NewPosition) + `<br>`
+	}
+	if change.Change == models.BlockModified {
+		card += `
+            <strong>Сходство:</strong> ` + strconv.Itoa(int(change.Similarity*100)) + `%`
+	}
+	card += `
+        </div>`
+
+	if change.Change == models.BlockModified {
+		card += diffLinesHTML("Текст", change.TextDiff) +
+			diffLinesHTML("HTML", change.HTMLDiff) +
+			diffLinesHTML("Структура", change.StructureDiff)
+	}
+
+	// Новый вид блока показывается для всех изменений, кроме удаления
+	blockID := change.NewBlockID
+	if change.Change == models.BlockRemoved {
+		blockID = change.OldBlockID
+	}
+	// HTML блока со страницы сайта выводится в фрейме без скриптов и с отдельным источником,
+	// чтобы его разметка не выполнялась в источнике API
+	if block, ok := blocks[*blockID]; ok {
+		card += `
+        <div class="block-container">
+            <iframe class="block-preview" sandbox srcdoc="` + html.EscapeString(block.HTML) + `" title="Блок ` + block.ID.String() + `"></iframe>
+        </div>`
+	}
+
+	card += `
+    </div>`
+
+	return card
+}
+
+// diffLinesHTML формирует список добавленных и удаленных строк
+func diffLinesHTML(caption string, lines []models.DiffLine) string {
+	if len(lines) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString(`
+        <strong>` + caption + `:</strong>
+        <div class="diff-lines">`)
+
+	for _, line := range lines {
+		class, sign := "diff-insert", "+ "
+		if line.Op == models.DiffDelete {
+			class, sign = "diff-delete", "- "
+		}
+		builder.WriteString(`<div class="` + class + `">` + sign + html.EscapeString(line.Text) + `</div>`)
+	}
+
+	builder.WriteString(`</div>`)
+	return builder.String()
+}
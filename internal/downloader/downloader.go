@@ -4,12 +4,15 @@ import (
 	"archive/zip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"io"
-	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
-	"path/filepath"
+	"path"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -21,147 +24,246 @@ import (
 
 	"website-scraper/internal/config"
 	"website-scraper/internal/models"
+	"website-scraper/internal/proxy"
+	"website-scraper/internal/storage"
 )
 
 // Downloader представляет сервис для загрузки веб-страниц и блоков
 type Downloader struct {
-	cfg *config.Config
+	cfg    *config.Config
+	client *http.Client
+	pool   *BrowserPool
+	// proxies выбирает прокси для загрузок
+	proxies *proxy.Pool
+	// storage хранит HTML страниц, файлы блоков и архивы
+	storage storage.Storage
 }
 
 // NewDownloader создает новый экземпляр Downloader
-func NewDownloader(cfg *config.Config) *Downloader {
-	// Создаем директории для сохранения блоков
-	directories := []string{
-		cfg.Downloader.OutputDir,
-		filepath.Join(cfg.Downloader.OutputDir, "blocks"),
-		filepath.Join(cfg.Downloader.OutputDir, "html"),
-		filepath.Join(cfg.Downloader.OutputDir, "exports"),
-	}
-
-	for _, dir := range directories {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			log.Printf("Ошибка создания директории %s: %v", dir, err)
-		}
+func NewDownloader(cfg *config.Config, pool *BrowserPool, proxies *proxy.Pool, files storage.Storage) *Downloader {
+	return &Downloader{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: cfg.Scraper.Timeout},
+		pool:    pool,
+		proxies: proxies,
+		storage: files,
 	}
+}
 
-	return &Downloader{
-		cfg: cfg,
+// PageResult представляет результат загрузки страницы в браузере
+type PageResult struct {
+	HTML string
+	// Screenshot содержит снимок всей страницы в PNG, если снимки включены
+	Screenshot []byte
+	// Strategy содержит способ, которым страница фактически загружена
+	Strategy models.FetchStrategy
+	// Response содержит метаданные ответа на запрос документа страницы
+	Response *models.ResponseMeta
+	// HAR содержит запись загрузки страницы, если она запрошена
+	HAR *HAR
+
+	// tab и release хранят вкладку браузера, оставленную открытой для снимков блоков
+	tab     context.Context
+	release context.CancelFunc
+}
+
+// Close закрывает вкладку браузера, оставленную для снимков блоков. Повторный вызов ничего не делает
+func (p *PageResult) Close() {
+	if p == nil || p.release == nil {
+		return
 	}
+	p.release()
+	p.tab, p.release = nil, nil
 }
 
-// DownloadPage загружает страницу и возвращает HTML
-func (d *Downloader) DownloadPage(ctx context.Context, url string) (string, error) {
-	// Определяем путь к браузеру
-	var execPath string
-
-	// Проверяем, какой браузер установлен
-	if _, err := os.Stat("/usr/bin/google-chrome"); err == nil {
-		execPath = "/usr/bin/google-chrome"
-	} else if _, err := os.Stat("/usr/bin/chromium-browser"); err == nil {
-		execPath = "/usr/bin/chromium-browser"
-	} else if _, err := os.Stat("/usr/bin/chromium"); err == nil {
-		execPath = "/usr/bin/chromium"
-	}
-
-	// Essential Chrome flags for Docker environment
-	opts := []chromedp.ExecAllocatorOption{
-		chromedp.NoFirstRun,
-		chromedp.NoDefaultBrowserCheck,
-		chromedp.Headless,
-		chromedp.Flag("disable-gpu", true),
-		chromedp.Flag("no-sandbox", true),
-		chromedp.Flag("disable-setuid-sandbox", true),
-		chromedp.Flag("disable-dev-shm-usage", true),
-		chromedp.Flag("disable-background-timer-throttling", true),
-		chromedp.Flag("disable-backgrounding-occluded-windows", true),
-		chromedp.Flag("disable-renderer-backgrounding", true),
-		chromedp.Flag("disable-features", "TranslateUI"),
-		chromedp.Flag("disable-ipc-flooding-protection", true),
-		chromedp.Flag("disable-background-networking", true),
-		chromedp.Flag("disable-client-side-phishing-detection", true),
-		chromedp.Flag("disable-default-apps", true),
-		chromedp.UserAgent(d.cfg.Scraper.UserAgent),
-		chromedp.WindowSize(1920, 1080),
-	}
-
-	// Добавляем путь к браузеру, если нашли
-	if execPath != "" {
-		opts = append(opts, chromedp.ExecPath(execPath))
-	}
-
-	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
-	defer cancel()
-
-	// Create context with timeout
-	taskCtx, cancel := chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Printf))
-	defer cancel()
+// DownloadPage загружает страницу и возвращает HTML вместе со снимком всей страницы
+// и метаданными ответа. Ответ вне 2xx ошибкой не считается и отмечается в Response.IsError.
+// При включенных снимках вкладка остается открытой для CaptureBlocks, и ее нужно закрыть через Close
+func (d *Downloader) DownloadPage(ctx context.Context, url string, opts models.ParseOptions) (*PageResult, error) {
+	scope := d.authScope(url, opts)
 
-	// Set timeout for the whole operation
-	taskCtx, cancel = context.WithTimeout(taskCtx, d.cfg.Scraper.Timeout)
-	defer cancel()
+	proxied, err := d.proxyFor(url, opts)
+	if err != nil {
+		return nil, err
+	}
 
-	var html string
+	taskCtx, cancel, err := d.newBrowserContext(ctx, scope != nil, proxied)
+	if err != nil {
+		return nil, err
+	}
+	keepTab := false
+	defer func() {
+		if !keepTab {
+			cancel()
+		}
+	}()
+
+	result := &PageResult{Strategy: models.FetchBrowser}
+
+	if err := chromedp.Run(taskCtx, d.emulateDevice(opts.Device)); err != nil {
+		return nil, fmt.Errorf("ошибка эмуляции устройства: %w", err)
+	}
+
+	if err := d.startReplay(taskCtx, opts); err != nil {
+		return nil, err
+	}
+
+	// Сценарий входа выполняется до подписки на события, чтобы его запросы не попали в результат
+	if err := d.applyAuth(taskCtx, url, scope, proxied); err != nil {
+		return nil, err
+	}
+
+	waiter := d.newPageWaiter(opts.Wait)
+	waiter.Listen(taskCtx)
+
+	recorder := &responseRecorder{}
+	recorder.Listen(taskCtx)
+
+	var har *harRecorder
+	if opts.RecordHAR {
+		har = newHARRecorder(url, scope.SensitiveHeaders())
+		har.Listen(taskCtx)
+	}
+
+	started := time.Now()
+
+	// Ответ на запрос документа доступен только во время перехода на страницу
+	resp, err := chromedp.RunResponse(taskCtx, chromedp.Navigate(url))
+	d.proxies.Report(proxied, navigationError(resp, err))
+	if err != nil {
+		log.Printf("Error downloading page %s: %v", url, err)
+		return nil, err
+	}
 
 	// Navigation and HTML extraction with better error handling
-	err := chromedp.Run(taskCtx,
-		chromedp.Navigate(url),
+	err = chromedp.Run(taskCtx,
 		chromedp.WaitReady("body", chromedp.ByQuery),
-		chromedp.Sleep(2*time.Second), // Wait for JS to execute
-		chromedp.OuterHTML("html", &html),
+		waiter.Wait(),
+		chromedp.OuterHTML("html", &result.HTML),
 	)
 
 	if err != nil {
 		log.Printf("Error downloading page %s: %v", url, err)
-		return "", err
+		return nil, err
+	}
+
+	// Страницы из кэша или data: URL могут не иметь сетевого ответа
+	if resp != nil {
+		result.Response = responseMetaFromCDP(resp, recorder.Redirects(), time.Since(started))
+	}
+
+	// Тела ответов нужно забрать до закрытия вкладки
+	if har != nil {
+		if err := chromedp.Run(taskCtx, har.Finish(&result.HAR)); err != nil {
+			log.Printf("Ошибка записи HAR %s: %v", url, err)
+		}
+	}
+
+	// Снимок страницы не обязателен, ошибка не должна срывать загрузку
+	if d.cfg.Downloader.Screenshots {
+		if err := chromedp.Run(taskCtx, chromedp.FullScreenshot(&result.Screenshot, 100)); err != nil {
+			log.Printf("Ошибка создания снимка страницы %s: %v", url, err)
+			result.Screenshot = nil
+		}
+
+		// Блоки снимаются в этой же вкладке: селекторы построены по ее DOM, а повторная загрузка стоила бы еще одного запроса
+		result.tab, result.release = taskCtx, cancel
+		keepTab = true
 	}
 
 	// Сохраняем HTML-файл
-	if err := d.SaveHTML(url, html); err != nil {
+	if err := d.SaveHTML(ctx, url, result.HTML); err != nil {
 		log.Printf("Ошибка сохранения HTML: %v", err)
 	}
 
 	log.Printf("Successfully downloaded page: %s", url)
-	return html, nil
+	return result, nil
 }
 
-// SaveHTML сохраняет HTML страницы в файл
-func (d *Downloader) SaveHTML(url, html string) error {
-	// Создаем имя файла на основе URL
-	filename := d.sanitizeFilename(url) + ".html"
-	filepath := filepath.Join(d.cfg.Downloader.OutputDir, "html", filename)
-
-	file, err := os.Create(filepath)
+// newBrowserContext открывает вкладку в пуле браузеров с таймаутом загрузки.
+// Изолированная вкладка получает собственные cookies и кэш; вкладка с прокси всегда изолирована,
+// так как прокси задается для контекста браузера
+func (d *Downloader) newBrowserContext(ctx context.Context, isolated bool, proxied *proxy.Proxy) (context.Context, context.CancelFunc, error) {
+	var (
+		tabCtx  context.Context
+		release context.CancelFunc
+		err     error
+	)
+	switch {
+	case proxied != nil:
+		tabCtx, release, err = d.pool.AcquireIsolated(ctx, proxied.Server())
+	case isolated:
+		tabCtx, release, err = d.pool.AcquireIsolated(ctx, "")
+	default:
+		tabCtx, release, err = d.pool.Acquire(ctx)
+	}
 	if err != nil {
-		return fmt.Errorf("ошибка создания файла: %w", err)
+		return nil, nil, fmt.Errorf("ошибка получения вкладки браузера: %w", err)
 	}
-	defer file.Close()
 
-	_, err = file.WriteString(html)
-	if err != nil {
-		return fmt.Errorf("ошибка записи в файл: %w", err)
+	// Set timeout for the whole operation
+	taskCtx, cancel := context.WithTimeout(tabCtx, d.cfg.Scraper.Timeout)
+
+	return taskCtx, func() {
+		cancel()
+		release()
+	}, nil
+}
+
+// SaveHTML сохраняет HTML страницы в хранилище
+func (d *Downloader) SaveHTML(ctx context.Context, url, html string) error {
+	key := d.pageHTMLKey(url)
+	if err := storage.PutBytes(ctx, d.storage, key, []byte(html), htmlContentType); err != nil {
+		return fmt.Errorf("ошибка сохранения HTML страницы: %w", err)
 	}
 
-	log.Printf("HTML сохранен в: %s", filepath)
+	log.Printf("HTML сохранен в: %s", key)
 	return nil
 }
 
-// SaveBlock сохраняет блок в файл
-func (d *Downloader) SaveBlock(block *models.Block) error {
-	// Создаем директорию для блока
-	blockDir := filepath.Join(d.cfg.Downloader.OutputDir, "blocks", block.OperationID.String())
-	if err := os.MkdirAll(blockDir, 0755); err != nil {
-		return fmt.Errorf("ошибка создания директории: %w", err)
+// LoadHTML возвращает сохраненный ранее HTML страницы
+func (d *Downloader) LoadHTML(ctx context.Context, url string) (string, error) {
+	data, err := storage.ReadAll(ctx, d.storage, d.pageHTMLKey(url))
+	if err != nil {
+		return "", fmt.Errorf("ошибка чтения HTML страницы: %w", err)
 	}
+	return string(data), nil
+}
 
-	// Сохраняем HTML блока
-	htmlFilename := fmt.Sprintf("%s_%s.html", block.BlockType, block.ID.String())
-	htmlPath := filepath.Join(blockDir, htmlFilename)
+// SaveSnapshot сохраняет HTML, разобранный операцией. В отличие от SaveHTML снимок
+// не перезаписывается следующими загрузками того же URL
+func (d *Downloader) SaveSnapshot(ctx context.Context, operationID uuid.UUID, html string) error {
+	if err := storage.PutBytes(ctx, d.storage, snapshotKey(operationID), []byte(html), htmlContentType); err != nil {
+		return fmt.Errorf("ошибка сохранения снимка HTML: %w", err)
+	}
+	return nil
+}
 
-	if err := ioutil.WriteFile(htmlPath, []byte(block.HTML), 0644); err != nil {
-		return fmt.Errorf("ошибка сохранения HTML блока: %w", err)
+// LoadSnapshot возвращает HTML, разобранный операцией. Для операций, созданных до появления
+// снимков, используется последний сохраненный HTML страницы по URL
+func (d *Downloader) LoadSnapshot(ctx context.Context, operationID uuid.UUID, url string) (string, error) {
+	data, err := storage.ReadAll(ctx, d.storage, snapshotKey(operationID))
+	if err == nil {
+		return string(data), nil
 	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return "", fmt.Errorf("ошибка чтения снимка HTML: %w", err)
+	}
+	return d.LoadHTML(ctx, url)
+}
 
-	// Сохраняем метаданные блока
+// blockHTMLFilename возвращает имя файла с HTML блока
+func blockHTMLFilename(block *models.Block) string {
+	return fmt.Sprintf("%s_%s.html", block.BlockType, block.ID.String())
+}
+
+// blockMetadataFilename возвращает имя файла с метаданными блока
+func blockMetadataFilename(block *models.Block) string {
+	return fmt.Sprintf("%s_%s_metadata.json", block.BlockType, block.ID.String())
+}
+
+// blockMetadata возвращает метаданные блока в JSON
+func blockMetadata(block *models.Block) ([]byte, error) {
 	metadata := map[string]interface{}{
 		"id":           block.ID,
 		"operation_id": block.OperationID,
@@ -173,24 +275,38 @@ func (d *Downloader) SaveBlock(block *models.Block) error {
 
 	metadataJSON, err := json.MarshalIndent(metadata, "", "  ")
 	if err != nil {
-		return fmt.Errorf("ошибка сериализации метаданных: %w", err)
+		return nil, fmt.Errorf("ошибка сериализации метаданных: %w", err)
 	}
+	return metadataJSON, nil
+}
+
+// SaveBlock сохраняет HTML и метаданные блока в хранилище
+func (d *Downloader) SaveBlock(ctx context.Context, block *models.Block) error {
+	prefix := blockPrefix(block.OperationID)
 
-	metadataFilename := fmt.Sprintf("%s_%s_metadata.json", block.BlockType, block.ID.String())
-	metadataPath := filepath.Join(blockDir, metadataFilename)
+	// Сохраняем HTML блока
+	htmlFilename := blockHTMLFilename(block)
+	if err := storage.PutBytes(ctx, d.storage, prefix+htmlFilename, []byte(block.HTML), htmlContentType); err != nil {
+		return fmt.Errorf("ошибка сохранения HTML блока: %w", err)
+	}
 
-	if err := ioutil.WriteFile(metadataPath, metadataJSON, 0644); err != nil {
+	// Сохраняем метаданные блока
+	metadataJSON, err := blockMetadata(block)
+	if err != nil {
+		return err
+	}
+	if err := storage.PutBytes(ctx, d.storage, prefix+blockMetadataFilename(block), metadataJSON, "application/json"); err != nil {
 		return fmt.Errorf("ошибка сохранения метаданных: %w", err)
 	}
 
-	log.Printf("Блок сохранен: %s", blockDir)
+	log.Printf("Блок сохранен: %s", prefix+htmlFilename)
 	return nil
 }
 
 // SaveBlocks сохраняет все блоки операции
-func (d *Downloader) SaveBlocks(blocks []models.Block) error {
+func (d *Downloader) SaveBlocks(ctx context.Context, blocks []models.Block) error {
 	for _, block := range blocks {
-		if err := d.SaveBlock(&block); err != nil {
+		if err := d.SaveBlock(ctx, &block); err != nil {
 			log.Printf("Ошибка сохранения блока %s: %v", block.ID, err)
 			continue
 		}
@@ -199,176 +315,232 @@ func (d *Downloader) SaveBlocks(blocks []models.Block) error {
 }
 
 // GetBlockFiles возвращает список файлов блоков для операции
-func (d *Downloader) GetBlockFiles(operationID uuid.UUID) (map[string][]string, error) {
-	blockDir := filepath.Join(d.cfg.Downloader.OutputDir, "blocks", operationID.String())
+func (d *Downloader) GetBlockFiles(ctx context.Context, operationID uuid.UUID) (map[string][]string, error) {
+	prefix := blockPrefix(operationID)
 
-	// Проверяем существование директории
-	if _, err := os.Stat(blockDir); os.IsNotExist(err) {
-		return nil, fmt.Errorf("директория блоков не найдена: %s", blockDir)
+	objects, err := d.storage.List(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файлов блоков: %w", err)
+	}
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("файлы блоков не найдены: %s", prefix)
 	}
 
 	files := make(map[string][]string)
-
-	// Проходим по директории
-	err := filepath.Walk(blockDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if info.IsDir() {
-			return nil
-		}
-
-		rel, err := filepath.Rel(blockDir, path)
-		if err != nil {
-			return err
-		}
+	for _, object := range objects {
+		rel := strings.TrimPrefix(object.Key, prefix)
 
 		// Определяем тип файла
-		ext := filepath.Ext(rel)
+		ext := path.Ext(rel)
 		if ext == ".html" {
 			files["html"] = append(files["html"], rel)
 		} else if ext == ".json" {
 			files["metadata"] = append(files["metadata"], rel)
+		} else if ext == ".png" {
+			files["screenshots"] = append(files["screenshots"], rel)
 		}
-
-		return nil
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("ошибка чтения директории: %w", err)
 	}
 
 	return files, nil
 }
 
-// ZipBlocks создает ZIP-архив с блоками операции
-func (d *Downloader) ZipBlocks(operationID uuid.UUID) (string, error) {
-	blockDir := filepath.Join(d.cfg.Downloader.OutputDir, "blocks", operationID.String())
+// WriteBlocksZip пишет ZIP-архив блоков в w по мере формирования: HTML и метаданные блоков
+// берутся из их записей, скриншоты — из хранилища, сводка блоков формируется в памяти.
+// Архив не сохраняется, поэтому его можно отдавать прямо в ответ на запрос
+func (d *Downloader) WriteBlocksZip(ctx context.Context, w io.Writer, operationID uuid.UUID, blocks []models.Block) error {
+	prefix := blockPrefix(operationID)
+	zipWriter := zip.NewWriter(w)
+	screenshots := make(map[uuid.UUID]bool)
 
-	// Проверяем существование директории
-	if _, err := os.Stat(blockDir); os.IsNotExist(err) {
-		return "", fmt.Errorf("директория блоков не найдена: %s", blockDir)
-	}
-
-	// Создаем архив
-	zipFilename := fmt.Sprintf("blocks_%s.zip", operationID.String())
-	zipPath := filepath.Join(d.cfg.Downloader.OutputDir, "exports", zipFilename)
-
-	// Создаем ZIP-файл
-	zipFile, err := os.Create(zipPath)
-	if err != nil {
-		return "", fmt.Errorf("ошибка создания ZIP-файла: %w", err)
-	}
-	defer zipFile.Close()
-
-	// Создаем ZIP-writer
-	zipWriter := zip.NewWriter(zipFile)
-	defer zipWriter.Close()
-
-	// Добавляем файлы в архив
-	err = filepath.Walk(blockDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
+	for i := range blocks {
+		if err := ctx.Err(); err != nil {
 			return err
 		}
+		block := &blocks[i]
 
-		// Пропускаем директории
-		if info.IsDir() {
-			return nil
+		if err := writeZipEntry(zipWriter, blockHTMLFilename(block), []byte(block.HTML)); err != nil {
+			return fmt.Errorf("ошибка записи HTML блока %s: %w", block.ID, err)
 		}
 
-		// Получаем относительный путь
-		relPath, err := filepath.Rel(blockDir, path)
+		metadataJSON, err := blockMetadata(block)
 		if err != nil {
 			return err
 		}
-
-		// Открываем файл
-		file, err := os.Open(path)
-		if err != nil {
-			return err
+		if err := writeZipEntry(zipWriter, blockMetadataFilename(block), metadataJSON); err != nil {
+			return fmt.Errorf("ошибка записи метаданных блока %s: %w", block.ID, err)
 		}
-		defer file.Close()
 
-		// Создаем файл в архиве
-		zipEntry, err := zipWriter.Create(relPath)
-		if err != nil {
-			return err
+		// Скриншоты есть только у блоков, снятых в браузере. Удаленный из хранилища скриншот
+		// пропускается, как и снимок страницы
+		if visual := blockVisual(*block); visual != nil && visual.Screenshot != "" {
+			err := d.addToZip(ctx, zipWriter, prefix+visual.Screenshot, visual.Screenshot)
+			if err == nil {
+				screenshots[block.ID] = true
+			} else if !errors.Is(err, storage.ErrNotFound) {
+				return fmt.Errorf("ошибка добавления скриншота блока %s: %w", block.ID, err)
+			}
 		}
+	}
 
-		// Копируем содержимое
-		_, err = io.Copy(zipEntry, file)
-		if err != nil {
-			return err
-		}
+	hasPageScreenshot := false
+	if err := d.addToZip(ctx, zipWriter, prefix+pageScreenshotFilename, pageScreenshotFilename); err == nil {
+		hasPageScreenshot = true
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("ошибка добавления снимка страницы: %w", err)
+	}
 
-		log.Printf("Добавлен в архив: %s", relPath)
-		return nil
-	})
+	summary := blocksSummaryHTML(operationID, blocks, screenshots, hasPageScreenshot)
+	if err := writeZipEntry(zipWriter, blocksSummaryFilename, []byte(summary)); err != nil {
+		return fmt.Errorf("ошибка записи сводки блоков: %w", err)
+	}
 
+	if err := zipWriter.Close(); err != nil {
+		return fmt.Errorf("ошибка создания архива: %w", err)
+	}
+	return nil
+}
+
+// ZipBlocks сохраняет ZIP-архив блоков в хранилище, чтобы выдать ссылку на него, и возвращает
+// ключ архива. У каждого архива свой ключ, поэтому одновременные выгрузки с разными фильтрами
+// не перезаписывают друг друга
+func (d *Downloader) ZipBlocks(ctx context.Context, operationID uuid.UUID, blocks []models.Block) (string, error) {
+	// Архив собирается во временном файле, чтобы передать хранилищу его размер
+	tmp, err := os.CreateTemp("", "blocks_*.zip")
 	if err != nil {
+		return "", fmt.Errorf("ошибка создания ZIP-файла: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := d.WriteBlocksZip(ctx, tmp, operationID, blocks); err != nil {
+		return "", err
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", fmt.Errorf("ошибка создания архива: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
 		return "", fmt.Errorf("ошибка создания архива: %w", err)
 	}
 
-	log.Printf("ZIP-архив создан: %s", zipPath)
-	return zipPath, nil
+	key := ExportKey(operationID, "blocks_"+uuid.NewString()+".zip")
+	if err := d.storage.Put(ctx, key, tmp, size, "application/zip"); err != nil {
+		return "", fmt.Errorf("ошибка сохранения архива: %w", err)
+	}
+
+	log.Printf("ZIP-архив создан: %s", key)
+	return key, nil
 }
 
-// DownloadBlock загружает конкретный блок
-func (d *Downloader) DownloadBlock(operationID, blockID uuid.UUID, format string) ([]byte, string, error) {
-	blockDir := filepath.Join(d.cfg.Downloader.OutputDir, "blocks", operationID.String())
+// writeZipEntry добавляет в архив файл с содержимым data
+func writeZipEntry(zipWriter *zip.Writer, name string, data []byte) error {
+	zipEntry, err := zipWriter.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = zipEntry.Write(data)
+	return err
+}
+
+// addToZip копирует файл хранилища в архив под именем name
+func (d *Downloader) addToZip(ctx context.Context, zipWriter *zip.Writer, key, name string) error {
+	body, err := d.storage.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	zipEntry, err := zipWriter.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(zipEntry, body)
+	return err
+}
 
-	var filename string
-	var data []byte
+// blockDocumentTemplate задает HTML-страницу блока для скачивания: метаданные и HTML блока
+const blockDocumentTemplate = `<!DOCTYPE html>
+<html lang="ru">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Блок %s - %s</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; padding: 20px; }
+        .metadata { background: #f5f5f5; padding: 15px; margin-bottom: 20px; border-radius: 5px; }
+        .content { border: 1px solid #ddd; padding: 15px; margin-top: 20px; }
+        pre { background: #f8f8f8; padding: 10px; overflow-x: auto; }
+    </style>
+</head>
+<body>
+    <div class="metadata">
+        <h2>Информация о блоке</h2>
+        <ul>
+            <li><strong>ID блока:</strong> %s</li>
+            <li><strong>ID операции:</strong> %s</li>
+            <li><strong>Тип блока:</strong> %s</li>
+            <li><strong>Платформа:</strong> %s</li>
+            <li><strong>Создан:</strong> %s</li>
+        </ul>
+        <h3>Контент (JSON):</h3>
+        <pre>%s</pre>
+    </div>
+    <div class="content">
+        <h2>HTML содержимое блока</h2>
+        %s
+    </div>
+</body>
+</html>`
 
+// DownloadBlock формирует файл блока для скачивания по его записи в БД: HTML-страницу
+// с метаданными или JSON. Копии, сохраненные SaveBlock, для этого не нужны и служат
+// только для архивов и сводок операции. Возвращает содержимое, имя файла и его тип
+func (d *Downloader) DownloadBlock(block *models.Block, format string) ([]byte, string, string, error) {
 	switch format {
 	case "html":
-		// Ищем HTML-файл блока
-		files, err := d.GetBlockFiles(operationID)
-		if err != nil {
-			return nil, "", err
-		}
-
-		for _, htmlFile := range files["html"] {
-			if strings.Contains(htmlFile, blockID.String()) {
-				filepath := filepath.Join(blockDir, htmlFile)
-				data, err = ioutil.ReadFile(filepath)
-				if err != nil {
-					return nil, "", err
-				}
-				filename = htmlFile
-				break
-			}
-		}
+		data := fmt.Sprintf(blockDocumentTemplate,
+			block.BlockType, block.ID.String(),
+			block.ID.String(),
+			block.OperationID.String(),
+			block.BlockType,
+			block.Platform,
+			block.CreatedAt.Format("2006-01-02 15:04:05"),
+			jsonPretty(block.Content),
+			block.HTML)
+
+		filename := fmt.Sprintf("block_%s_%s.html", block.BlockType, block.ID.String())
+		return []byte(data), filename, htmlContentType, nil
 
 	case "json":
-		// Ищем JSON-файл блока
-		files, err := d.GetBlockFiles(operationID)
+		data, err := json.MarshalIndent(map[string]interface{}{
+			"id":           block.ID,
+			"operation_id": block.OperationID,
+			"block_type":   block.BlockType,
+			"platform":     block.Platform,
+			"created_at":   block.CreatedAt,
+			"content":      block.Content,
+			"html":         block.HTML,
+		}, "", "  ")
 		if err != nil {
-			return nil, "", err
+			return nil, "", "", fmt.Errorf("ошибка сериализации блока: %w", err)
 		}
 
-		for _, jsonFile := range files["metadata"] {
-			if strings.Contains(jsonFile, blockID.String()) {
-				filepath := filepath.Join(blockDir, jsonFile)
-				data, err = ioutil.ReadFile(filepath)
-				if err != nil {
-					return nil, "", err
-				}
-				filename = jsonFile
-				break
-			}
-		}
+		filename := fmt.Sprintf("block_%s_%s.json", block.BlockType, block.ID.String())
+		return data, filename, "application/json", nil
 
 	default:
-		return nil, "", fmt.Errorf("неподдерживаемый формат: %s", format)
+		return nil, "", "", fmt.Errorf("неподдерживаемый формат: %s", format)
 	}
+}
 
-	if len(data) == 0 {
-		return nil, "", fmt.Errorf("блок не найден")
+// jsonPretty форматирует значение как JSON с отступами
+func jsonPretty(v interface{}) string {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%v", v)
 	}
-
-	return data, filename, nil
+	return string(b)
 }
 
 // sanitizeFilename создает безопасное имя файла из URL
@@ -396,22 +568,36 @@ func (d *Downloader) sanitizeFilename(url string) string {
 	return filename
 }
 
-// CreateBlocksSummary создает сводный HTML-файл со всеми шапками и подвалами по платформам
-func (d *Downloader) CreateBlocksSummary(operationID uuid.UUID, blocks []models.Block) (string, error) {
-	// Создаем директорию для этой операции
-	blockDir := filepath.Join(d.cfg.Downloader.OutputDir, "blocks", operationID.String())
-	if err := os.MkdirAll(blockDir, 0755); err != nil {
-		return "", fmt.Errorf("ошибка создания директории: %w", err)
-	}
-
-	// Создаем файл сводки
-	summaryPath := filepath.Join(blockDir, "blocks_summary.html")
-	file, err := os.Create(summaryPath)
-	if err != nil {
-		return "", fmt.Errorf("ошибка создания файла сводки: %w", err)
-	}
-	defer file.Close()
-
+// summaryStyles содержит стили сводки блоков, общие для сводки операции и отчета о сравнении операций
+const summaryStyles = `        body { font-family: Arial, sans-serif; line-height: 1.6; padding: 20px; }
+        h1, h2, h3 { color: #333; }
+        .platform-section { margin-bottom: 30px; border: 1px solid #ddd; padding: 15px; border-radius: 5px; }
+        .block-container { margin-bottom: 20px; padding: 10px; background: #f9f9f9; border-radius: 5px; }
+        .block-content { border: 1px solid #ccc; padding: 10px; margin-top: 10px; background: white; overflow: auto; max-height: 300px; }
+        .block-info { margin-bottom: 10px; }
+        .platform-wordpress { border-left: 5px solid #21759b; }
+        .platform-tilda { border-left: 5px solid #ff8c69; }
+        .platform-bitrix { border-left: 5px solid #c2185b; }
+        .platform-html5 { border-left: 5px solid #4caf50; }
+        .platform-unknown { border-left: 5px solid #9e9e9e; }
+        .toc { background: #f5f5f5; padding: 15px; margin-bottom: 20px; border-radius: 5px; }
+        .toc ul { list-style-type: none; padding-left: 20px; }
+        .toc li { margin-bottom: 5px; }
+        .toc a { text-decoration: none; color: #0066cc; }
+        .toc a:hover { text-decoration: underline; }
+        .block-screenshot { max-width: 100%; border: 1px solid #ccc; margin-top: 10px; }
+        .page-screenshot { max-width: 100%; max-height: 600px; overflow: auto; border: 1px solid #ddd; margin-bottom: 20px; }
+        .block-styles { border-collapse: collapse; margin-top: 10px; font-size: 13px; }
+        .block-styles td { border: 1px solid #ddd; padding: 3px 8px; }
+        .color-swatch { display: inline-block; width: 12px; height: 12px; border: 1px solid #999; margin-right: 5px; vertical-align: middle; }
+`
+
+// blocksSummaryFilename задает имя сводки блоков в архиве
+const blocksSummaryFilename = "blocks_summary.html"
+
+// blocksSummaryHTML формирует сводный HTML-файл со всеми шапками и подвалами по платформам.
+// Снимок страницы и скриншоты блоков из screenshots добавляются, если они лежат в архиве рядом со сводкой
+func blocksSummaryHTML(operationID uuid.UUID, blocks []models.Block, screenshots map[uuid.UUID]bool, hasPageScreenshot bool) string {
 	// Группируем блоки по типу и платформе
 	headersByPlatform := make(map[string][]models.Block)
 	footersByPlatform := make(map[string][]models.Block)
@@ -434,23 +620,7 @@ func (d *Downloader) CreateBlocksSummary(operationID uuid.UUID, blocks []models.
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
     <title>Сводка блоков для операции: ` + operationID.String() + `</title>
     <style>
-        body { font-family: Arial, sans-serif; line-height: 1.6; padding: 20px; }
-        h1, h2, h3 { color: #333; }
-        .platform-section { margin-bottom: 30px; border: 1px solid #ddd; padding: 15px; border-radius: 5px; }
-        .block-container { margin-bottom: 20px; padding: 10px; background: #f9f9f9; border-radius: 5px; }
-        .block-content { border: 1px solid #ccc; padding: 10px; margin-top: 10px; background: white; overflow: auto; max-height: 300px; }
-        .block-info { margin-bottom: 10px; }
-        .platform-wordpress { border-left: 5px solid #21759b; }
-        .platform-tilda { border-left: 5px solid #ff8c69; }
-        .platform-bitrix { border-left: 5px solid #c2185b; }
-        .platform-html5 { border-left: 5px solid #4caf50; }
-        .platform-unknown { border-left: 5px solid #9e9e9e; }
-        .toc { background: #f5f5f5; padding: 15px; margin-bottom: 20px; border-radius: 5px; }
-        .toc ul { list-style-type: none; padding-left: 20px; }
-        .toc li { margin-bottom: 5px; }
-        .toc a { text-decoration: none; color: #0066cc; }
-        .toc a:hover { text-decoration: underline; }
-    </style>
+` + summaryStyles + `    </style>
 </head>
 <body>
     <h1>Сводка блоков для операции: ` + operationID.String() + `</h1>
@@ -478,7 +648,16 @@ func (d *Downloader) CreateBlocksSummary(operationID uuid.UUID, blocks []models.
 	html += `</ul>
             </li>
         </ul>
-    </div>
+    </div>`
+
+	// Добавляем снимок всей страницы, если он был сделан при загрузке
+	if hasPageScreenshot {
+		html += `
+    <h2>Снимок страницы</h2>
+    <div class="page-screenshot"><img src="` + pageScreenshotFilename + `" alt="Снимок страницы" style="max-width: 100%;"></div>`
+	}
+
+	html += `
 
     <h2 id="headers">Шапки сайтов по платформам</h2>`
 
@@ -488,13 +667,7 @@ func (d *Downloader) CreateBlocksSummary(operationID uuid.UUID, blocks []models.
     <h3>Платформа: ` + platform + ` (` + strconv.Itoa(len(headers)) + ` шапок)</h3>`
 
 		for _, header := range headers {
-			html += `<div class="block-container">
-        <div class="block-info">
-            <strong>ID блока:</strong> ` + header.ID.String() + `<br>
-            <strong>Создан:</strong> ` + header.CreatedAt.Format("2006-01-02 15:04:05") + `
-        </div>
-        <div class="block-content">` + header.HTML + `</div>
-    </div>`
+			html += summaryBlockHTML(header, screenshots[header.ID])
 		}
 		html += `</div>`
 	}
@@ -506,13 +679,7 @@ func (d *Downloader) CreateBlocksSummary(operationID uuid.UUID, blocks []models.
     <h3>Платформа: ` + platform + ` (` + strconv.Itoa(len(footers)) + ` подвалов)</h3>`
 
 		for _, footer := range footers {
-			html += `<div class="block-container">
-        <div class="block-info">
-            <strong>ID блока:</strong> ` + footer.ID.String() + `<br>
-            <strong>Создан:</strong> ` + footer.CreatedAt.Format("2006-01-02 15:04:05") + `
-        </div>
-        <div class="block-content">` + footer.HTML + `</div>
-    </div>`
+			html += summaryBlockHTML(footer, screenshots[footer.ID])
 		}
 		html += `</div>`
 	}
@@ -520,18 +687,94 @@ func (d *Downloader) CreateBlocksSummary(operationID uuid.UUID, blocks []models.
 	html += `</body>
 </html>`
 
-	// Записываем HTML в файл
-	_, err = file.WriteString(html)
+	return html
+}
+
+// summaryBlockHTML формирует карточку блока для сводки: скриншот, если он есть в архиве,
+// вычисленные стили и HTML
+func summaryBlockHTML(block models.Block, hasScreenshot bool) string {
+	out := `<div class="block-container">
+        <div class="block-info">
+            <strong>ID блока:</strong> ` + block.ID.String() + `<br>
+            <strong>Создан:</strong> ` + block.CreatedAt.Format("2006-01-02 15:04:05")
+
+	visual := blockVisual(block)
+	if visual != nil && visual.BoundingBox != nil {
+		out += `<br>
+            <strong>Размер:</strong> ` + strconv.Itoa(int(visual.BoundingBox.Width)) + `×` + strconv.Itoa(int(visual.BoundingBox.Height)) +
+			` px, позиция ` + strconv.Itoa(int(visual.BoundingBox.X)) + `, ` + strconv.Itoa(int(visual.BoundingBox.Y))
+	}
+	out += `
+        </div>`
+
+	if hasScreenshot {
+		out += `
+        <img class="block-screenshot" src="` + html.EscapeString(visual.Screenshot) + `" alt="Скриншот блока">`
+	}
+
+	if visual != nil && len(visual.Styles) > 0 {
+		names := make([]string, 0, len(visual.Styles))
+		for name := range visual.Styles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		out += `
+        <table class="block-styles">`
+		for _, name := range names {
+			// Вычисленные стили приходят со страницы и экранируются, как любой текст сайта
+			value := html.EscapeString(visual.Styles[name])
+			swatch := ""
+			if strings.HasSuffix(name, "color") {
+				swatch = `<span class="color-swatch" style="background: ` + value + `;"></span>`
+			}
+			out += `<tr><td>` + html.EscapeString(name) + `</td><td>` + swatch + value + `</td></tr>`
+		}
+		out += `</table>`
+	}
+
+	out += `
+        <div class="block-content">` + block.HTML + `</div>
+    </div>`
+
+	return out
+}
+
+// blockVisual извлекает из контента блока данные о его внешнем виде
+func blockVisual(block models.Block) *models.BlockVisual {
+	content, ok := block.Content.(map[string]interface{})
+	if !ok || content["visual"] == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(content["visual"])
 	if err != nil {
-		return "", fmt.Errorf("ошибка записи в файл сводки: %w", err)
+		return nil
 	}
 
-	return summaryPath, nil
+	var visual models.BlockVisual
+	if err := json.Unmarshal(data, &visual); err != nil {
+		return nil
+	}
+	return &visual
 }
 
 // Module регистрирует зависимости для загрузчика
 var Module = fx.Module("downloader",
 	fx.Provide(
+		NewBrowserPool,
 		NewDownloader,
 	),
+	fx.Invoke(func(lc fx.Lifecycle, pool *BrowserPool) {
+		lc.Append(fx.Hook{
+			OnStart: func(ctx context.Context) error {
+				pool.Start()
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				pool.Close()
+				return nil
+			},
+		})
+	}),
 )
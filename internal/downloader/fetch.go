@@ -0,0 +1,220 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"website-scraper/internal/models"
+	"website-scraper/internal/proxy"
+)
+
+// maxHTTPPageSize ограничивает размер страницы, загружаемой по HTTP
+const maxHTTPPageSize = 10 << 20
+
+// minRenderedTextLength задает минимальную длину видимого текста страницы,
+// при которой она считается отрисованной на сервере
+const minRenderedTextLength = 200
+
+var (
+	// spaRootSelectors содержит контейнеры, в которые SPA-фреймворки отрисовывают приложение
+	spaRootSelectors = []string{
+		"#root", "#app", "#__next", "#__nuxt", "#___gatsby", "#svelte",
+		"[ng-app]", "[data-reactroot]", "app-root",
+	}
+
+	// spaScriptRegexp находит признаки клиентской отрисовки в скриптах страницы
+	spaScriptRegexp = regexp.MustCompile(`(?i)window\.__NUXT__|__NEXT_DATA__|ReactDOM\.render|createRoot\(|new Vue\(|createApp\(|platformBrowserDynamic`)
+
+	// noscriptShellRegexp находит заглушки, требующие включить JavaScript
+	noscriptShellRegexp = regexp.MustCompile(`(?i)(enable|включите)\s+javascript|javascript\s+(is\s+)?(required|disabled)|требуется\s+javascript`)
+)
+
+// ValidFetchStrategy проверяет, поддерживается ли способ загрузки
+func ValidFetchStrategy(strategy models.FetchStrategy) bool {
+	switch strategy {
+	case models.FetchHTTP, models.FetchBrowser, models.FetchAuto:
+		return true
+	}
+	return false
+}
+
+// Fetch загружает страницу выбранным способом. В режиме auto страница сначала загружается
+// по HTTP и отрисовывается в браузере, только если без JavaScript она неполная.
+// Способ, которым страница фактически получена, возвращается в PageResult.Strategy
+func (d *Downloader) Fetch(ctx context.Context, url string, opts models.ParseOptions) (*PageResult, error) {
+	strategy := opts.FetchStrategy
+	if strategy == "" {
+		strategy = models.FetchStrategy(d.cfg.Scraper.FetchStrategy)
+	}
+
+	// Сценарий входа выполняется только в браузере
+	if opts.Auth != nil && opts.Auth.Login != nil && opts.ReplayHAR == nil {
+		if strategy == models.FetchHTTP {
+			return nil, fmt.Errorf("сценарий входа недоступен при загрузке по HTTP")
+		}
+		strategy = models.FetchBrowser
+	}
+
+	switch strategy {
+	case models.FetchHTTP:
+		return d.DownloadPageHTTP(ctx, url, opts)
+
+	case models.FetchAuto:
+		result, err := d.DownloadPageHTTP(ctx, url, opts)
+		if err != nil {
+			log.Printf("Ошибка загрузки %s по HTTP, используем браузер: %v", url, err)
+			return d.DownloadPage(ctx, url, opts)
+		}
+
+		// Ответ с ошибкой может быть заглушкой защиты от ботов, которую браузер пройдет
+		if result.Response != nil && result.Response.IsError {
+			log.Printf("Страница %s вернула статус %d по HTTP, используем браузер", url, result.Response.StatusCode)
+			return d.DownloadPage(ctx, url, opts)
+		}
+
+		if reason := NeedsRendering(result.HTML); reason != "" {
+			log.Printf("Страница %s требует отрисовки в браузере: %s", url, reason)
+			return d.DownloadPage(ctx, url, opts)
+		}
+
+		return result, nil
+
+	default:
+		return d.DownloadPage(ctx, url, opts)
+	}
+}
+
+// DownloadPageHTTP загружает страницу обычным HTTP-запросом без выполнения JavaScript.
+// Ответ вне 2xx ошибкой не считается и отмечается в Response.IsError
+func (d *Downloader) DownloadPageHTTP(ctx context.Context, url string, opts models.ParseOptions) (*PageResult, error) {
+	proxied, err := d.proxyFor(url, opts)
+	if err != nil {
+		return nil, err
+	}
+	client := d.proxies.Client(d.client, proxied)
+
+	replay, err := d.replayFor(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if replay != nil {
+		client = &http.Client{Transport: replay, Timeout: d.client.Timeout}
+	}
+
+	timer := &httpTimer{}
+	req, err := http.NewRequestWithContext(timer.trace(ctx), http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", d.userAgent(opts.Device))
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+
+	// Учетные данные передаются только сайту страницы, в том числе после перенаправлений
+	scope := d.authScope(url, opts)
+	if scope != nil {
+		scope.ApplyHTTP(req)
+		scoped := *client
+		scoped.CheckRedirect = scope.CheckRedirect
+		client = &scoped
+	}
+
+	resp, err := client.Do(req)
+	d.proxies.Report(proxied, proxy.ResponseError(resp, err))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err == nil && mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+			return nil, fmt.Errorf("unexpected content type: %s", mediaType)
+		}
+	}
+
+	// Обрезанный HTML разобрался бы как полная страница, поэтому слишком большая страница считается ошибкой
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPPageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxHTTPPageSize {
+		return nil, fmt.Errorf("page is larger than %d bytes", maxHTTPPageSize)
+	}
+
+	result := &PageResult{
+		HTML:     string(data),
+		Strategy: models.FetchHTTP,
+		Response: responseMetaFromHTTP(resp, timer.timing()),
+	}
+
+	if opts.RecordHAR {
+		result.HAR = harFromHTTP(url, resp, data, timer.start, result.Response.Timing, scope.SensitiveHeaders())
+	}
+
+	// Сохраняем HTML-файл
+	if err := d.SaveHTML(ctx, url, result.HTML); err != nil {
+		log.Printf("Ошибка сохранения HTML: %v", err)
+	}
+
+	log.Printf("Successfully downloaded page over HTTP: %s", url)
+	return result, nil
+}
+
+// NeedsRendering проверяет, зависит ли содержимое страницы от JavaScript.
+// Возвращает причину, по которой страницу нужно отрисовать в браузере, или пустую строку
+func NeedsRendering(html string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return "не удалось разобрать HTML"
+	}
+
+	body := doc.Find("body")
+	if body.Length() == 0 {
+		return "нет тела страницы"
+	}
+
+	// Видимый текст без скриптов, стилей и заглушек noscript
+	visible := body.Clone()
+	visible.Find("script, style, noscript, template").Remove()
+	textLength := len([]rune(strings.Join(strings.Fields(visible.Text()), " ")))
+
+	if textLength == 0 {
+		return "пустое тело страницы"
+	}
+
+	for _, selector := range spaRootSelectors {
+		root := body.Find(selector).First()
+		if root.Length() == 0 {
+			continue
+		}
+		if len(strings.TrimSpace(root.Text())) == 0 && root.Children().Length() == 0 {
+			return "пустой корневой контейнер " + selector
+		}
+	}
+
+	noscriptShell := false
+	body.Find("noscript").Each(func(i int, noscript *goquery.Selection) {
+		if noscriptShellRegexp.MatchString(noscript.Text()) {
+			noscriptShell = true
+		}
+	})
+
+	if textLength < minRenderedTextLength {
+		if noscriptShell {
+			return "заглушка noscript без содержимого"
+		}
+		if spaScriptRegexp.MatchString(html) {
+			return "клиентская отрисовка без содержимого"
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,130 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"website-scraper/internal/storage"
+)
+
+// htmlContentType задает тип сохраняемых HTML-файлов
+const htmlContentType = "text/html; charset=utf-8"
+
+// FileUsage представляет число файлов и занятое ими место в хранилище
+type FileUsage struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+// blockPrefix возвращает префикс ключей файлов операции: блоков, скриншотов, автономных копий и HAR
+func blockPrefix(operationID uuid.UUID) string {
+	return "blocks/" + operationID.String() + "/"
+}
+
+// snapshotKey возвращает ключ HTML, разобранного операцией
+func snapshotKey(operationID uuid.UUID) string {
+	return "html/" + operationID.String() + ".html"
+}
+
+// pageHTMLKey возвращает ключ последнего HTML страницы по URL
+func (d *Downloader) pageHTMLKey(url string) string {
+	return "html/" + d.sanitizeFilename(url) + ".html"
+}
+
+// legacyArchiveKey возвращает ключ ZIP-архива блоков, который сохранялся при каждом скачивании
+// до потоковой выгрузки. Теперь архивы по ссылке сохраняются среди выгрузок операции
+func legacyArchiveKey(operationID uuid.UUID) string {
+	return fmt.Sprintf("exports/blocks_%s.zip", operationID.String())
+}
+
+// ExportKey возвращает ключ выгрузки операции в файл filename: отчета или архива блоков
+func ExportKey(operationID uuid.UUID, filename string) string {
+	return "exports/" + operationID.String() + "/" + filename
+}
+
+// operationKeys возвращает префиксы и ключи файлов операции: файлы блоков со скриншотами,
+// автономными копиями и HAR, выгрузки с архивами блоков и снимок разобранного HTML. Если передан
+// адрес страницы, добавляется и последний HTML страницы по URL, общий для всех операций этого адреса
+func (d *Downloader) operationKeys(operationID uuid.UUID, pageURL string) (prefixes, keys []string) {
+	prefixes = []string{
+		blockPrefix(operationID),
+		"exports/" + operationID.String() + "/",
+	}
+	keys = []string{
+		snapshotKey(operationID),
+		legacyArchiveKey(operationID),
+	}
+	if pageURL != "" {
+		keys = append(keys, d.pageHTMLKey(pageURL))
+	}
+	return prefixes, keys
+}
+
+// OperationFileUsage считает файлы операции в хранилище. pageURL передается, если вместе с операцией
+// удаляется и общий HTML страницы, то есть других операций этого адреса не осталось
+func (d *Downloader) OperationFileUsage(ctx context.Context, operationID uuid.UUID, pageURL string) (FileUsage, error) {
+	var usage FileUsage
+
+	prefixes, keys := d.operationKeys(operationID, pageURL)
+	for _, prefix := range prefixes {
+		prefixUsage, err := d.fileUsage(ctx, prefix)
+		if err != nil {
+			return usage, err
+		}
+		usage.Files += prefixUsage.Files
+		usage.Bytes += prefixUsage.Bytes
+	}
+	for _, key := range keys {
+		object, err := d.storage.Stat(ctx, key)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				continue
+			}
+			return usage, err
+		}
+		usage.Files++
+		usage.Bytes += object.Size
+	}
+
+	return usage, nil
+}
+
+// DeleteOperationFiles удаляет файлы операции из хранилища. pageURL передается так же, как в OperationFileUsage
+func (d *Downloader) DeleteOperationFiles(ctx context.Context, operationID uuid.UUID, pageURL string) error {
+	prefixes, keys := d.operationKeys(operationID, pageURL)
+	for _, prefix := range prefixes {
+		if err := d.storage.DeletePrefix(ctx, prefix); err != nil {
+			return fmt.Errorf("ошибка удаления %s: %w", prefix, err)
+		}
+	}
+	for _, key := range keys {
+		if err := d.storage.Delete(ctx, key); err != nil {
+			return fmt.Errorf("ошибка удаления %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// DiskUsage считает место, занятое всеми загруженными и сохраненными файлами
+func (d *Downloader) DiskUsage(ctx context.Context) (FileUsage, error) {
+	return d.fileUsage(ctx, "")
+}
+
+// fileUsage считает файлы хранилища с префиксом prefix
+func (d *Downloader) fileUsage(ctx context.Context, prefix string) (FileUsage, error) {
+	var usage FileUsage
+
+	objects, err := d.storage.List(ctx, prefix)
+	if err != nil {
+		return usage, fmt.Errorf("ошибка подсчета файлов %s: %w", prefix, err)
+	}
+	for _, object := range objects {
+		usage.Files++
+		usage.Bytes += object.Size
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,733 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/google/uuid"
+
+	"website-scraper/internal/models"
+	"website-scraper/internal/storage"
+)
+
+const (
+	// harFilename задает имя HAR-файла среди файлов блоков операции
+	harFilename = "page.har"
+
+	// maxHARBodySize ограничивает размер тела ответа, сохраняемого в HAR
+	maxHARBodySize = 5 << 20
+
+	// redactedValue заменяет значения заголовков с учетными данными
+	redactedValue = "[redacted]"
+)
+
+// sensitiveHeaders содержит заголовки, значения которых не попадают в HAR
+var sensitiveHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+}
+
+// HAR представляет запись загрузки страницы в формате HTTP Archive 1.2
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog представляет корневой объект HAR
+type HARLog struct {
+	Version string      `json:"version"`
+	Creator HARCreator  `json:"creator"`
+	Pages   []HARPage   `json:"pages"`
+	Entries []*HAREntry `json:"entries"`
+}
+
+// HARCreator описывает приложение, создавшее HAR
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HARPage описывает загруженную страницу
+type HARPage struct {
+	StartedDateTime time.Time      `json:"startedDateTime"`
+	ID              string         `json:"id"`
+	Title           string         `json:"title"`
+	PageTimings     HARPageTimings `json:"pageTimings"`
+}
+
+// HARPageTimings содержит время загрузки страницы в миллисекундах
+type HARPageTimings struct {
+	OnLoad float64 `json:"onLoad"`
+}
+
+// HAREntry представляет один запрос страницы и ответ на него
+type HAREntry struct {
+	PageRef         string      `json:"pageref,omitempty"`
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	// Error содержит причину, по которой запрос не завершился
+	Error string `json:"_error,omitempty"`
+}
+
+// HARRequest описывает запрос
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse описывает ответ
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARNameValue представляет заголовок, cookie или параметр запроса
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARContent содержит тело ответа. Двоичные данные хранятся в base64
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARTimings содержит длительности этапов запроса в миллисекундах, -1 — этап не выполнялся
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// newHAR создает пустой HAR для страницы
+func newHAR(pageURL string, started time.Time) *HAR {
+	return &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "website-scraper", Version: "1.0"},
+		Pages: []HARPage{{
+			StartedDateTime: started,
+			ID:              "page_1",
+			Title:           pageURL,
+		}},
+		Entries: []*HAREntry{},
+	}}
+}
+
+// PageURL возвращает адрес страницы, загрузка которой записана в HAR
+func (h *HAR) PageURL() string {
+	if len(h.Log.Pages) > 0 && h.Log.Pages[0].Title != "" {
+		return h.Log.Pages[0].Title
+	}
+	if len(h.Log.Entries) > 0 {
+		return h.Log.Entries[0].Request.URL
+	}
+	return ""
+}
+
+// HARKey возвращает ключ HAR-файла операции в хранилище
+func HARKey(operationID uuid.UUID) string {
+	return blockPrefix(operationID) + harFilename
+}
+
+// SaveHAR сохраняет HAR загрузки страницы рядом с файлами блоков операции
+func (d *Downloader) SaveHAR(ctx context.Context, operationID uuid.UUID, har *HAR) error {
+	data, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации HAR: %w", err)
+	}
+
+	key := HARKey(operationID)
+	if err := storage.PutBytes(ctx, d.storage, key, data, "application/json"); err != nil {
+		return fmt.Errorf("ошибка сохранения HAR: %w", err)
+	}
+
+	log.Printf("HAR сохранен в: %s (запросов: %d)", key, len(har.Log.Entries))
+	return nil
+}
+
+// LoadHAR загружает сохраненный HAR операции
+func (d *Downloader) LoadHAR(ctx context.Context, operationID uuid.UUID) (*HAR, error) {
+	data, err := storage.ReadAll(ctx, d.storage, HARKey(operationID))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("HAR операции %s не найден", operationID)
+		}
+		return nil, fmt.Errorf("ошибка чтения HAR: %w", err)
+	}
+
+	var har HAR
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("ошибка разбора HAR: %w", err)
+	}
+
+	return &har, nil
+}
+
+// harRecorder собирает HAR загрузки страницы по сетевым событиям вкладки
+type harRecorder struct {
+	mu      sync.Mutex
+	har     *HAR
+	redact  map[string]bool
+	pending map[network.RequestID]*harPending
+	order   []*harPending
+	// stopped выставляется в Finish: после него события вкладки записи не меняют
+	stopped bool
+}
+
+// harPending содержит запись о запросе, который еще может получить ответ и тело
+type harPending struct {
+	id       network.RequestID
+	entry    *HAREntry
+	started  time.Time
+	timing   *network.ResourceTiming
+	finished bool
+}
+
+// newHARRecorder создает запись HAR для страницы. Значения заголовков из redact скрываются
+// так же, как учетные данные в стандартных заголовках
+func newHARRecorder(pageURL string, redact map[string]bool) *harRecorder {
+	return &harRecorder{
+		har:     newHAR(pageURL, time.Now()),
+		redact:  redact,
+		pending: make(map[network.RequestID]*harPending),
+	}
+}
+
+// Listen подписывается на сетевые события вкладки. Вызывается до перехода на страницу
+func (r *harRecorder) Listen(ctx context.Context) {
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if r.stopped {
+			return
+		}
+
+		switch ev := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			if strings.HasPrefix(ev.Request.URL, "data:") {
+				return
+			}
+
+			// Перенаправление приходит тем же запросом: предыдущий шаг завершается ответом 3xx
+			if prev, ok := r.pending[ev.RequestID]; ok && ev.RedirectResponse != nil {
+				prev.entry.Response = harResponseFromCDP(ev.RedirectResponse, r.redact)
+				prev.entry.Response.RedirectURL = ev.Request.URL
+				prev.entry.ServerIPAddress = ev.RedirectResponse.RemoteIPAddress
+				prev.timing = ev.RedirectResponse.Timing
+				prev.finish(monotonic(ev.Timestamp))
+				delete(r.pending, ev.RequestID)
+			}
+
+			p := &harPending{
+				id:      ev.RequestID,
+				started: monotonic(ev.Timestamp),
+				entry: &HAREntry{
+					PageRef:         "page_1",
+					StartedDateTime: time.Now().UTC(),
+					Request:         harRequestFromCDP(ev.Request, r.redact),
+					Response:        HARResponse{Cookies: []HARNameValue{}, Headers: []HARNameValue{}},
+				},
+			}
+			if ev.WallTime != nil {
+				p.entry.StartedDateTime = ev.WallTime.Time().UTC()
+			}
+			r.pending[ev.RequestID] = p
+			r.order = append(r.order, p)
+
+		case *network.EventResponseReceived:
+			if p, ok := r.pending[ev.RequestID]; ok {
+				p.entry.Response = harResponseFromCDP(ev.Response, r.redact)
+				p.entry.Request.HTTPVersion = p.entry.Response.HTTPVersion
+				p.entry.ServerIPAddress = ev.Response.RemoteIPAddress
+				p.timing = ev.Response.Timing
+			}
+
+		case *network.EventLoadingFinished:
+			if p, ok := r.pending[ev.RequestID]; ok {
+				p.entry.Response.BodySize = int(ev.EncodedDataLength)
+				p.finish(monotonic(ev.Timestamp))
+			}
+
+		case *network.EventLoadingFailed:
+			if p, ok := r.pending[ev.RequestID]; ok {
+				p.entry.Error = ev.ErrorText
+				p.finish(monotonic(ev.Timestamp))
+				delete(r.pending, ev.RequestID)
+			}
+		}
+	})
+}
+
+// finish фиксирует общее время запроса и длительности этапов
+func (p *harPending) finish(at time.Time) {
+	p.finished = true
+	if !at.IsZero() && !p.started.IsZero() {
+		p.entry.Time = milliseconds(at.Sub(p.started))
+	}
+	p.entry.Timings = harTimingsFromCDP(p.timing, p.entry.Time)
+}
+
+// Finish возвращает действие, которое останавливает запись и дописывает в HAR тела завершенных ответов.
+// Тела доступны, пока вкладка открыта, поэтому действие выполняется до ее закрытия
+func (r *harRecorder) Finish(har **HAR) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		// Запросы, начатые после остановки, в запись не попадают, а начатые раньше больше не меняются,
+		// поэтому записи можно читать и дополнять без блокировки
+		r.mu.Lock()
+		r.stopped = true
+		order := append([]*harPending(nil), r.order...)
+		r.mu.Unlock()
+
+		for _, p := range order {
+			if !p.finished || p.entry.Error != "" || p.entry.Response.RedirectURL != "" {
+				continue
+			}
+			if ctx.Err() != nil {
+				break
+			}
+
+			// Тела ответов, вытесненные из буфера браузера, пропускаем
+			body, err := network.GetResponseBody(p.id).Do(ctx)
+			if err != nil {
+				continue
+			}
+			setHARContent(&p.entry.Response.Content, body)
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		for _, p := range order {
+			r.har.Log.Entries = append(r.har.Log.Entries, p.entry)
+		}
+		if len(r.har.Log.Entries) > 0 {
+			first := r.har.Log.Entries[0]
+			r.har.Log.Pages[0].StartedDateTime = first.StartedDateTime
+		}
+		r.har.Log.Pages[0].PageTimings.OnLoad = milliseconds(time.Since(r.har.Log.Pages[0].StartedDateTime))
+
+		*har = r.har
+		return nil
+	})
+}
+
+// harRequestFromCDP переводит запрос браузера в запись HAR
+func harRequestFromCDP(req *network.Request, redact map[string]bool) HARRequest {
+	return HARRequest{
+		Method:      req.Method,
+		URL:         req.URL,
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []HARNameValue{},
+		Headers:     harHeadersFromCDP(req.Headers, redact),
+		QueryString: harQueryString(req.URL),
+		HeadersSize: -1,
+	}
+}
+
+// harResponseFromCDP переводит ответ браузера в запись HAR без тела
+func harResponseFromCDP(resp *network.Response, redact map[string]bool) HARResponse {
+	version := resp.Protocol
+	if version == "" {
+		version = "HTTP/1.1"
+	}
+
+	return HARResponse{
+		Status:      int(resp.Status),
+		StatusText:  resp.StatusText,
+		HTTPVersion: version,
+		Cookies:     []HARNameValue{},
+		Headers:     harHeadersFromCDP(resp.Headers, redact),
+		Content:     HARContent{MimeType: resp.MimeType},
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+}
+
+// harTimingsFromCDP переводит ResourceTiming браузера в этапы HAR
+func harTimingsFromCDP(t *network.ResourceTiming, total float64) HARTimings {
+	timings := HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Receive: total}
+	if t == nil {
+		return timings
+	}
+
+	harPhase := func(start, end float64) float64 {
+		if start < 0 || end < 0 {
+			return -1
+		}
+		return end - start
+	}
+
+	timings.DNS = harPhase(t.DNSStart, t.DNSEnd)
+	timings.Connect = harPhase(t.ConnectStart, t.ConnectEnd)
+	timings.SSL = harPhase(t.SslStart, t.SslEnd)
+	timings.Send = harPhase(t.SendStart, t.SendEnd)
+	timings.Wait = harPhase(t.SendEnd, t.ReceiveHeadersEnd)
+	timings.Receive = total - t.ReceiveHeadersEnd
+
+	// HAR требует неотрицательных send, wait и receive
+	for _, v := range []*float64{&timings.Send, &timings.Wait, &timings.Receive} {
+		if *v < 0 {
+			*v = 0
+		}
+	}
+
+	return timings
+}
+
+// harHeadersFromCDP переводит заголовки браузера в записи HAR, скрывая учетные данные
+func harHeadersFromCDP(headers network.Headers, redact map[string]bool) []HARNameValue {
+	result := make([]HARNameValue, 0, len(headers))
+	for name, value := range headers {
+		// Браузер объединяет повторяющиеся заголовки через перевод строки
+		for _, line := range strings.Split(fmt.Sprint(value), "\n") {
+			result = append(result, harHeader(name, line, redact))
+		}
+	}
+	return result
+}
+
+// harHeadersFromHTTP переводит заголовки HTTP-клиента в записи HAR, скрывая учетные данные
+func harHeadersFromHTTP(headers http.Header, redact map[string]bool) []HARNameValue {
+	result := make([]HARNameValue, 0, len(headers))
+	for name, values := range headers {
+		for _, value := range values {
+			result = append(result, harHeader(name, value, redact))
+		}
+	}
+	return result
+}
+
+// harHeader создает запись заголовка HAR
+func harHeader(name, value string, redact map[string]bool) HARNameValue {
+	if sensitiveHeaders[strings.ToLower(name)] || redact[strings.ToLower(name)] {
+		value = redactedValue
+	}
+	return HARNameValue{Name: name, Value: value}
+}
+
+// harQueryString возвращает параметры запроса из URL
+func harQueryString(rawURL string) []HARNameValue {
+	result := []HARNameValue{}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return result
+	}
+	for name, values := range parsed.Query() {
+		for _, value := range values {
+			result = append(result, HARNameValue{Name: name, Value: value})
+		}
+	}
+	return result
+}
+
+// setHARContent записывает тело ответа: текст как есть, двоичные данные в base64
+func setHARContent(content *HARContent, body []byte) {
+	content.Size = len(body)
+	if len(body) > maxHARBodySize {
+		content.Comment = "тело ответа не сохранено: превышен размер"
+		return
+	}
+
+	if isTextMimeType(content.MimeType) && utf8.Valid(body) {
+		content.Text = string(body)
+		return
+	}
+	content.Text = base64.StdEncoding.EncodeToString(body)
+	content.Encoding = "base64"
+}
+
+// isTextMimeType проверяет, что тело ответа текстовое
+func isTextMimeType(mimeType string) bool {
+	mimeType = strings.ToLower(mimeType)
+	return strings.HasPrefix(mimeType, "text/") ||
+		strings.Contains(mimeType, "json") ||
+		strings.Contains(mimeType, "javascript") ||
+		strings.Contains(mimeType, "xml")
+}
+
+// harFromHTTP создает HAR по ответу HTTP-клиента: цепочка перенаправлений и итоговая страница
+func harFromHTTP(pageURL string, resp *http.Response, body []byte, started time.Time, timing *models.ResponseTiming, redact map[string]bool) *HAR {
+	har := newHAR(pageURL, started.UTC())
+
+	var chain []*http.Response
+	for r := resp; r != nil; r = r.Request.Response {
+		chain = append([]*http.Response{r}, chain...)
+	}
+
+	for _, r := range chain {
+		entry := &HAREntry{
+			PageRef:         "page_1",
+			StartedDateTime: started.UTC(),
+			Request: HARRequest{
+				Method:      r.Request.Method,
+				URL:         r.Request.URL.String(),
+				HTTPVersion: r.Proto,
+				Cookies:     []HARNameValue{},
+				Headers:     harHeadersFromHTTP(r.Request.Header, redact),
+				QueryString: harQueryString(r.Request.URL.String()),
+				HeadersSize: -1,
+			},
+			Response: HARResponse{
+				Status:      r.StatusCode,
+				StatusText:  http.StatusText(r.StatusCode),
+				HTTPVersion: r.Proto,
+				Cookies:     []HARNameValue{},
+				Headers:     harHeadersFromHTTP(r.Header, redact),
+				Content:     HARContent{MimeType: r.Header.Get("Content-Type")},
+				HeadersSize: -1,
+				BodySize:    -1,
+			},
+			Timings: HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+		}
+
+		if location, err := r.Location(); err == nil {
+			entry.Response.RedirectURL = location.String()
+		}
+
+		// Тело и тайминги известны только для последнего ответа
+		if r == resp {
+			setHARContent(&entry.Response.Content, body)
+			entry.Response.BodySize = len(body)
+			if timing != nil {
+				entry.Time = timing.TotalMS
+				entry.Timings.Wait = timing.TTFBMS
+				entry.Timings.Receive = max(timing.TotalMS-timing.TTFBMS, 0)
+			}
+		}
+
+		har.Log.Entries = append(har.Log.Entries, entry)
+	}
+
+	if timing != nil {
+		har.Log.Pages[0].PageTimings.OnLoad = timing.TotalMS
+	}
+
+	return har
+}
+
+// monotonic переводит монотонное время браузера в time.Time
+func monotonic(t *cdp.MonotonicTime) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.Time()
+}
+
+// harReplay отдает ответы из сохраненного HAR вместо обращения к сети.
+// Повторяющиеся запросы получают ответы в порядке записи, последний ответ повторяется
+type harReplay struct {
+	mu      sync.Mutex
+	entries map[string][]*HAREntry
+	served  map[string]int
+}
+
+// newHARReplay индексирует записи HAR по методу и URL
+func newHARReplay(har *HAR) *harReplay {
+	replay := &harReplay{
+		entries: make(map[string][]*HAREntry),
+		served:  make(map[string]int),
+	}
+
+	for _, entry := range har.Log.Entries {
+		if entry.Error != "" || entry.Response.Status == 0 {
+			continue
+		}
+		key := replayKey(entry.Request.Method, entry.Request.URL)
+		replay.entries[key] = append(replay.entries[key], entry)
+	}
+
+	return replay
+}
+
+// replayKey строит ключ записи без фрагмента URL
+func replayKey(method, rawURL string) string {
+	if i := strings.IndexByte(rawURL, '#'); i >= 0 {
+		rawURL = rawURL[:i]
+	}
+	return strings.ToUpper(method) + " " + rawURL
+}
+
+// lookup находит ответ на запрос или возвращает nil, если запрос не записан
+func (r *harReplay) lookup(method, rawURL string) *HAREntry {
+	key := replayKey(method, rawURL)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := r.entries[key]
+	if len(entries) == 0 {
+		return nil
+	}
+
+	i := r.served[key]
+	if i >= len(entries) {
+		i = len(entries) - 1
+	}
+	r.served[key]++
+
+	return entries[i]
+}
+
+// replayBody возвращает тело ответа записи
+func replayBody(entry *HAREntry) []byte {
+	if entry.Response.Content.Encoding == "base64" {
+		body, err := base64.StdEncoding.DecodeString(entry.Response.Content.Text)
+		if err != nil {
+			return nil
+		}
+		return body
+	}
+	return []byte(entry.Response.Content.Text)
+}
+
+// replayHeaders возвращает заголовки ответа для воспроизведения. Тело в HAR хранится
+// распакованным, поэтому заголовки сжатия и длины не передаются
+func replayHeaders(entry *HAREntry) []HARNameValue {
+	var headers []HARNameValue
+	for _, header := range entry.Response.Headers {
+		name := strings.ToLower(header.Name)
+		if name == "content-encoding" || name == "content-length" || name == "transfer-encoding" || header.Value == redactedValue {
+			continue
+		}
+		headers = append(headers, header)
+	}
+	return headers
+}
+
+// Intercept перехватывает запросы вкладки и отвечает на них из HAR, работая для браузера
+// как локальный прокси. Запросы, которых нет в записи, завершаются ошибкой сети.
+// Вызывается до первого действия во вкладке; возвращает действие, включающее перехват
+func (r *harReplay) Intercept(ctx context.Context) chromedp.Action {
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		paused, ok := ev.(*fetch.EventRequestPaused)
+		if !ok {
+			return
+		}
+
+		// Команды нельзя выполнять в обработчике событий, он блокирует их получение
+		go func() {
+			executor := cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Target)
+
+			entry := r.lookup(paused.Request.Method, paused.Request.URL)
+			if entry == nil {
+				if err := fetch.FailRequest(paused.RequestID, network.ErrorReasonInternetDisconnected).Do(executor); err != nil && ctx.Err() == nil {
+					log.Printf("Ошибка отклонения запроса %s: %v", paused.Request.URL, err)
+				}
+				return
+			}
+
+			var headers []*fetch.HeaderEntry
+			for _, header := range replayHeaders(entry) {
+				headers = append(headers, &fetch.HeaderEntry{Name: header.Name, Value: header.Value})
+			}
+
+			fulfill := fetch.FulfillRequest(paused.RequestID, int64(entry.Response.Status)).
+				WithResponseHeaders(headers).
+				WithBody(base64.StdEncoding.EncodeToString(replayBody(entry)))
+			if entry.Response.StatusText != "" {
+				fulfill = fulfill.WithResponsePhrase(entry.Response.StatusText)
+			}
+
+			if err := fulfill.Do(executor); err != nil && ctx.Err() == nil {
+				log.Printf("Ошибка воспроизведения запроса %s: %v", paused.Request.URL, err)
+			}
+		}()
+	})
+
+	return fetch.Enable()
+}
+
+// RoundTrip отвечает на запросы HTTP-клиента из HAR
+func (r *harReplay) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := r.lookup(req.Method, req.URL.String())
+	if entry == nil {
+		return nil, fmt.Errorf("запрос %s %s отсутствует в HAR", req.Method, req.URL)
+	}
+
+	header := make(http.Header)
+	for _, h := range replayHeaders(entry) {
+		header.Add(h.Name, h.Value)
+	}
+
+	body := replayBody(entry)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.Response.Status, entry.Response.StatusText),
+		StatusCode:    entry.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// replayFor загружает HAR для воспроизведения, если он указан в параметрах
+func (d *Downloader) replayFor(ctx context.Context, opts models.ParseOptions) (*harReplay, error) {
+	if opts.ReplayHAR == nil {
+		return nil, nil
+	}
+
+	har, err := d.LoadHAR(ctx, *opts.ReplayHAR)
+	if err != nil {
+		return nil, err
+	}
+	return newHARReplay(har), nil
+}
+
+// startReplay включает во вкладке воспроизведение HAR, если оно задано в параметрах
+func (d *Downloader) startReplay(taskCtx context.Context, opts models.ParseOptions) error {
+	replay, err := d.replayFor(taskCtx, opts)
+	if err != nil || replay == nil {
+		return err
+	}
+
+	log.Printf("Страница загружается из HAR операции %s", opts.ReplayHAR)
+	return chromedp.Run(taskCtx, replay.Intercept(taskCtx))
+}
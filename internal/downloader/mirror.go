@@ -0,0 +1,321 @@
+package downloader
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"website-scraper/internal/assets"
+	"website-scraper/internal/models"
+	"website-scraper/internal/proxy"
+	"website-scraper/internal/storage"
+)
+
+// maxMirrorAssetSize ограничивает размер одного скачиваемого ресурса
+const maxMirrorAssetSize = 20 << 20
+
+// Stylesheet представляет таблицу стилей страницы вместе с URL, относительно которого разрешаются ссылки
+type Stylesheet struct {
+	BaseURL string
+	CSS     string
+}
+
+// PageStyles содержит все таблицы стилей страницы в порядке подключения
+type PageStyles struct {
+	Sheets []Stylesheet
+}
+
+// assetMirror скачивает ресурсы блока к файлам блоков операции и запоминает уже скачанные
+type assetMirror struct {
+	d      *Downloader
+	ctx    context.Context
+	prefix string
+	files  map[string]string
+}
+
+// CollectPageStyles загружает внешние таблицы стилей страницы и собирает встроенные стили
+func (d *Downloader) CollectPageStyles(ctx context.Context, pageURL, pageHTML string) *PageStyles {
+	styles := &PageStyles{}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(pageHTML))
+	if err != nil {
+		return styles
+	}
+
+	base, _ := url.Parse(pageURL)
+
+	doc.Find("link[rel~='stylesheet'], style").Each(func(i int, el *goquery.Selection) {
+		if goquery.NodeName(el) == "style" {
+			styles.Sheets = append(styles.Sheets, Stylesheet{BaseURL: pageURL, CSS: el.Text()})
+			return
+		}
+
+		sheetURL := assets.Resolve(base, el.AttrOr("href", ""))
+		if sheetURL == "" {
+			return
+		}
+
+		data, _, err := d.fetchAsset(ctx, sheetURL)
+		if err != nil {
+			log.Printf("Ошибка загрузки таблицы стилей %s: %v", sheetURL, err)
+			return
+		}
+		styles.Sheets = append(styles.Sheets, Stylesheet{BaseURL: sheetURL, CSS: string(data)})
+	})
+
+	return styles
+}
+
+// SaveStandaloneBlock сохраняет блок как самостоятельную HTML-страницу: ресурсы скачиваются
+// к файлам блоков операции, ссылки переписываются на локальные, критический CSS страницы встраивается
+func (d *Downloader) SaveStandaloneBlock(ctx context.Context, block *models.Block, pageURL string, styles *PageStyles) error {
+	mirror := &assetMirror{
+		d:      d,
+		ctx:    ctx,
+		prefix: blockPrefix(block.OperationID),
+		files:  make(map[string]string),
+	}
+
+	// Оставляем из стилей страницы только правила, применимые к блоку
+	var css strings.Builder
+	if styles != nil {
+		used := assets.CollectSelectors(block.HTML)
+		for _, sheet := range styles.Sheets {
+			critical := assets.CriticalCSS(sheet.CSS, used)
+			if critical == "" {
+				continue
+			}
+			css.WriteString(mirror.rewriteCSS(critical, sheet.BaseURL))
+			css.WriteString("\n")
+		}
+	}
+
+	blockHTML, err := mirror.rewriteHTML(block.HTML, pageURL)
+	if err != nil {
+		return fmt.Errorf("ошибка обработки HTML блока: %w", err)
+	}
+
+	document := `<!DOCTYPE html>
+<html lang="ru">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>` + string(block.BlockType) + ` ` + block.ID.String() + `</title>
+    <style>
+` + css.String() + `
+    </style>
+</head>
+<body>
+` + blockHTML + `
+</body>
+</html>`
+
+	htmlFilename := fmt.Sprintf("%s_%s_standalone.html", block.BlockType, block.ID.String())
+	if err := storage.PutBytes(ctx, d.storage, mirror.prefix+htmlFilename, []byte(document), htmlContentType); err != nil {
+		return fmt.Errorf("ошибка сохранения HTML блока: %w", err)
+	}
+
+	log.Printf("Автономная копия блока сохранена: %s (%d ресурсов)", htmlFilename, len(mirror.files))
+	return nil
+}
+
+// rewriteHTML переписывает ссылки на изображения, стили и шрифты блока на локальные копии
+func (m *assetMirror) rewriteHTML(blockHTML, pageURL string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(blockHTML))
+	if err != nil {
+		return "", err
+	}
+
+	base, _ := url.Parse(pageURL)
+
+	doc.Find("img, source").Each(func(i int, el *goquery.Selection) {
+		// Ленивые изображения подставляем сразу в src, иначе без скриптов страницы они не появятся
+		lazySrc := ""
+		for _, attr := range []string{"src", "data-original", "data-src", "data-lazy-src"} {
+			if src, exists := el.Attr(attr); exists {
+				if local := m.localize(assets.Resolve(base, src)); local != "" {
+					el.SetAttr(attr, local)
+					if attr != "src" && lazySrc == "" {
+						lazySrc = local
+					}
+				}
+			}
+		}
+		if lazySrc != "" && goquery.NodeName(el) == "img" {
+			el.SetAttr("src", lazySrc)
+		}
+		for _, attr := range []string{"srcset", "data-srcset"} {
+			if srcset, exists := el.Attr(attr); exists {
+				el.SetAttr(attr, m.rewriteSrcset(srcset, base))
+			}
+		}
+	})
+
+	doc.Find("[style]").Each(func(i int, el *goquery.Selection) {
+		el.SetAttr("style", m.rewriteCSS(el.AttrOr("style", ""), pageURL))
+	})
+
+	// Фоновые изображения Tilda задаются через data-original и подставляются скриптом
+	doc.Find(".t-bgimg[data-original], [data-bg]").Each(func(i int, el *goquery.Selection) {
+		ref := el.AttrOr("data-original", el.AttrOr("data-bg", ""))
+		if local := m.localize(assets.Resolve(base, ref)); local != "" {
+			style := strings.TrimSpace(el.AttrOr("style", ""))
+			if style != "" && !strings.HasSuffix(style, ";") {
+				style += ";"
+			}
+			el.SetAttr("style", style+"background-image:url('"+local+"');")
+		}
+	})
+
+	doc.Find("style").Each(func(i int, el *goquery.Selection) {
+		el.SetText(m.rewriteCSS(el.Text(), pageURL))
+	})
+
+	doc.Find("link[href]").Each(func(i int, el *goquery.Selection) {
+		rel := strings.ToLower(el.AttrOr("rel", ""))
+		if !strings.Contains(rel, "stylesheet") && el.AttrOr("as", "") != "font" {
+			return
+		}
+		if local := m.localize(assets.Resolve(base, el.AttrOr("href", ""))); local != "" {
+			el.SetAttr("href", local)
+		}
+	})
+
+	doc.Find("video[poster]").Each(func(i int, el *goquery.Selection) {
+		if local := m.localize(assets.Resolve(base, el.AttrOr("poster", ""))); local != "" {
+			el.SetAttr("poster", local)
+		}
+	})
+
+	return doc.Find("body").Html()
+}
+
+// rewriteCSS переписывает ссылки url(...) в CSS на локальные копии
+func (m *assetMirror) rewriteCSS(css, baseURL string) string {
+	base, _ := url.Parse(baseURL)
+
+	return assets.RewriteCSSURLs(css, func(ref string) string {
+		return m.localize(assets.Resolve(base, ref))
+	})
+}
+
+// rewriteSrcset переписывает ссылки атрибута srcset на локальные копии
+func (m *assetMirror) rewriteSrcset(srcset string, base *url.URL) string {
+	candidates := strings.Split(srcset, ",")
+	for i, candidate := range candidates {
+		fields := strings.Fields(strings.TrimSpace(candidate))
+		if len(fields) == 0 {
+			continue
+		}
+		if local := m.localize(assets.Resolve(base, fields[0])); local != "" {
+			fields[0] = local
+		}
+		candidates[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// localize скачивает ресурс и возвращает путь к локальной копии относительно файла блока
+func (m *assetMirror) localize(assetURL string) string {
+	if assetURL == "" {
+		return ""
+	}
+	if local, ok := m.files[assetURL]; ok {
+		return local
+	}
+
+	hash := sha1.Sum([]byte(assetURL))
+	name := hex.EncodeToString(hash[:])[:16]
+
+	data, contentType, err := m.d.fetchAsset(m.ctx, assetURL)
+	if err != nil {
+		log.Printf("Ошибка загрузки ресурса %s: %v", assetURL, err)
+		m.files[assetURL] = ""
+		return ""
+	}
+
+	local := "assets/" + name + assetExtension(assetURL, contentType)
+	// Запоминаем путь заранее: таблицы стилей могут ссылаться друг на друга
+	m.files[assetURL] = local
+
+	// Ссылки внутри скачанной таблицы стилей разрешаются относительно ее адреса,
+	// а локальные копии лежат рядом с ней в той же директории assets/
+	if path.Ext(local) == ".css" {
+		base, _ := url.Parse(assetURL)
+		css := assets.RewriteCSSURLs(string(data), func(ref string) string {
+			return strings.TrimPrefix(m.localize(assets.Resolve(base, ref)), "assets/")
+		})
+		data = []byte(css)
+	}
+
+	if err := storage.PutBytes(m.ctx, m.d.storage, m.prefix+local, data, contentType); err != nil {
+		log.Printf("Ошибка сохранения ресурса %s: %v", assetURL, err)
+		m.files[assetURL] = ""
+		return ""
+	}
+
+	return local
+}
+
+// fetchAsset загружает ресурс по HTTP
+func (d *Downloader) fetchAsset(ctx context.Context, assetURL string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, assetURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", d.cfg.Scraper.UserAgent)
+
+	// Ресурсы загружаются через прокси пула, закрепленный за их доменом
+	proxied, err := d.proxies.Select(assetURL, "")
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := d.proxies.Client(d.client, proxied).Do(req)
+	d.proxies.Report(proxied, proxy.ResponseError(resp, err))
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxMirrorAssetSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxMirrorAssetSize {
+		return nil, "", fmt.Errorf("asset is larger than %d bytes", maxMirrorAssetSize)
+	}
+
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// assetExtension определяет расширение файла ресурса по URL или Content-Type
+func assetExtension(assetURL, contentType string) string {
+	if parsed, err := url.Parse(assetURL); err == nil {
+		if ext := path.Ext(parsed.Path); ext != "" && len(ext) <= 6 {
+			return strings.ToLower(ext)
+		}
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if extensions, err := mime.ExtensionsByType(mediaType); err == nil && len(extensions) > 0 {
+			return extensions[0]
+		}
+	}
+
+	return ""
+}
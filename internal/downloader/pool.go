@@ -0,0 +1,397 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+
+	"website-scraper/internal/config"
+)
+
+// healthCheckTimeout ограничивает время проверки одного браузера
+const healthCheckTimeout = 5 * time.Second
+
+// ErrPoolClosed возвращается при запросе вкладки у остановленного пула
+var ErrPoolClosed = errors.New("пул браузеров остановлен")
+
+// BrowserPool управляет долгоживущими экземплярами Chrome и выдает вкладки для отдельных загрузок.
+// Число одновременно открытых вкладок ограничено, браузеры, которые упали или отработали
+// заданное число вкладок, пересоздаются
+type BrowserPool struct {
+	cfg *config.Config
+
+	mu       sync.Mutex
+	browsers []*pooledBrowser
+	closed   bool
+
+	// launching содержит число запускаемых браузеров, launched сообщает о завершении запуска.
+	// Браузер запускается без блокировки, но место под него в пуле занимается заранее
+	launching int
+	launched  *sync.Cond
+
+	// tabs ограничивает число одновременно открытых вкладок
+	tabs chan struct{}
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// pooledBrowser представляет запущенный экземпляр Chrome
+type pooledBrowser struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// active содержит число открытых вкладок, served — число вкладок за все время жизни
+	active  int
+	served  int
+	retired bool
+}
+
+// NewBrowserPool создает пул браузеров. Браузеры запускаются лениво при первом запросе вкладки
+func NewBrowserPool(cfg *config.Config) *BrowserPool {
+	maxTabs := cfg.Downloader.MaxTabs
+	if maxTabs <= 0 {
+		maxTabs = 1
+	}
+
+	p := &BrowserPool{
+		cfg:  cfg,
+		tabs: make(chan struct{}, maxTabs),
+		stop: make(chan struct{}),
+	}
+	p.launched = sync.NewCond(&p.mu)
+	return p
+}
+
+// Start запускает периодическую проверку браузеров
+func (p *BrowserPool) Start() {
+	interval := p.cfg.Downloader.HealthCheckInterval
+	if interval <= 0 {
+		return
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.checkHealth()
+			}
+		}
+	}()
+}
+
+// Close закрывает все браузеры пула. Открытые вкладки прерываются
+func (p *BrowserPool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	browsers := p.browsers
+	p.browsers = nil
+	p.mu.Unlock()
+
+	close(p.stop)
+	p.wg.Wait()
+
+	for _, browser := range browsers {
+		browser.cancel()
+	}
+
+	log.Printf("Пул браузеров остановлен, закрыто браузеров: %d", len(browsers))
+}
+
+// Acquire открывает новую вкладку в одном из браузеров пула. Если все вкладки заняты,
+// ожидает освобождения или отмены контекста. Вкладку нужно закрыть вызовом release
+func (p *BrowserPool) Acquire(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	return p.acquire(ctx)
+}
+
+// AcquireIsolated открывает вкладку в отдельном контексте браузера со своими cookies и кэшем.
+// Используется для загрузок с учетными данными, чтобы сессия не досталась другим операциям.
+// Непустой proxyServer направляет запросы вкладки через прокси
+func (p *BrowserPool) AcquireIsolated(ctx context.Context, proxyServer string) (context.Context, context.CancelFunc, error) {
+	if proxyServer == "" {
+		return p.acquire(ctx, chromedp.WithNewBrowserContext())
+	}
+
+	return p.acquire(ctx, chromedp.WithNewBrowserContext(func(params *target.CreateBrowserContextParams) *target.CreateBrowserContextParams {
+		return params.WithProxyServer(proxyServer)
+	}))
+}
+
+// acquire открывает вкладку с переданными параметрами контекста
+func (p *BrowserPool) acquire(ctx context.Context, opts ...chromedp.ContextOption) (context.Context, context.CancelFunc, error) {
+	select {
+	case p.tabs <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-p.stop:
+		return nil, nil, ErrPoolClosed
+	}
+
+	browser, err := p.pickBrowser()
+	if err != nil {
+		<-p.tabs
+		return nil, nil, err
+	}
+
+	tabCtx, cancelTab := chromedp.NewContext(browser.ctx, opts...)
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			cancelTab()
+			p.releaseTab(browser)
+			<-p.tabs
+		})
+	}
+
+	return tabCtx, release, nil
+}
+
+// pickBrowser выбирает наименее загруженный браузер, при необходимости запуская новый.
+// Запуск Chrome занимает секунды, поэтому выполняется без блокировки пула
+func (p *BrowserPool) pickBrowser() (*pooledBrowser, error) {
+	size := p.cfg.Downloader.BrowserPoolSize
+	if size <= 0 {
+		size = 1
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for {
+		if p.closed {
+			return nil, ErrPoolClosed
+		}
+
+		best := p.leastLoaded()
+		slots := size - p.liveBrowsers() - p.launching
+
+		// Новый браузер запускаем, только если все существующие заняты и есть место в пуле
+		if best != nil && (best.active == 0 || slots <= 0) {
+			return p.assignTab(best), nil
+		}
+		if best == nil && slots <= 0 {
+			// Все места заняты запускаемыми браузерами, ждем любой из них
+			p.launched.Wait()
+			continue
+		}
+
+		p.launching++
+		p.mu.Unlock()
+		browser, err := p.launchBrowser()
+		p.mu.Lock()
+		p.launching--
+		p.launched.Broadcast()
+
+		if p.closed {
+			if browser != nil {
+				browser.cancel()
+			}
+			return nil, ErrPoolClosed
+		}
+		if err != nil {
+			// За время запуска существующий браузер мог закрыться, выбираем заново
+			if best := p.leastLoaded(); best != nil {
+				log.Printf("Ошибка запуска браузера, используем существующий: %v", err)
+				return p.assignTab(best), nil
+			}
+			return nil, err
+		}
+
+		p.browsers = append(p.browsers, browser)
+		return p.assignTab(browser), nil
+	}
+}
+
+// leastLoaded возвращает браузер с наименьшим числом открытых вкладок. Вызывается под блокировкой
+func (p *BrowserPool) leastLoaded() *pooledBrowser {
+	var best *pooledBrowser
+	for _, browser := range p.browsers {
+		if browser.retired {
+			continue
+		}
+		if best == nil || browser.active < best.active {
+			best = browser
+		}
+	}
+	return best
+}
+
+// assignTab учитывает новую вкладку браузера. Вызывается под блокировкой
+func (p *BrowserPool) assignTab(browser *pooledBrowser) *pooledBrowser {
+	browser.active++
+	browser.served++
+
+	recycleAfter := p.cfg.Downloader.BrowserRecycleAfter
+	if recycleAfter > 0 && browser.served >= recycleAfter {
+		// Браузер дорабатывает текущие вкладки и закрывается, чтобы не копить утечки памяти
+		browser.retired = true
+	}
+
+	return browser
+}
+
+// releaseTab уменьшает счетчик вкладок браузера и закрывает выведенный из работы браузер
+func (p *BrowserPool) releaseTab(browser *pooledBrowser) {
+	p.mu.Lock()
+	browser.active--
+	shouldClose := browser.retired && browser.active == 0
+	if shouldClose {
+		p.removeBrowser(browser)
+	}
+	p.mu.Unlock()
+
+	if shouldClose {
+		browser.cancel()
+		log.Printf("Браузер пересоздается после %d вкладок", browser.served)
+	}
+}
+
+// checkHealth проверяет простаивающие браузеры и закрывает неотвечающие
+func (p *BrowserPool) checkHealth() {
+	p.mu.Lock()
+	var idle []*pooledBrowser
+	for _, browser := range p.browsers {
+		if browser.active == 0 && !browser.retired {
+			idle = append(idle, browser)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, browser := range idle {
+		err := probeBrowser(browser.ctx)
+		if err == nil {
+			continue
+		}
+		log.Printf("Браузер не отвечает и будет перезапущен: %v", err)
+
+		p.mu.Lock()
+		// За время проверки браузер мог получить новые вкладки
+		if browser.active > 0 {
+			browser.retired = true
+			p.mu.Unlock()
+			continue
+		}
+		p.removeBrowser(browser)
+		p.mu.Unlock()
+
+		browser.cancel()
+	}
+}
+
+// liveBrowsers возвращает число браузеров, принимающих новые вкладки. Вызывается под блокировкой
+func (p *BrowserPool) liveBrowsers() int {
+	count := 0
+	for _, browser := range p.browsers {
+		if !browser.retired {
+			count++
+		}
+	}
+	return count
+}
+
+// removeBrowser удаляет браузер из пула. Вызывается под блокировкой
+func (p *BrowserPool) removeBrowser(browser *pooledBrowser) {
+	for i, b := range p.browsers {
+		if b == browser {
+			p.browsers = append(p.browsers[:i], p.browsers[i+1:]...)
+			return
+		}
+	}
+}
+
+// launchBrowser запускает новый экземпляр Chrome
+func (p *BrowserPool) launchBrowser() (*pooledBrowser, error) {
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), p.allocatorOptions()...)
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Printf))
+
+	// Первый Run запускает процесс браузера
+	if err := chromedp.Run(browserCtx); err != nil {
+		cancelBrowser()
+		cancelAlloc()
+		return nil, err
+	}
+
+	log.Printf("Запущен браузер пула")
+
+	return &pooledBrowser{
+		ctx: browserCtx,
+		cancel: func() {
+			cancelBrowser()
+			cancelAlloc()
+		},
+	}, nil
+}
+
+// probeBrowser открывает вкладку и выполняет простой скрипт, проверяя, что браузер жив
+func probeBrowser(browserCtx context.Context) error {
+	tabCtx, cancelTab := chromedp.NewContext(browserCtx)
+	defer cancelTab()
+
+	probeCtx, cancel := context.WithTimeout(tabCtx, healthCheckTimeout)
+	defer cancel()
+
+	var result int
+	return chromedp.Run(probeCtx, chromedp.Evaluate(`1`, &result))
+}
+
+// allocatorOptions возвращает параметры запуска Chrome
+func (p *BrowserPool) allocatorOptions() []chromedp.ExecAllocatorOption {
+	// Определяем путь к браузеру
+	var execPath string
+
+	// Проверяем, какой браузер установлен
+	if _, err := os.Stat("/usr/bin/google-chrome"); err == nil {
+		execPath = "/usr/bin/google-chrome"
+	} else if _, err := os.Stat("/usr/bin/chromium-browser"); err == nil {
+		execPath = "/usr/bin/chromium-browser"
+	} else if _, err := os.Stat("/usr/bin/chromium"); err == nil {
+		execPath = "/usr/bin/chromium"
+	}
+
+	// Essential Chrome flags for Docker environment
+	opts := []chromedp.ExecAllocatorOption{
+		chromedp.NoFirstRun,
+		chromedp.NoDefaultBrowserCheck,
+		chromedp.Headless,
+		chromedp.Flag("disable-gpu", true),
+		chromedp.Flag("no-sandbox", true),
+		chromedp.Flag("disable-setuid-sandbox", true),
+		chromedp.Flag("disable-dev-shm-usage", true),
+		chromedp.Flag("disable-background-timer-throttling", true),
+		chromedp.Flag("disable-backgrounding-occluded-windows", true),
+		chromedp.Flag("disable-renderer-backgrounding", true),
+		chromedp.Flag("disable-features", "TranslateUI"),
+		chromedp.Flag("disable-ipc-flooding-protection", true),
+		chromedp.Flag("disable-background-networking", true),
+		chromedp.Flag("disable-client-side-phishing-detection", true),
+		chromedp.Flag("disable-default-apps", true),
+		chromedp.UserAgent(p.cfg.Scraper.UserAgent),
+		chromedp.WindowSize(1920, 1080),
+	}
+
+	// Добавляем путь к браузеру, если нашли
+	if execPath != "" {
+		opts = append(opts, chromedp.ExecPath(execPath))
+	}
+
+	return opts
+}
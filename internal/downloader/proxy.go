@@ -0,0 +1,39 @@
+package downloader
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/chromedp/cdproto/network"
+
+	"website-scraper/internal/models"
+	"website-scraper/internal/proxy"
+)
+
+// proxyFor выбирает прокси загрузки: прокси запроса или прокси пула. При воспроизведении HAR
+// обращений к сети нет, и прокси не используется
+func (d *Downloader) proxyFor(pageURL string, opts models.ParseOptions) (*proxy.Proxy, error) {
+	if opts.ReplayHAR != nil {
+		return nil, nil
+	}
+
+	proxied, err := d.proxies.Select(pageURL, opts.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выбора прокси: %w", err)
+	}
+	return proxied, nil
+}
+
+// ProxyStatus возвращает состояние прокси пула
+func (d *Downloader) ProxyStatus() []models.ProxyStatus {
+	return d.proxies.Status()
+}
+
+// navigationError возвращает ошибку перехода в браузере для учета в пуле прокси,
+// считая ответ 407 ошибкой прокси
+func navigationError(resp *network.Response, err error) error {
+	if err == nil && resp != nil && resp.Status == http.StatusProxyAuthRequired {
+		return proxy.ErrProxyAuth
+	}
+	return err
+}
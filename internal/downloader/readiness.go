@@ -0,0 +1,274 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+
+	"website-scraper/internal/models"
+)
+
+const (
+	// readinessPollInterval задает период проверки готовности страницы
+	readinessPollInterval = 100 * time.Millisecond
+
+	// maxIdleRequests задает число незавершенных запросов, при котором сеть считается простаивающей.
+	// Счетчики аналитики и long polling иначе не дают странице стать готовой
+	maxIdleRequests = 2
+
+	// scrollStepDelay задает паузу между шагами прокрутки, чтобы ленивые секции успели начать загрузку
+	scrollStepDelay = 250 * time.Millisecond
+
+	// defaultFixedWait сохраняет прежнее поведение стратегии fixed без заданного времени
+	defaultFixedWait = 2 * time.Second
+)
+
+// installMutationObserverJS запоминает время последнего изменения DOM
+const installMutationObserverJS = `(function() {
+	if (window.__scraperLastMutation !== undefined) return true;
+	window.__scraperLastMutation = performance.now();
+	new MutationObserver(function() {
+		window.__scraperLastMutation = performance.now();
+	}).observe(document, {childList: true, subtree: true, attributes: true, characterData: true});
+	return true;
+})()`
+
+// domIdleJS возвращает время в миллисекундах с последнего изменения DOM
+const domIdleJS = `performance.now() - window.__scraperLastMutation`
+
+// scrollStepJS прокручивает страницу на высоту окна и сообщает, достигнут ли конец страницы
+const scrollStepJS = `(function() {
+	window.scrollBy(0, window.innerHeight);
+	return window.scrollY + window.innerHeight >= document.documentElement.scrollHeight - 2;
+})()`
+
+// pageWaiter определяет момент, когда страница в браузере готова к извлечению HTML
+type pageWaiter struct {
+	strategy   models.WaitStrategy
+	selector   string
+	idle       time.Duration
+	autoScroll bool
+	maxWait    time.Duration
+
+	mu           sync.Mutex
+	inflight     map[network.RequestID]bool
+	lastActivity time.Time
+}
+
+// newPageWaiter создает ожидание готовности страницы, дополняя параметры запроса настройками сервиса
+func (d *Downloader) newPageWaiter(opts *models.WaitOptions) *pageWaiter {
+	cfg := d.cfg.Downloader
+
+	w := &pageWaiter{
+		strategy:     models.WaitStrategy(cfg.WaitStrategy),
+		selector:     cfg.WaitSelector,
+		idle:         cfg.WaitIdle,
+		autoScroll:   cfg.AutoScroll,
+		maxWait:      cfg.WaitMax,
+		inflight:     make(map[network.RequestID]bool),
+		lastActivity: time.Now(),
+	}
+
+	if opts != nil {
+		if opts.Strategy != "" {
+			w.strategy = opts.Strategy
+		}
+		if opts.Selector != "" {
+			w.selector = opts.Selector
+		}
+		if opts.IdleMS > 0 {
+			w.idle = time.Duration(opts.IdleMS) * time.Millisecond
+		}
+		if opts.AutoScroll != nil {
+			w.autoScroll = *opts.AutoScroll
+		}
+		if opts.MaxWaitMS > 0 {
+			w.maxWait = time.Duration(opts.MaxWaitMS) * time.Millisecond
+		}
+	}
+
+	if w.strategy == models.WaitFixed && w.idle <= 0 {
+		w.idle = defaultFixedWait
+	}
+
+	return w
+}
+
+// ValidWaitOptions проверяет параметры ожидания готовности страницы
+func ValidWaitOptions(opts *models.WaitOptions) error {
+	if opts == nil {
+		return nil
+	}
+
+	switch opts.Strategy {
+	case "", models.WaitNetworkIdle, models.WaitDOMIdle, models.WaitFixed:
+	case models.WaitSelector:
+		if opts.Selector == "" {
+			return fmt.Errorf("для стратегии selector нужно указать selector")
+		}
+	default:
+		return fmt.Errorf("неизвестная стратегия ожидания: %s (допустимо: network_idle, dom_idle, selector, fixed)", opts.Strategy)
+	}
+
+	if opts.IdleMS < 0 || opts.MaxWaitMS < 0 {
+		return fmt.Errorf("время ожидания не может быть отрицательным")
+	}
+
+	return nil
+}
+
+// Listen подписывается на сетевые события вкладки. Вызывается до перехода на страницу
+func (w *pageWaiter) Listen(ctx context.Context) {
+	if w.strategy != models.WaitNetworkIdle {
+		return
+	}
+
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		switch ev := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			// Веб-сокеты и потоки событий не завершаются и не должны блокировать готовность
+			if ev.Type == network.ResourceTypeWebSocket || ev.Type == network.ResourceTypeEventSource {
+				return
+			}
+			w.inflight[ev.RequestID] = true
+		case *network.EventLoadingFinished:
+			delete(w.inflight, ev.RequestID)
+		case *network.EventLoadingFailed:
+			delete(w.inflight, ev.RequestID)
+		default:
+			return
+		}
+		w.lastActivity = time.Now()
+	})
+}
+
+// Wait ждет готовности страницы. Истечение общего бюджета ожидания ошибкой не считается:
+// страница берется в том состоянии, в котором успела загрузиться
+func (w *pageWaiter) Wait() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		started := time.Now()
+
+		waitCtx := ctx
+		if w.maxWait > 0 {
+			var cancel context.CancelFunc
+			waitCtx, cancel = context.WithTimeout(ctx, w.maxWait)
+			defer cancel()
+		}
+
+		err := w.wait(waitCtx)
+
+		// Ошибка родительского контекста (общий таймаут загрузки) прерывает загрузку
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil && waitCtx.Err() == nil {
+			return err
+		}
+		if waitCtx.Err() != nil {
+			log.Printf("Страница не стала готовой за %v (%s), используем текущее состояние", w.maxWait, w.strategy)
+		}
+
+		log.Printf("Ожидание готовности страницы (%s): %v", w.strategy, time.Since(started).Round(time.Millisecond))
+		return nil
+	})
+}
+
+// wait выполняет прокрутку и ожидание по выбранной стратегии
+func (w *pageWaiter) wait(ctx context.Context) error {
+	if w.strategy == models.WaitDOMIdle {
+		var installed bool
+		if err := chromedp.Evaluate(installMutationObserverJS, &installed).Do(ctx); err != nil {
+			return err
+		}
+	}
+
+	if w.autoScroll {
+		if err := w.scroll(ctx); err != nil {
+			return err
+		}
+	}
+
+	switch w.strategy {
+	case models.WaitNetworkIdle:
+		return w.waitNetworkIdle(ctx)
+	case models.WaitDOMIdle:
+		return w.waitDOMIdle(ctx)
+	case models.WaitSelector:
+		return chromedp.WaitVisible(w.selector, chromedp.ByQuery).Do(ctx)
+	case models.WaitFixed:
+		return chromedp.Sleep(w.idle).Do(ctx)
+	}
+
+	return nil
+}
+
+// scroll прокручивает страницу до конца шагами по высоте окна и возвращается наверх
+func (w *pageWaiter) scroll(ctx context.Context) error {
+	for {
+		var atBottom bool
+		if err := chromedp.Evaluate(scrollStepJS, &atBottom).Do(ctx); err != nil {
+			return err
+		}
+		if err := chromedp.Sleep(scrollStepDelay).Do(ctx); err != nil {
+			return err
+		}
+		if atBottom {
+			break
+		}
+	}
+
+	var ignored interface{}
+	return chromedp.Evaluate(`window.scrollTo(0, 0)`, &ignored).Do(ctx)
+}
+
+// waitNetworkIdle ждет, пока число незавершенных запросов не опустится до порога на время idle
+func (w *pageWaiter) waitNetworkIdle(ctx context.Context) error {
+	ticker := time.NewTicker(readinessPollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.mu.Lock()
+		idle := len(w.inflight) <= maxIdleRequests && time.Since(w.lastActivity) >= w.idle
+		w.mu.Unlock()
+
+		if idle {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// waitDOMIdle ждет, пока DOM не будет изменяться в течение idle
+func (w *pageWaiter) waitDOMIdle(ctx context.Context) error {
+	ticker := time.NewTicker(readinessPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var sinceMutation float64
+		if err := chromedp.Evaluate(domIdleJS, &sinceMutation).Do(ctx); err != nil {
+			return err
+		}
+		if time.Duration(sinceMutation)*time.Millisecond >= w.idle {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package downloader
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+
+	"website-scraper/internal/models"
+)
+
+// responseRecorder собирает цепочку перенаправлений документа основной страницы во вкладке
+type responseRecorder struct {
+	mu        sync.Mutex
+	frameID   cdp.FrameID
+	redirects []string
+}
+
+// Listen подписывается на сетевые события вкладки. Вызывается до перехода на страницу
+func (r *responseRecorder) Listen(ctx context.Context) {
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		req, ok := ev.(*network.EventRequestWillBeSent)
+		if !ok || req.Type != network.ResourceTypeDocument {
+			return
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		// Первый запрос документа относится к основной странице, документы фреймов пропускаем
+		if r.frameID == "" {
+			r.frameID = req.FrameID
+		}
+		if req.FrameID != r.frameID || req.RedirectResponse == nil {
+			return
+		}
+		r.redirects = append(r.redirects, req.RedirectResponse.URL)
+	})
+}
+
+// Redirects возвращает адреса, с которых браузер был перенаправлен
+func (r *responseRecorder) Redirects() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.redirects...)
+}
+
+// responseMetaFromCDP переводит ответ браузера на запрос документа в метаданные операции
+func responseMetaFromCDP(resp *network.Response, redirects []string, total time.Duration) *models.ResponseMeta {
+	meta := &models.ResponseMeta{
+		StatusCode:  int(resp.Status),
+		StatusText:  resp.StatusText,
+		FinalURL:    resp.URL,
+		Redirects:   redirects,
+		ContentType: resp.MimeType,
+		Protocol:    resp.Protocol,
+		RemoteIP:    resp.RemoteIPAddress,
+		Headers:     make(map[string]string, len(resp.Headers)),
+		Timing:      &models.ResponseTiming{TotalMS: milliseconds(total)},
+	}
+	meta.IsError = isErrorStatus(meta.StatusCode)
+
+	if resp.Charset != "" {
+		meta.ContentType += "; charset=" + resp.Charset
+	}
+
+	for name, value := range resp.Headers {
+		meta.Headers[strings.ToLower(name)] = responseHeaderValue(name, fmt.Sprint(value))
+	}
+
+	if t := resp.Timing; t != nil {
+		meta.Timing.DNSMS = phase(t.DNSStart, t.DNSEnd)
+		meta.Timing.ConnectMS = phase(t.ConnectStart, t.ConnectEnd)
+		meta.Timing.TLSMS = phase(t.SslStart, t.SslEnd)
+		meta.Timing.TTFBMS = t.ReceiveHeadersEnd
+	}
+
+	if sd := resp.SecurityDetails; sd != nil {
+		meta.TLS = &models.TLSInfo{
+			Protocol: sd.Protocol,
+			Cipher:   sd.Cipher,
+			Subject:  sd.SubjectName,
+			Issuer:   sd.Issuer,
+		}
+		if sd.ValidFrom != nil {
+			meta.TLS.ValidFrom = sd.ValidFrom.Time().UTC()
+		}
+		if sd.ValidTo != nil {
+			meta.TLS.ValidTo = sd.ValidTo.Time().UTC()
+		}
+	}
+
+	return meta
+}
+
+// phase возвращает длительность этапа из ResourceTiming. Отрицательные отметки означают,
+// что этап не выполнялся, например соединение было переиспользовано
+func phase(start, end float64) float64 {
+	if start < 0 || end < 0 {
+		return 0
+	}
+	return end - start
+}
+
+// httpTimer замеряет этапы HTTP-запроса через httptrace. Обработчики соединения могут
+// вызываться из параллельных попыток подключения (Happy Eyeballs), поэтому поля защищены mu
+type httpTimer struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+}
+
+// trace возвращает контекст запроса с подключенным замером этапов
+func (t *httpTimer) trace(ctx context.Context) context.Context {
+	t.start = time.Now()
+	mark := func(field *time.Time) {
+		t.mu.Lock()
+		*field = time.Now()
+		t.mu.Unlock()
+	}
+
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { mark(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { mark(&t.dnsDone) },
+		ConnectStart:         func(string, string) { mark(&t.connectStart) },
+		ConnectDone:          func(string, string, error) { mark(&t.connectDone) },
+		TLSHandshakeStart:    func() { mark(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { mark(&t.tlsDone) },
+		GotFirstResponseByte: func() { mark(&t.firstByte) },
+	})
+}
+
+// timing возвращает длительности этапов. При перенаправлениях учитывается последний запрос
+func (t *httpTimer) timing() *models.ResponseTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	between := func(start, end time.Time) float64 {
+		if start.IsZero() || end.IsZero() {
+			return 0
+		}
+		return milliseconds(end.Sub(start))
+	}
+
+	return &models.ResponseTiming{
+		DNSMS:     between(t.dnsStart, t.dnsDone),
+		ConnectMS: between(t.connectStart, t.connectDone),
+		TLSMS:     between(t.tlsStart, t.tlsDone),
+		TTFBMS:    between(t.start, t.firstByte),
+		TotalMS:   milliseconds(time.Since(t.start)),
+	}
+}
+
+// responseMetaFromHTTP переводит HTTP-ответ в метаданные операции
+func responseMetaFromHTTP(resp *http.Response, timing *models.ResponseTiming) *models.ResponseMeta {
+	meta := &models.ResponseMeta{
+		StatusCode:  resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		IsError:     isErrorStatus(resp.StatusCode),
+		FinalURL:    resp.Request.URL.String(),
+		ContentType: resp.Header.Get("Content-Type"),
+		Protocol:    resp.Proto,
+		Headers:     make(map[string]string, len(resp.Header)),
+		Timing:      timing,
+	}
+
+	// Цепочку перенаправлений восстанавливаем от последнего запроса к первому
+	for prev := resp.Request.Response; prev != nil; prev = prev.Request.Response {
+		meta.Redirects = append([]string{prev.Request.URL.String()}, meta.Redirects...)
+	}
+
+	for name, values := range resp.Header {
+		meta.Headers[strings.ToLower(name)] = responseHeaderValue(name, strings.Join(values, ", "))
+	}
+
+	if state := resp.TLS; state != nil {
+		meta.TLS = &models.TLSInfo{
+			Protocol: tls.VersionName(state.Version),
+			Cipher:   tls.CipherSuiteName(state.CipherSuite),
+		}
+		if len(state.PeerCertificates) > 0 {
+			cert := state.PeerCertificates[0]
+			meta.TLS.Subject = cert.Subject.String()
+			meta.TLS.Issuer = cert.Issuer.String()
+			meta.TLS.ValidFrom = cert.NotBefore.UTC()
+			meta.TLS.ValidTo = cert.NotAfter.UTC()
+		}
+	}
+
+	return meta
+}
+
+// responseHeaderValue скрывает сессионные cookies, чтобы они не сохранялись в операции
+func responseHeaderValue(name, value string) string {
+	if sensitiveHeaders[strings.ToLower(name)] {
+		return redactedValue
+	}
+	return value
+}
+
+// isErrorStatus сообщает, что статус ответа вне диапазона 2xx
+func isErrorStatus(status int) bool {
+	return status < 200 || status >= 300
+}
+
+// milliseconds переводит длительность в миллисекунды с дробной частью
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
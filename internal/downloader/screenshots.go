@@ -0,0 +1,164 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/google/uuid"
+
+	"website-scraper/internal/models"
+	"website-scraper/internal/storage"
+)
+
+// blockCaptureTimeout ограничивает время снимка одного блока
+const blockCaptureTimeout = 10 * time.Second
+
+// pageScreenshotFilename задает имя снимка всей страницы среди файлов блоков операции
+const pageScreenshotFilename = "page.png"
+
+// blockVisualJS возвращает положение блока и его ключевые вычисленные стили.
+// Шрифт текста и заголовков берется с первых потомков, так как у контейнера он часто не задан явно
+const blockVisualJS = `(function(selector) {
+	const el = document.querySelector(selector);
+	if (!el) return null;
+	const rect = el.getBoundingClientRect();
+	const style = getComputedStyle(el);
+	const styles = {
+		"font-family": style.fontFamily,
+		"font-size": style.fontSize,
+		"font-weight": style.fontWeight,
+		"line-height": style.lineHeight,
+		"color": style.color,
+		"background-color": style.backgroundColor,
+		"background-image": style.backgroundImage
+	};
+	const text = el.querySelector("p, span, li, a");
+	if (text) {
+		const textStyle = getComputedStyle(text);
+		styles["text-font-family"] = textStyle.fontFamily;
+		styles["text-color"] = textStyle.color;
+	}
+	const heading = el.querySelector("h1, h2, h3, h4, h5, h6");
+	if (heading) {
+		const headingStyle = getComputedStyle(heading);
+		styles["heading-font-family"] = headingStyle.fontFamily;
+		styles["heading-font-size"] = headingStyle.fontSize;
+		styles["heading-color"] = headingStyle.color;
+	}
+	const link = el.querySelector("a[href]");
+	if (link) {
+		styles["link-color"] = getComputedStyle(link).color;
+	}
+	return {
+		x: rect.left + window.scrollX,
+		y: rect.top + window.scrollY,
+		width: rect.width,
+		height: rect.height,
+		hidden: rect.width === 0 || rect.height === 0 || style.display === "none" || style.visibility === "hidden",
+		styles: styles
+	};
+})(%s)`
+
+// blockVisualResult представляет результат выполнения blockVisualJS
+type blockVisualResult struct {
+	models.BoundingBox
+	Styles map[string]string `json:"styles"`
+	Hidden bool              `json:"hidden"`
+}
+
+// ScreenshotsEnabled сообщает, включены ли снимки страниц и блоков
+func (d *Downloader) ScreenshotsEnabled() bool {
+	return d.cfg.Downloader.Screenshots
+}
+
+// SavePageScreenshot сохраняет снимок всей страницы рядом с файлами блоков операции
+func (d *Downloader) SavePageScreenshot(ctx context.Context, operationID uuid.UUID, data []byte) (string, error) {
+	key := blockPrefix(operationID) + pageScreenshotFilename
+	if err := storage.PutBytes(ctx, d.storage, key, data, "image/png"); err != nil {
+		return "", fmt.Errorf("ошибка сохранения снимка страницы: %w", err)
+	}
+
+	return pageScreenshotFilename, nil
+}
+
+// CaptureBlocks снимает блоки во вкладке, в которой DownloadPage загрузил страницу: для каждого
+// блока по его CSS-селектору сохраняются скриншот элемента, положение на странице и вычисленные стили.
+// Блоки, которые не удалось найти или которые не видны, пропускаются
+func (d *Downloader) CaptureBlocks(ctx context.Context, page *PageResult, operationID uuid.UUID, selectors map[uuid.UUID]string) (map[uuid.UUID]*models.BlockVisual, error) {
+	if page == nil || page.tab == nil {
+		return nil, fmt.Errorf("вкладка страницы уже закрыта или страница загружена без браузера")
+	}
+
+	visuals := make(map[uuid.UUID]*models.BlockVisual)
+
+	for blockID, selector := range selectors {
+		if selector == "" {
+			continue
+		}
+		if err := page.tab.Err(); err != nil {
+			log.Printf("Время на снимки блоков истекло: %v", err)
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		visual, err := d.captureBlock(ctx, page.tab, operationID, blockID, selector)
+		if err != nil {
+			log.Printf("Ошибка снимка блока %s (%s): %v", blockID, selector, err)
+			continue
+		}
+		visuals[blockID] = visual
+	}
+
+	return visuals, nil
+}
+
+// captureBlock снимает положение, стили и скриншот одного блока во вкладке tab
+func (d *Downloader) captureBlock(ctx, tab context.Context, operationID, blockID uuid.UUID, selector string) (*models.BlockVisual, error) {
+	quoted, err := json.Marshal(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *blockVisualResult
+	if err := chromedp.Run(tab, chromedp.Evaluate(fmt.Sprintf(blockVisualJS, quoted), &result)); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("элемент не найден на странице")
+	}
+
+	visual := &models.BlockVisual{
+		Selector:    selector,
+		BoundingBox: &result.BoundingBox,
+		Styles:      result.Styles,
+		Hidden:      result.Hidden,
+	}
+
+	// Скрытые элементы снять нельзя, chromedp будет ждать их появления
+	if result.Hidden || result.Width <= 0 || result.Height <= 0 {
+		return visual, nil
+	}
+
+	captureCtx, cancel := context.WithTimeout(tab, blockCaptureTimeout)
+	defer cancel()
+
+	var screenshot []byte
+	if err := chromedp.Run(captureCtx, chromedp.Screenshot(selector, &screenshot, chromedp.ByQuery)); err != nil {
+		log.Printf("Ошибка скриншота блока %s: %v", blockID, err)
+		return visual, nil
+	}
+
+	filename := "screenshots/" + blockID.String() + ".png"
+	if err := storage.PutBytes(ctx, d.storage, blockPrefix(operationID)+filename, screenshot, "image/png"); err != nil {
+		return nil, fmt.Errorf("ошибка сохранения скриншота: %w", err)
+	}
+	visual.Screenshot = filename
+
+	return visual, nil
+}
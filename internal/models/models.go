@@ -1,6 +1,8 @@
 package models
 
 import (
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,6 +18,41 @@ const (
 	StatusError      OperationStatus = "error"
 )
 
+// FetchStrategy представляет способ загрузки страницы
+type FetchStrategy string
+
+const (
+	// FetchHTTP загружает страницу обычным HTTP-запросом без выполнения JavaScript
+	FetchHTTP FetchStrategy = "http"
+	// FetchBrowser отрисовывает страницу в headless Chrome
+	FetchBrowser FetchStrategy = "browser"
+	// FetchAuto загружает страницу по HTTP и переходит к браузеру, если страница зависит от JavaScript
+	FetchAuto FetchStrategy = "auto"
+)
+
+// OperationSource представляет источник HTML, разобранного операцией
+type OperationSource string
+
+const (
+	// SourceFetch означает, что страница загружена из сети
+	SourceFetch OperationSource = "fetch"
+	// SourceReparse означает повторный разбор сохраненного HTML другой операции
+	SourceReparse OperationSource = "reparse"
+	// SourceUpload означает разбор HTML, загруженного пользователем
+	SourceUpload OperationSource = "upload"
+)
+
+// DevicePreset представляет устройство, которое эмулируется при загрузке страницы
+type DevicePreset string
+
+const (
+	DeviceDesktop DevicePreset = "desktop"
+	DeviceTablet  DevicePreset = "tablet"
+	DeviceMobile  DevicePreset = "mobile"
+	// DeviceCustom задает размеры окна и параметры устройства явно
+	DeviceCustom DevicePreset = "custom"
+)
+
 // BlockType представляет тип блока
 type BlockType string
 
@@ -38,11 +75,100 @@ const (
 
 // Operation представляет операцию парсинга
 type Operation struct {
-	ID        uuid.UUID       `json:"id" db:"id"`
-	URL       string          `json:"url" db:"url"`
-	Status    OperationStatus `json:"status" db:"status"`
-	CreatedAt time.Time       `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time       `json:"updated_at" db:"updated_at"`
+	ID            uuid.UUID       `json:"id" db:"id"`
+	URL           string          `json:"url" db:"url"`
+	Status        OperationStatus `json:"status" db:"status"`
+	FetchStrategy FetchStrategy   `json:"fetch_strategy,omitempty" db:"fetch_strategy"`
+	Response      *ResponseMeta   `json:"response,omitempty" db:"response"`
+	Source        OperationSource `json:"source" db:"source"`
+	ParentID      *uuid.UUID      `json:"parent_id,omitempty" db:"parent_id"`
+	Device        *DeviceOptions  `json:"device,omitempty" db:"device"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// OperationSort задает порядок списка операций. Минус перед полем означает обратный порядок
+type OperationSort string
+
+const (
+	SortCreatedDesc OperationSort = "-created_at"
+	SortCreatedAsc  OperationSort = "created_at"
+	SortURLAsc      OperationSort = "url"
+	SortURLDesc     OperationSort = "-url"
+)
+
+// OperationCursor указывает на последнюю операцию предыдущей страницы списка:
+// значение поля сортировки и ID для операций с одинаковым значением
+type OperationCursor struct {
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// OperationListQuery задает фильтры, порядок и страницу списка операций. Пустые поля не ограничивают выборку
+type OperationListQuery struct {
+	Status OperationStatus
+	// URL ищется как часть адреса страницы без учета регистра
+	URL    string
+	Domain string
+	// Platform оставляет операции, в которых найдены блоки платформы
+	Platform Platform
+	// From и To ограничивают время создания операции, To не включается
+	From  *time.Time
+	To    *time.Time
+	Sort  OperationSort
+	After *OperationCursor
+	Limit int
+}
+
+// OperationSummary представляет операцию в списке вместе с числом ее блоков
+type OperationSummary struct {
+	Operation
+	BlockCount int               `json:"block_count"`
+	BlockTypes map[BlockType]int `json:"block_types"`
+	Platforms  []Platform        `json:"platforms"`
+}
+
+// OperationListResponse представляет страницу списка операций
+type OperationListResponse struct {
+	Operations []OperationSummary `json:"operations"`
+	Sort       OperationSort      `json:"sort"`
+	Limit      int                `json:"limit"`
+	// NextCursor передается в параметре cursor для получения следующей страницы, пустой на последней
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// TLSInfo представляет параметры защищенного соединения со страницей
+type TLSInfo struct {
+	Protocol  string    `json:"protocol"`
+	Cipher    string    `json:"cipher,omitempty"`
+	Subject   string    `json:"subject,omitempty"`
+	Issuer    string    `json:"issuer,omitempty"`
+	ValidFrom time.Time `json:"valid_from,omitzero"`
+	ValidTo   time.Time `json:"valid_to,omitzero"`
+}
+
+// ResponseTiming представляет время этапов загрузки документа в миллисекундах
+type ResponseTiming struct {
+	DNSMS     float64 `json:"dns_ms,omitempty"`
+	ConnectMS float64 `json:"connect_ms,omitempty"`
+	TLSMS     float64 `json:"tls_ms,omitempty"`
+	TTFBMS    float64 `json:"ttfb_ms"`  // от начала запроса до получения заголовков ответа
+	TotalMS   float64 `json:"total_ms"` // от начала загрузки до готовности страницы
+}
+
+// ResponseMeta представляет метаданные HTTP-ответа на запрос документа страницы
+type ResponseMeta struct {
+	StatusCode  int               `json:"status_code"`
+	StatusText  string            `json:"status_text,omitempty"`
+	IsError     bool              `json:"is_error"` // статус ответа вне диапазона 2xx
+	FinalURL    string            `json:"final_url"`
+	Redirects   []string          `json:"redirects,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+	Protocol    string            `json:"protocol,omitempty"`
+	RemoteIP    string            `json:"remote_ip,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	TLS         *TLSInfo          `json:"tls,omitempty"`
+	Timing      *ResponseTiming   `json:"timing,omitempty"`
 }
 
 // Block представляет блок, найденный при парсинге
@@ -53,7 +179,19 @@ type Block struct {
 	Platform    Platform    `json:"platform" db:"platform"`
 	Content     interface{} `json:"content" db:"content"`
 	HTML        string      `json:"html" db:"html"`
-	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
+	// Viewport содержит устройство, на котором страница разобрана
+	Viewport DevicePreset `json:"viewport" db:"viewport"`
+	// Fingerprint содержит simhash структуры и текста блока: у почти одинаковых блоков
+	// отпечатки различаются в нескольких битах
+	Fingerprint uint64 `json:"fingerprint,string,omitempty" db:"fingerprint"`
+	// StructureSignature содержит simhash дерева тегов и классов блока без текста и изображений:
+	// по нему находятся блоки того же шаблона или темы на других сайтах
+	StructureSignature uint64 `json:"structure_signature,string,omitempty" db:"structure_signature"`
+	// SearchText содержит видимый текст, текстовые атрибуты и токены разметки блока для полнотекстового индекса
+	SearchText string `json:"-" db:"-"`
+	// GroupID содержит группу одинаковых и почти одинаковых блоков всех операций
+	GroupID   *uuid.UUID `json:"group_id,omitempty" db:"group_id"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
 }
 
 // BlockTemplate представляет шаблон блока
@@ -77,9 +215,371 @@ type Link struct {
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 }
 
+// FormIntegration представляет тип интеграции формы
+type FormIntegration string
+
+const (
+	FormIntegrationNative        FormIntegration = "native"
+	FormIntegrationTilda         FormIntegration = "tilda"
+	FormIntegrationBitrixWebForm FormIntegration = "bitrix_webform"
+	FormIntegrationContactForm7  FormIntegration = "contact_form_7"
+	FormIntegrationAmoCRM        FormIntegration = "amocrm"
+	FormIntegrationBitrix24      FormIntegration = "bitrix24"
+)
+
+// FormField представляет поле формы
+type FormField struct {
+	Name        string   `json:"name,omitempty"`
+	Type        string   `json:"type"`
+	Label       string   `json:"label,omitempty"`
+	Placeholder string   `json:"placeholder,omitempty"`
+	Required    bool     `json:"required"`
+	Options     []string `json:"options,omitempty"`
+}
+
+// FormInfo представляет структурированное описание формы блока
+type FormInfo struct {
+	Action       string            `json:"action,omitempty"`
+	Method       string            `json:"method,omitempty"`
+	Fields       []FormField       `json:"fields"`
+	HiddenFields map[string]string `json:"hidden_fields,omitempty"`
+	SubmitText   string            `json:"submit_text,omitempty"`
+	HasCaptcha   bool              `json:"has_captcha"`
+	CaptchaType  string            `json:"captcha_type,omitempty"`
+	Integration  FormIntegration   `json:"integration"`
+}
+
+// ProductAvailability представляет наличие товара
+type ProductAvailability string
+
+const (
+	AvailabilityInStock    ProductAvailability = "in_stock"
+	AvailabilityOutOfStock ProductAvailability = "out_of_stock"
+	AvailabilityPreOrder   ProductAvailability = "preorder"
+)
+
+// Product представляет товар, найденный в блоке каталога или карточке товара
+type Product struct {
+	Name         string              `json:"name"`
+	Price        float64             `json:"price,omitempty"`
+	OldPrice     float64             `json:"old_price,omitempty"`
+	Currency     string              `json:"currency,omitempty"`
+	Image        string              `json:"image,omitempty"`
+	Link         string              `json:"link,omitempty"`
+	SKU          string              `json:"sku,omitempty"`
+	Availability ProductAvailability `json:"availability,omitempty"`
+	Source       string              `json:"source"` // "schema.org" или "dom"
+}
+
+// AssetType представляет тип ресурса блока
+type AssetType string
+
+const (
+	AssetTypeImage           AssetType = "image"
+	AssetTypeBackgroundImage AssetType = "background_image"
+	AssetTypeSVG             AssetType = "svg"
+	AssetTypeIframe          AssetType = "iframe"
+	AssetTypeVideo           AssetType = "video"
+	AssetTypeAudio           AssetType = "audio"
+	AssetTypeFont            AssetType = "font"
+	AssetTypeStylesheet      AssetType = "stylesheet"
+	AssetTypeScript          AssetType = "script"
+)
+
+// Asset представляет ресурс, на который ссылается блок
+type Asset struct {
+	Type     AssetType `json:"type"`
+	URL      string    `json:"url"`
+	Source   string    `json:"source"`   // откуда взята ссылка: img[src], srcset, style и т.д.
+	External bool      `json:"external"` // ресурс загружается с чужого домена (hotlink)
+}
+
+// BlockAssets представляет инвентаризацию ресурсов блока
+type BlockAssets struct {
+	Items        []Asset           `json:"items"`
+	Counts       map[AssetType]int `json:"counts"`
+	InlineSVG    int               `json:"inline_svg"`
+	FontFamilies []string          `json:"font_families,omitempty"`
+	External     int               `json:"external"`
+}
+
+// AssetTotals представляет сводку ресурсов по операции
+type AssetTotals struct {
+	Total         int               `json:"total"`
+	Unique        int               `json:"unique"`
+	External      int               `json:"external"`
+	InlineSVG     int               `json:"inline_svg"`
+	ByType        map[AssetType]int `json:"by_type"`
+	ExternalHosts map[string]int    `json:"external_hosts,omitempty"`
+	FontFamilies  []string          `json:"font_families,omitempty"`
+}
+
+// BoundingBox представляет положение и размер блока на странице в CSS-пикселях
+type BoundingBox struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// BlockVisual представляет внешний вид блока, отрисованного в браузере
+type BlockVisual struct {
+	Selector    string            `json:"selector"`
+	Screenshot  string            `json:"screenshot,omitempty"` // путь относительно файлов блоков операции
+	BoundingBox *BoundingBox      `json:"bounding_box,omitempty"`
+	Styles      map[string]string `json:"styles,omitempty"` // вычисленные стили: шрифты, цвета, фон
+	// Hidden означает, что блок есть в документе, но не отображается на устройстве
+	Hidden bool `json:"hidden,omitempty"`
+}
+
+// LayoutChange представляет отличие блока на другом устройстве
+type LayoutChange string
+
+const (
+	// LayoutSame означает, что блок виден на обоих устройствах в том же порядке
+	LayoutSame LayoutChange = "same"
+	// LayoutMoved означает, что блок виден на обоих устройствах, но стоит в другом месте страницы
+	LayoutMoved LayoutChange = "moved"
+	// LayoutHidden означает, что блок есть в документе, но скрыт на втором устройстве
+	LayoutHidden LayoutChange = "hidden"
+	// LayoutShown означает, что блок скрыт на первом устройстве и виден на втором
+	LayoutShown LayoutChange = "shown"
+	// LayoutMissing означает, что блок не найден на втором устройстве
+	LayoutMissing LayoutChange = "missing"
+	// LayoutAdded означает, что блок есть только на втором устройстве
+	LayoutAdded LayoutChange = "added"
+)
+
+// BlockLayoutDiff представляет сопоставление блока двух операций. Позиции считаются
+// среди видимых блоков страницы с единицы
+type BlockLayoutDiff struct {
+	BlockType     BlockType    `json:"block_type"`
+	Selector      string       `json:"selector,omitempty"`
+	BaseBlockID   *uuid.UUID   `json:"base_block_id,omitempty"`
+	OtherBlockID  *uuid.UUID   `json:"other_block_id,omitempty"`
+	BasePosition  int          `json:"base_position,omitempty"`
+	OtherPosition int          `json:"other_position,omitempty"`
+	Change        LayoutChange `json:"change"`
+}
+
+// LayoutComparison представляет сравнение верстки страницы на двух устройствах
+type LayoutComparison struct {
+	BaseOperationID  uuid.UUID            `json:"base_operation_id"`
+	OtherOperationID uuid.UUID            `json:"other_operation_id"`
+	BaseViewport     DevicePreset         `json:"base_viewport"`
+	OtherViewport    DevicePreset         `json:"other_viewport"`
+	Blocks           []BlockLayoutDiff    `json:"blocks"`
+	Summary          map[LayoutChange]int `json:"summary"`
+	// VisualsAvailable сообщает, что у блоков есть снимки; без них скрытые блоки не определяются,
+	// а порядок берется из документа
+	VisualsAvailable bool `json:"visuals_available"`
+}
+
+// BlockChangeType представляет изменение блока между двумя операциями
+type BlockChangeType string
+
+const (
+	BlockAdded    BlockChangeType = "added"
+	BlockRemoved  BlockChangeType = "removed"
+	BlockModified BlockChangeType = "modified"
+	// BlockMoved означает, что блок не изменился, но стоит на странице в другом порядке
+	BlockMoved BlockChangeType = "moved"
+)
+
+// DiffOp представляет вид строки построчного сравнения
+type DiffOp string
+
+const (
+	DiffInsert DiffOp = "insert"
+	DiffDelete DiffOp = "delete"
+)
+
+// DiffLine представляет добавленную или удаленную строку сравнения
+type DiffLine struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
+
+// BlockChange представляет изменение блока. Для измененных блоков приводятся различия
+// в тексте, в HTML и в структуре тегов
+type BlockChange struct {
+	Change       BlockChangeType `json:"change"`
+	BlockType    BlockType       `json:"block_type"`
+	TemplateName string          `json:"template_name,omitempty"`
+	OldBlockID   *uuid.UUID      `json:"old_block_id,omitempty"`
+	NewBlockID   *uuid.UUID      `json:"new_block_id,omitempty"`
+	// OldPosition и NewPosition содержат порядковый номер блока в операции, начиная с 1
+	OldPosition int `json:"old_position,omitempty"`
+	NewPosition int `json:"new_position,omitempty"`
+	// Moved отмечает измененный блок, который к тому же стоит в другом порядке
+	Moved bool `json:"moved,omitempty"`
+	// Similarity содержит сходство измененного блока с прежним от 0 до 1
+	Similarity    float64    `json:"similarity,omitempty"`
+	TextDiff      []DiffLine `json:"text_diff,omitempty"`
+	HTMLDiff      []DiffLine `json:"html_diff,omitempty"`
+	StructureDiff []DiffLine `json:"structure_diff,omitempty"`
+}
+
+// DiffSummary представляет число изменений каждого вида
+type DiffSummary struct {
+	Added     int `json:"added"`
+	Removed   int `json:"removed"`
+	Modified  int `json:"modified"`
+	Moved     int `json:"moved"`
+	Unchanged int `json:"unchanged"`
+}
+
+// HasChanges сообщает, что между операциями есть изменения
+func (s DiffSummary) HasChanges() bool {
+	return s.Added+s.Removed+s.Modified+s.Moved > 0
+}
+
+// OperationDiff представляет изменения блоков между двумя операциями
+type OperationDiff struct {
+	OldOperationID uuid.UUID     `json:"old_operation_id"`
+	NewOperationID uuid.UUID     `json:"new_operation_id"`
+	OldURL         string        `json:"old_url"`
+	NewURL         string        `json:"new_url"`
+	Summary        DiffSummary   `json:"summary"`
+	Changes        []BlockChange `json:"changes"`
+}
+
+// WaitStrategy представляет способ определения готовности страницы в браузере
+type WaitStrategy string
+
+const (
+	// WaitNetworkIdle ждет, пока на странице не останется сетевых запросов
+	WaitNetworkIdle WaitStrategy = "network_idle"
+	// WaitDOMIdle ждет, пока DOM перестанет изменяться
+	WaitDOMIdle WaitStrategy = "dom_idle"
+	// WaitSelector ждет появления элемента по CSS-селектору
+	WaitSelector WaitStrategy = "selector"
+	// WaitFixed ждет фиксированное время
+	WaitFixed WaitStrategy = "fixed"
+)
+
+// WaitOptions представляет параметры ожидания готовности страницы.
+// Незаданные поля берутся из конфигурации сервиса
+type WaitOptions struct {
+	Strategy WaitStrategy `json:"strategy,omitempty"`
+	// Selector используется стратегией selector
+	Selector string `json:"selector,omitempty"`
+	// IdleMS задает, сколько миллисекунд сеть или DOM должны оставаться без изменений;
+	// для стратегии fixed это время ожидания
+	IdleMS int `json:"idle_ms,omitempty"`
+	// AutoScroll прокручивает страницу до конца, чтобы подгрузить ленивые секции
+	AutoScroll *bool `json:"auto_scroll,omitempty"`
+	// MaxWaitMS ограничивает общее время ожидания; по его истечении страница берется как есть
+	MaxWaitMS int `json:"max_wait_ms,omitempty"`
+}
+
+// ParseOptions представляет дополнительные параметры парсинга
+type ParseOptions struct {
+	// MirrorAssets включает сохранение автономных копий блоков с локальными ресурсами
+	MirrorAssets bool `json:"mirror_assets,omitempty"`
+	// FetchStrategy задает способ загрузки страницы: http, browser или auto
+	FetchStrategy FetchStrategy `json:"fetch_strategy,omitempty"`
+	// Wait задает ожидание готовности страницы в браузере
+	Wait *WaitOptions `json:"wait,omitempty"`
+	// AllowHTTPErrors разрешает парсить страницы с ответом вне 2xx; операция помечается флагом
+	// response.is_error вместо завершения с ошибкой
+	AllowHTTPErrors bool `json:"allow_http_errors,omitempty"`
+	// RecordHAR сохраняет HAR загрузки страницы рядом с файлами блоков операции
+	RecordHAR bool `json:"record_har,omitempty"`
+	// ReplayHAR задает операцию, HAR которой воспроизводится вместо обращения к сети
+	ReplayHAR *uuid.UUID `json:"replay_har,omitempty"`
+	// Auth задает учетные данные для закрытых страниц. В БД и логи не сохраняется
+	Auth *AuthOptions `json:"auth,omitempty"`
+	// Proxy задает прокси для загрузки вместо пула из конфигурации: http://, https:// или socks5://.
+	// Значение direct отключает прокси
+	Proxy string `json:"proxy,omitempty"`
+	// Device задает эмулируемое устройство; без него страница загружается как на десктопе
+	Device *DeviceOptions `json:"device,omitempty"`
+}
+
+// DeviceOptions представляет эмулируемое устройство. Незаданные поля берутся из пресета
+type DeviceOptions struct {
+	Preset DevicePreset `json:"preset,omitempty"`
+	Width  int          `json:"width,omitempty"`
+	Height int          `json:"height,omitempty"`
+	// ScaleFactor задает плотность пикселей экрана
+	ScaleFactor float64 `json:"scale_factor,omitempty"`
+	// Mobile включает мобильный viewport и касания; для пресетов определяется пресетом
+	Mobile    bool   `json:"mobile"`
+	UserAgent string `json:"user_agent,omitempty"`
+}
+
+// AuthOptions представляет учетные данные для загрузки закрытых страниц.
+// Заголовки, cookies и basic auth передаются только сайту страницы и странице входа
+type AuthOptions struct {
+	Headers   map[string]string `json:"headers,omitempty"`
+	Cookies   []AuthCookie      `json:"cookies,omitempty"`
+	BasicAuth *BasicAuth        `json:"basic_auth,omitempty"`
+	Login     *LoginScript      `json:"login,omitempty"`
+}
+
+// AuthCookie представляет cookie, устанавливаемую перед загрузкой страницы
+type AuthCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Domain по умолчанию равен домену страницы
+	Domain   string `json:"domain,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+	HTTPOnly bool   `json:"http_only,omitempty"`
+}
+
+// BasicAuth представляет учетные данные HTTP basic auth
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LoginStepAction представляет действие шага входа
+type LoginStepAction string
+
+const (
+	// LoginFill вводит значение в поле
+	LoginFill LoginStepAction = "fill"
+	// LoginClick нажимает на элемент
+	LoginClick LoginStepAction = "click"
+	// LoginWait ждет появления элемента или заданное время
+	LoginWait LoginStepAction = "wait"
+)
+
+// LoginStep представляет шаг сценария входа
+type LoginStep struct {
+	Action   LoginStepAction `json:"action"`
+	Selector string          `json:"selector,omitempty"`
+	Value    string          `json:"value,omitempty"`
+	// TimeoutMS ограничивает ожидание элемента, для wait без селектора задает паузу
+	TimeoutMS int `json:"timeout_ms,omitempty"`
+}
+
+// LoginScript представляет сценарий входа, выполняемый в браузере перед загрузкой страницы
+type LoginScript struct {
+	// URL страницы входа, по умолчанию загружаемая страница
+	URL   string      `json:"url,omitempty"`
+	Steps []LoginStep `json:"steps"`
+}
+
 // Request/Response models
 type ParseURLRequest struct {
 	URL string `json:"url"`
+	// HTML передает страницу в теле запроса вместо загрузки по URL
+	HTML string `json:"html,omitempty"`
+	ParseOptions
+}
+
+// UploadedPage представляет страницу, переданную пользователем вместо URL:
+// HTML-файл или ZIP-архив сохраненного сайта
+type UploadedPage struct {
+	Filename string
+	Data     []byte
+	// URL задает адрес страницы для разрешения ссылок и ресурсов, необязателен
+	URL string
+	// Entry задает путь к странице внутри архива
+	Entry string
 }
 
 type ParseURLResponse struct {
@@ -87,8 +587,22 @@ type ParseURLResponse struct {
 }
 
 type GetOperationResultResponse struct {
-	Operation Operation `json:"operation"`
-	Blocks    []Block   `json:"blocks"`
+	Operation   Operation    `json:"operation"`
+	Blocks      []Block      `json:"blocks"`
+	AssetTotals *AssetTotals `json:"asset_totals,omitempty"`
+}
+
+type BlockAssetsEntry struct {
+	BlockID   uuid.UUID    `json:"block_id"`
+	BlockType BlockType    `json:"block_type"`
+	Assets    *BlockAssets `json:"assets"`
+}
+
+type OperationAssetsResponse struct {
+	OperationID uuid.UUID          `json:"operation_id"`
+	URL         string             `json:"url"`
+	Blocks      []BlockAssetsEntry `json:"blocks"`
+	Totals      *AssetTotals       `json:"totals"`
 }
 
 type ExportOperationRequest struct {
@@ -100,6 +614,279 @@ type CrawlURLRequest struct {
 	URL       string `json:"url"`
 	MaxDepth  int    `json:"max_depth,omitempty"`
 	UserAgent string `json:"user_agent,omitempty"`
+	CrawlOptions
+}
+
+// CrawlOptions представляет параметры обхода, которые передаются в краулер
+type CrawlOptions struct {
+	// Auth задает учетные данные для сайта начального URL. В логи не сохраняется
+	Auth *AuthOptions `json:"auth,omitempty"`
+	// Proxy задает прокси обхода вместо пула из конфигурации; direct отключает прокси
+	Proxy string `json:"proxy,omitempty"`
+}
+
+// ProxyStatus представляет состояние прокси из пула
+type ProxyStatus struct {
+	// Proxy содержит адрес прокси без пароля
+	Proxy     string `json:"proxy"`
+	Healthy   bool   `json:"healthy"`
+	Failures  int    `json:"failures"`
+	Requests  int64  `json:"requests"`
+	Errors    int64  `json:"errors"`
+	LastError string `json:"last_error,omitempty"`
+	// CheckedAt содержит время последней проверки доступности
+	CheckedAt time.Time `json:"checked_at,omitzero"`
+	// Domains содержит домены, закрепленные за прокси
+	Domains []string `json:"domains,omitempty"`
+}
+
+// BlockScope представляет распространенность группы блоков на страницах сайта
+type BlockScope string
+
+const (
+	// ScopeSiteWide означает блок, повторяющийся на большинстве страниц сайта, например шапку
+	ScopeSiteWide BlockScope = "site_wide"
+	// ScopeShared означает блок, повторяющийся на нескольких страницах
+	ScopeShared BlockScope = "shared"
+	// ScopePageSpecific означает блок одной страницы
+	ScopePageSpecific BlockScope = "page_specific"
+)
+
+// BlockGroupUsage представляет группу одинаковых и почти одинаковых блоков на страницах сайта
+type BlockGroupUsage struct {
+	GroupID      uuid.UUID `json:"group_id"`
+	BlockType    BlockType `json:"block_type"`
+	TemplateName string    `json:"template_name,omitempty"`
+	Fingerprint  uint64    `json:"fingerprint,string"`
+	// Pages содержит число страниц с блоком группы, Occurrences — число блоков с учетом повторных разборов
+	Pages       int `json:"pages"`
+	Occurrences int `json:"occurrences"`
+	// Variants содержит число различающихся вариантов HTML блока в группе
+	Variants      int        `json:"variants"`
+	Scope         BlockScope `json:"scope"`
+	SampleBlockID uuid.UUID  `json:"sample_block_id"`
+	PageURLs      []string   `json:"page_urls"`
+}
+
+// DuplicateReport представляет отчет о повторяющихся блоках сайта
+type DuplicateReport struct {
+	Domain string `json:"domain"`
+	Pages  int    `json:"pages"`
+	Blocks int    `json:"blocks"`
+	// DistinctBlocks содержит число групп блоков, то есть действительно разных блоков сайта
+	DistinctBlocks int `json:"distinct_blocks"`
+	// StoredHTML содержит число сохраненных вариантов HTML: одинаковые блоки хранятся один раз
+	StoredHTML int `json:"stored_html"`
+	// Ungrouped содержит блоки, сохраненные до появления отпечатков
+	Ungrouped int `json:"ungrouped"`
+	// Templates содержит число разных блоков каждого типа, которые использует сайт
+	Templates map[BlockType]int  `json:"templates"`
+	Summary   map[BlockScope]int `json:"summary"`
+	Groups    []BlockGroupUsage  `json:"groups"`
+}
+
+// SimilarBlocksQuery задает параметры поиска структурно похожих блоков
+type SimilarBlocksQuery struct {
+	// MaxDistance задает наибольшее число различающихся бит структурных подписей
+	MaxDistance int
+	Limit       int
+	// BlockType ограничивает поиск блоками одного типа
+	BlockType BlockType
+	// OtherSites исключает блоки сайта, которому принадлежит исходный блок
+	OtherSites bool
+}
+
+// SimilarBlock представляет блок, структурно похожий на исходный
+type SimilarBlock struct {
+	BlockID            uuid.UUID `json:"block_id"`
+	OperationID        uuid.UUID `json:"operation_id"`
+	URL                string    `json:"url"`
+	Domain             string    `json:"domain"`
+	BlockType          BlockType `json:"block_type"`
+	Platform           Platform  `json:"platform"`
+	TemplateName       string    `json:"template_name,omitempty"`
+	StructureSignature uint64    `json:"structure_signature,string"`
+	// Distance содержит число различающихся бит подписей, Similarity — долю совпадающих
+	Distance   int       `json:"distance"`
+	Similarity float64   `json:"similarity"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// SimilarBlocksResponse представляет результат поиска структурно похожих блоков
+type SimilarBlocksResponse struct {
+	BlockID            uuid.UUID `json:"block_id"`
+	OperationID        uuid.UUID `json:"operation_id"`
+	BlockType          BlockType `json:"block_type"`
+	StructureSignature uint64    `json:"structure_signature,string"`
+	MaxDistance        int       `json:"max_distance"`
+	// Domains содержит число найденных блоков на каждом сайте
+	Domains map[string]int `json:"domains"`
+	Blocks  []SimilarBlock `json:"blocks"`
+	Count   int            `json:"count"`
+}
+
+// BlockFilter отбирает блоки операции по типам, платформам и названиям шаблонов.
+// Пустой список не ограничивает отбор, названия шаблонов сравниваются без учета регистра
+type BlockFilter struct {
+	BlockTypes []BlockType
+	Platforms  []Platform
+	Templates  []string
+}
+
+// Matches проверяет, что блок подходит под фильтр
+func (f BlockFilter) Matches(block Block) bool {
+	if len(f.BlockTypes) > 0 && !slices.Contains(f.BlockTypes, block.BlockType) {
+		return false
+	}
+	if len(f.Platforms) > 0 && !slices.Contains(f.Platforms, block.Platform) {
+		return false
+	}
+	if len(f.Templates) > 0 {
+		content, _ := block.Content.(map[string]interface{})
+		templateName, _ := content["template_name"].(string)
+		if !slices.ContainsFunc(f.Templates, func(name string) bool {
+			return strings.EqualFold(name, templateName)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// BlockSearchQuery задает фильтры поиска блоков по всем операциям. Пустые поля не ограничивают поиск
+type BlockSearchQuery struct {
+	// Query содержит поисковый запрос в синтаксисе websearch: слова, "фразы", or и -исключения
+	Query     string
+	Platform  Platform
+	BlockType BlockType
+	// TemplateName ищется как часть названия шаблона без учета регистра
+	TemplateName string
+	Domain       string
+	// From и To ограничивают время сохранения блока, To не включается
+	From   *time.Time
+	To     *time.Time
+	Limit  int
+	Offset int
+}
+
+// BlockSearchResult представляет блок, найденный поиском
+type BlockSearchResult struct {
+	BlockID      uuid.UUID `json:"block_id"`
+	OperationID  uuid.UUID `json:"operation_id"`
+	URL          string    `json:"url"`
+	Domain       string    `json:"domain"`
+	BlockType    BlockType `json:"block_type"`
+	Platform     Platform  `json:"platform"`
+	TemplateName string    `json:"template_name,omitempty"`
+	// Rank содержит релевантность блока поисковому запросу
+	Rank      float64   `json:"rank,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BlockSearchResponse представляет страницу результатов поиска блоков
+type BlockSearchResponse struct {
+	Blocks []BlockSearchResult `json:"blocks"`
+	// Total содержит число блоков, подходящих под фильтры, на всех страницах
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// PurgeReason представляет причину удаления операции
+type PurgeReason string
+
+const (
+	// PurgeManual означает удаление операции по запросу
+	PurgeManual PurgeReason = "manual"
+	// PurgeMaxAge означает операцию старше срока хранения
+	PurgeMaxAge PurgeReason = "max_age"
+	// PurgeMaxDisk означает самую старую операцию при превышении места в хранилище
+	PurgeMaxDisk PurgeReason = "max_disk"
+)
+
+// PurgedOperation представляет удаленную или намеченную к удалению операцию
+type PurgedOperation struct {
+	OperationID uuid.UUID       `json:"operation_id"`
+	URL         string          `json:"url"`
+	Status      OperationStatus `json:"status"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Reason      PurgeReason     `json:"reason"`
+	Blocks      int             `json:"blocks"`
+	// Files и Bytes содержат файлы операции в хранилище и занятое ими место
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+// RetentionReport представляет результат применения политики хранения. В пробном запуске
+// операции только перечисляются, ничего не удаляется
+type RetentionReport struct {
+	DryRun bool `json:"dry_run"`
+	// MaxAge и MaxDiskBytes содержат действующие ограничения, пустые значения не ограничивают хранение
+	MaxAge       string `json:"max_age,omitempty"`
+	MaxDiskBytes int64  `json:"max_disk_bytes,omitempty"`
+	// DiskBytes содержит место, занятое файлами до очистки, DiskBytesAfter — после нее.
+	// Считаются только при ограничении места
+	DiskBytes      int64             `json:"disk_bytes,omitempty"`
+	DiskBytesAfter int64             `json:"disk_bytes_after,omitempty"`
+	Operations     []PurgedOperation `json:"operations"`
+	Blocks         int               `json:"blocks"`
+	Files          int               `json:"files"`
+	Bytes          int64             `json:"bytes"`
+	// Kept содержит подходящие под политику операции, которые не удаляются: незавершенные
+	// и последние проверки мониторов, с которыми сравнивается следующая проверка
+	Kept       int       `json:"kept"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// Monitor представляет периодическую проверку страницы на изменения
+type Monitor struct {
+	ID  uuid.UUID `json:"id" db:"id"`
+	URL string    `json:"url" db:"url"`
+	// Schedule задает расписание в формате cron из пяти полей или @every <интервал>
+	Schedule string       `json:"schedule" db:"schedule"`
+	Options  ParseOptions `json:"options" db:"options"`
+	Enabled  bool         `json:"enabled" db:"enabled"`
+	// LastOperationID содержит последнюю успешную операцию, с которой сравнивается следующая
+	LastOperationID *uuid.UUID `json:"last_operation_id,omitempty" db:"last_operation_id"`
+	LastRunAt       *time.Time `json:"last_run_at,omitempty" db:"last_run_at"`
+	LastChangedAt   *time.Time `json:"last_changed_at,omitempty" db:"last_changed_at"`
+	NextRunAt       *time.Time `json:"next_run_at,omitempty" db:"next_run_at"`
+	LastError       string     `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// MonitorChange представляет изменения страницы, найденные при проверке монитора
+type MonitorChange struct {
+	ID                  uuid.UUID     `json:"id" db:"id"`
+	MonitorID           uuid.UUID     `json:"monitor_id" db:"monitor_id"`
+	OperationID         *uuid.UUID    `json:"operation_id,omitempty" db:"operation_id"`
+	PreviousOperationID *uuid.UUID    `json:"previous_operation_id,omitempty" db:"previous_operation_id"`
+	Summary             DiffSummary   `json:"summary" db:"summary"`
+	Changes             []BlockChange `json:"changes" db:"changes"`
+	CreatedAt           time.Time     `json:"created_at" db:"created_at"`
+}
+
+type CreateMonitorRequest struct {
+	URL      string       `json:"url"`
+	Schedule string       `json:"schedule"`
+	Enabled  *bool        `json:"enabled,omitempty"`
+	Options  ParseOptions `json:"options"`
+}
+
+type UpdateMonitorRequest struct {
+	Schedule *string       `json:"schedule,omitempty"`
+	Enabled  *bool         `json:"enabled,omitempty"`
+	Options  *ParseOptions `json:"options,omitempty"`
+}
+
+// DownloadLink представляет ссылку на скачивание файла из хранилища
+type DownloadLink struct {
+	URL       string    `json:"url"`
+	Key       string    `json:"key"`
+	Filename  string    `json:"filename"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 type ErrorResponse struct {
@@ -0,0 +1,217 @@
+package monitor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule вычисляет время следующей проверки монитора
+type Schedule interface {
+	// Next возвращает первое время проверки после after
+	Next(after time.Time) time.Time
+	// MinInterval возвращает наименьший промежуток между проверками
+	MinInterval() time.Duration
+}
+
+// everySchedule повторяет проверку через фиксированный промежуток
+type everySchedule struct {
+	interval time.Duration
+}
+
+func (s everySchedule) Next(after time.Time) time.Time {
+	return after.Add(s.interval)
+}
+
+func (s everySchedule) MinInterval() time.Duration {
+	return s.interval
+}
+
+// cronSchedule задает проверки в формате cron: минута, час, день месяца, месяц, день недели
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny и dowAny отмечают поля дня, начинающиеся со звездочки (* или */2). Если ограничены
+	// оба поля, как и в cron, подходит день, совпавший хотя бы с одним из них
+	domAny, dowAny bool
+}
+
+// cronField описывает допустимые значения поля cron
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{name: "минута", min: 0, max: 59},
+	{name: "час", min: 0, max: 23},
+	{name: "день месяца", min: 1, max: 31},
+	{name: "месяц", min: 1, max: 12},
+	{name: "день недели", min: 0, max: 7},
+}
+
+// cronAliases содержит сокращенные расписания
+var cronAliases = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// ParseSchedule разбирает расписание монитора: "@every 30m", "@hourly", "@daily", "@weekly",
+// "@monthly" или выражение cron из пяти полей со списками, диапазонами и шагом, например "*/15 9-18 * * 1-5".
+// Время cron считается в UTC
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("некорректный интервал расписания: %s", rest)
+		}
+		return everySchedule{interval: interval}, nil
+	}
+
+	if alias, ok := cronAliases[spec]; ok {
+		spec = alias
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("расписание должно содержать 5 полей cron или @every <интервал>: %q", spec)
+	}
+
+	values := make([]uint64, len(fields))
+	for i, field := range fields {
+		bits, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		values[i] = bits
+	}
+
+	// Воскресенье можно указать как 0 или 7
+	if values[4]&(1<<7) != 0 {
+		values[4] = values[4]&^(1<<7) | 1
+	}
+
+	return &cronSchedule{
+		minute: values[0],
+		hour:   values[1],
+		dom:    values[2],
+		month:  values[3],
+		dow:    values[4],
+		domAny: strings.HasPrefix(fields[2], "*"),
+		dowAny: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parseCronField разбирает поле cron в битовую маску допустимых значений
+func parseCronField(field string, spec cronField) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("некорректный шаг в поле «%s»: %s", spec.name, part)
+			}
+		}
+
+		low, high := spec.min, spec.max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+
+			var err error
+			if low, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("некорректное значение в поле «%s»: %s", spec.name, part)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("некорректный диапазон в поле «%s»: %s", spec.name, part)
+				}
+			} else if hasStep {
+				// "5/15" означает каждые 15 начиная с 5
+				high = spec.max
+			}
+		}
+
+		if low < spec.min || high > spec.max || low > high {
+			return 0, fmt.Errorf("значение поля «%s» должно быть от %d до %d: %s", spec.name, spec.min, spec.max, part)
+		}
+
+		for value := low; value <= high; value += step {
+			bits |= 1 << value
+		}
+	}
+
+	return bits, nil
+}
+
+// maxCronSearch ограничивает поиск следующего времени для расписаний, которые никогда не срабатывают,
+// например 30 февраля
+const maxCronSearch = 5 * 366 * 24 * time.Hour
+
+// Next возвращает первое время проверки после after. Для невыполнимого расписания возвращается нулевое время
+func (s *cronSchedule) Next(after time.Time) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxCronSearch)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// dayMatches проверяет день месяца и день недели
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// MinInterval возвращает наименьший промежуток между соседними проверками среди первых
+// срабатываний расписания. Для невыполнимого расписания возвращается 0
+func (s *cronSchedule) MinInterval() time.Duration {
+	var minimal time.Duration
+
+	t := s.Next(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC).Add(-time.Minute))
+	for i := 0; i < 24*60 && !t.IsZero(); i++ {
+		next := s.Next(t)
+		if next.IsZero() {
+			break
+		}
+		if interval := next.Sub(t); minimal == 0 || interval < minimal {
+			minimal = interval
+		}
+		t = next
+	}
+
+	return minimal
+}
@@ -0,0 +1,112 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-a * * * *",
+		"@every",
+		"@every -1m",
+		"@every abc",
+		"@yearly",
+	}
+
+	for _, spec := range tests {
+		t.Run(spec, func(t *testing.T) {
+			if _, err := ParseSchedule(spec); err == nil {
+				t.Errorf("ParseSchedule(%q) = nil error, want error", spec)
+			}
+		})
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// 1 января 2024 года — понедельник
+	tests := []struct {
+		spec  string
+		after time.Time
+		want  time.Time
+	}{
+		{"@every 30m", date(2024, 1, 1, 10, 0), date(2024, 1, 1, 10, 30)},
+		{"*/15 * * * *", date(2024, 1, 1, 10, 7), date(2024, 1, 1, 10, 15)},
+		{"*/15 * * * *", date(2024, 1, 1, 23, 50), date(2024, 1, 2, 0, 0)},
+		{"5/15 * * * *", date(2024, 1, 1, 10, 6), date(2024, 1, 1, 10, 20)},
+		{"0,30 * * * *", date(2024, 1, 1, 10, 0), date(2024, 1, 1, 10, 30)},
+		{"30 14 * * *", date(2024, 1, 1, 14, 30), date(2024, 1, 2, 14, 30)},
+		{"0 9 * * 1-5", date(2024, 1, 5, 10, 0), date(2024, 1, 8, 9, 0)},
+		{"@hourly", date(2024, 1, 1, 10, 59), date(2024, 1, 1, 11, 0)},
+		{"@daily", date(2024, 12, 31, 12, 0), date(2025, 1, 1, 0, 0)},
+		{"@weekly", date(2024, 1, 1, 0, 0), date(2024, 1, 7, 0, 0)},
+		{"@monthly", date(2024, 1, 15, 0, 0), date(2024, 2, 1, 0, 0)},
+		// Воскресенье можно задать как 7
+		{"0 12 * * 7", date(2024, 1, 1, 0, 0), date(2024, 1, 7, 12, 0)},
+		// Оба поля дня ограничены: подходит 13-е число или пятница
+		{"0 0 13 * 5", date(2024, 1, 1, 0, 0), date(2024, 1, 5, 0, 0)},
+		{"0 0 13 * 5", date(2024, 1, 12, 0, 0), date(2024, 1, 13, 0, 0)},
+		// Поле со звездочкой и шагом не ограничивает день: нужен нечетный понедельник
+		{"0 0 */2 * 1", date(2024, 1, 1, 0, 0), date(2024, 1, 15, 0, 0)},
+		{"0 0 * * */2", date(2024, 1, 1, 0, 0), date(2024, 1, 2, 0, 0)},
+		{"0 0 29 2 *", date(2024, 3, 1, 0, 0), date(2028, 2, 29, 0, 0)},
+		// Невыполнимое расписание
+		{"0 0 30 2 *", date(2024, 1, 1, 0, 0), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec+" after "+tt.after.Format(time.RFC3339), func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseSchedule(%q): %v", tt.spec, err)
+			}
+			if got := schedule.Next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleMinInterval(t *testing.T) {
+	tests := []struct {
+		spec string
+		want time.Duration
+	}{
+		{"@every 6h", 6 * time.Hour},
+		{"* * * * *", time.Minute},
+		{"*/15 * * * *", 15 * time.Minute},
+		{"0,30 * * * *", 30 * time.Minute},
+		{"0,50 * * * *", 10 * time.Minute},
+		{"0 9-18 * * *", time.Hour},
+		{"@daily", 24 * time.Hour},
+		{"@weekly", 7 * 24 * time.Hour},
+		{"0 0 30 2 *", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseSchedule(%q): %v", tt.spec, err)
+			}
+			if got := schedule.MinInterval(); got != tt.want {
+				t.Errorf("MinInterval() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
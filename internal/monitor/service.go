@@ -0,0 +1,460 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/fx"
+
+	"website-scraper/internal/config"
+	"website-scraper/internal/downloader"
+	"website-scraper/internal/models"
+	"website-scraper/internal/parser"
+	"website-scraper/internal/proxy"
+	"website-scraper/internal/repo"
+)
+
+// operationPollInterval задает период проверки статуса операции разбора
+const operationPollInterval = 2 * time.Second
+
+// saveTimeout ограничивает сохранение результата проверки. Результат сохраняется и после
+// остановки сервиса, чтобы завершенная проверка не потеряла новую точку отсчета
+const saveTimeout = 10 * time.Second
+
+var (
+	// ErrInvalidMonitor возвращается для некорректного адреса, расписания или параметров монитора
+	ErrInvalidMonitor = errors.New("некорректный монитор")
+	// ErrMonitorRunning возвращается при запуске проверки, которая уже выполняется
+	ErrMonitorRunning = errors.New("проверка монитора уже выполняется")
+)
+
+// MonitorService интерфейс для сервиса мониторов страниц
+type MonitorService interface {
+	// CreateMonitor создает монитор. Первая проверка выполняется сразу и служит точкой отсчета
+	CreateMonitor(ctx context.Context, req models.CreateMonitorRequest) (*models.Monitor, error)
+	// GetMonitor получает монитор по ID
+	GetMonitor(ctx context.Context, monitorID uuid.UUID) (*models.Monitor, error)
+	// ListMonitors получает все мониторы
+	ListMonitors(ctx context.Context) ([]models.Monitor, error)
+	// UpdateMonitor изменяет расписание, параметры разбора или включенность монитора
+	UpdateMonitor(ctx context.Context, monitorID uuid.UUID, req models.UpdateMonitorRequest) (*models.Monitor, error)
+	// DeleteMonitor удаляет монитор и историю его изменений
+	DeleteMonitor(ctx context.Context, monitorID uuid.UUID) error
+	// RunMonitor запускает внеочередную проверку монитора
+	RunMonitor(ctx context.Context, monitorID uuid.UUID) error
+	// GetMonitorChanges получает историю изменений монитора, начиная с последних
+	GetMonitorChanges(ctx context.Context, monitorID uuid.UUID, limit int) ([]models.MonitorChange, error)
+}
+
+// monitorService реализация MonitorService. Планировщик периодически выбирает мониторы,
+// время проверки которых наступило, заново разбирает страницу и сравнивает блоки с прошлой проверкой
+type monitorService struct {
+	cfg        *config.Config
+	repo       repo.MonitorRepo
+	operations repo.ParserRepo
+	parser     parser.ParserService
+
+	// running содержит мониторы, проверка которых выполняется
+	mu      sync.Mutex
+	running map[uuid.UUID]bool
+	slots   chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+// NewMonitorService создает новый экземпляр MonitorService
+func NewMonitorService(cfg *config.Config, monitors repo.MonitorRepo, operations repo.ParserRepo, parserService parser.ParserService) *monitorService {
+	concurrency := cfg.Monitor.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &monitorService{
+		cfg:        cfg,
+		repo:       monitors,
+		operations: operations,
+		parser:     parserService,
+		running:    make(map[uuid.UUID]bool),
+		slots:      make(chan struct{}, concurrency),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// CreateMonitor создает монитор
+func (s *monitorService) CreateMonitor(ctx context.Context, req models.CreateMonitorRequest) (*models.Monitor, error) {
+	monitor := &models.Monitor{
+		URL:      req.URL,
+		Schedule: req.Schedule,
+		Options:  req.Options,
+		Enabled:  req.Enabled == nil || *req.Enabled,
+	}
+
+	if err := s.validate(monitor); err != nil {
+		return nil, err
+	}
+
+	if monitor.Enabled {
+		now := time.Now()
+		monitor.NextRunAt = &now
+	}
+
+	if err := s.repo.CreateMonitor(ctx, monitor); err != nil {
+		return nil, err
+	}
+	return monitor, nil
+}
+
+// GetMonitor получает монитор по ID
+func (s *monitorService) GetMonitor(ctx context.Context, monitorID uuid.UUID) (*models.Monitor, error) {
+	return s.repo.GetMonitorByID(ctx, monitorID)
+}
+
+// ListMonitors получает все мониторы
+func (s *monitorService) ListMonitors(ctx context.Context) ([]models.Monitor, error) {
+	return s.repo.GetAllMonitors(ctx)
+}
+
+// UpdateMonitor изменяет монитор. При смене расписания или включении следующая проверка
+// назначается по новому расписанию
+func (s *monitorService) UpdateMonitor(ctx context.Context, monitorID uuid.UUID, req models.UpdateMonitorRequest) (*models.Monitor, error) {
+	monitor, err := s.repo.GetMonitorByID(ctx, monitorID)
+	if err != nil {
+		return nil, err
+	}
+
+	reschedule := false
+	if req.Schedule != nil && *req.Schedule != monitor.Schedule {
+		monitor.Schedule = *req.Schedule
+		reschedule = true
+	}
+	if req.Enabled != nil && *req.Enabled != monitor.Enabled {
+		monitor.Enabled = *req.Enabled
+		reschedule = true
+	}
+	if req.Options != nil {
+		monitor.Options = *req.Options
+	}
+
+	if err := s.validate(monitor); err != nil {
+		return nil, err
+	}
+
+	if reschedule {
+		monitor.NextRunAt = nil
+		if monitor.Enabled {
+			schedule, _ := ParseSchedule(monitor.Schedule)
+			next := schedule.Next(time.Now())
+			monitor.NextRunAt = &next
+		}
+	}
+
+	if err := s.repo.UpdateMonitor(ctx, monitor); err != nil {
+		return nil, err
+	}
+	return monitor, nil
+}
+
+// DeleteMonitor удаляет монитор. Уже запущенная проверка завершится, но ее результат не сохранится
+func (s *monitorService) DeleteMonitor(ctx context.Context, monitorID uuid.UUID) error {
+	return s.repo.DeleteMonitor(ctx, monitorID)
+}
+
+// RunMonitor запускает внеочередную проверку, не меняя время следующей плановой
+func (s *monitorService) RunMonitor(ctx context.Context, monitorID uuid.UUID) error {
+	monitor, err := s.repo.GetMonitorByID(ctx, monitorID)
+	if err != nil {
+		return err
+	}
+
+	if !s.start(*monitor) {
+		return ErrMonitorRunning
+	}
+	return nil
+}
+
+// GetMonitorChanges получает историю изменений монитора
+func (s *monitorService) GetMonitorChanges(ctx context.Context, monitorID uuid.UUID, limit int) ([]models.MonitorChange, error) {
+	if _, err := s.repo.GetMonitorByID(ctx, monitorID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetMonitorChanges(ctx, monitorID, limit)
+}
+
+// validate проверяет адрес, расписание и параметры разбора монитора и дополняет устройство
+// значениями пресета
+func (s *monitorService) validate(monitor *models.Monitor) error {
+	parsed, err := url.Parse(monitor.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: нужен адрес страницы http:// или https://", ErrInvalidMonitor)
+	}
+	if parsed.User != nil {
+		return fmt.Errorf("%w: учетные данные в адресе не сохраняются в мониторе", ErrInvalidMonitor)
+	}
+
+	schedule, err := ParseSchedule(monitor.Schedule)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMonitor, err)
+	}
+	interval := schedule.MinInterval()
+	if interval == 0 {
+		return fmt.Errorf("%w: расписание никогда не срабатывает", ErrInvalidMonitor)
+	}
+	if interval < s.cfg.Monitor.MinInterval {
+		return fmt.Errorf("%w: проверки не чаще одного раза в %s", ErrInvalidMonitor, s.cfg.Monitor.MinInterval)
+	}
+
+	opts := &monitor.Options
+	// Параметры монитора хранятся в БД, поэтому секреты в них не принимаются
+	if opts.Auth != nil {
+		return fmt.Errorf("%w: учетные данные не поддерживаются в мониторах", ErrInvalidMonitor)
+	}
+	if opts.ReplayHAR != nil {
+		return fmt.Errorf("%w: монитор загружает страницу из сети, replay_har не поддерживается", ErrInvalidMonitor)
+	}
+	if err := proxy.Validate(opts.Proxy); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMonitor, err)
+	}
+	if proxied, _ := proxy.Parse(opts.Proxy); proxied != nil {
+		if _, _, ok := proxied.Credentials(); ok {
+			return fmt.Errorf("%w: прокси с паролем не сохраняется в мониторе, используйте пул прокси", ErrInvalidMonitor)
+		}
+	}
+	if opts.FetchStrategy != "" && !downloader.ValidFetchStrategy(opts.FetchStrategy) {
+		return fmt.Errorf("%w: неизвестный способ загрузки %s", ErrInvalidMonitor, opts.FetchStrategy)
+	}
+	if err := downloader.ValidWaitOptions(opts.Wait); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMonitor, err)
+	}
+	if opts.Device, err = downloader.ResolveDevice(opts.Device); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMonitor, err)
+	}
+
+	return nil
+}
+
+// Start запускает планировщик проверок
+func (s *monitorService) Start() {
+	interval := s.cfg.Monitor.PollInterval
+	if interval <= 0 {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.dispatchDue()
+
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close останавливает планировщик и дожидается завершения проверок
+func (s *monitorService) Close() {
+	s.once.Do(func() {
+		s.cancel()
+		s.wg.Wait()
+	})
+}
+
+// dispatchDue запускает проверки мониторов, время которых наступило. Следующая проверка
+// назначается до запуска, чтобы долгая проверка не запускалась повторно
+func (s *monitorService) dispatchDue() {
+	now := time.Now()
+
+	monitors, err := s.repo.GetDueMonitors(s.ctx, now)
+	if err != nil {
+		if s.ctx.Err() == nil {
+			log.Printf("Error getting due monitors: %v", err)
+		}
+		return
+	}
+
+	for _, monitor := range monitors {
+		schedule, err := ParseSchedule(monitor.Schedule)
+		var next *time.Time
+		if err == nil {
+			if t := schedule.Next(now); !t.IsZero() {
+				next = &t
+			}
+		}
+
+		if err := s.repo.ScheduleMonitor(s.ctx, monitor.ID, next); err != nil {
+			log.Printf("Error scheduling monitor %s: %v", monitor.ID, err)
+			continue
+		}
+
+		s.start(monitor)
+	}
+}
+
+// start запускает проверку монитора в отдельной горутине, если она еще не выполняется
+func (s *monitorService) start(monitor models.Monitor) bool {
+	s.mu.Lock()
+	if s.running[monitor.ID] {
+		s.mu.Unlock()
+		return false
+	}
+	s.running[monitor.ID] = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, monitor.ID)
+			s.mu.Unlock()
+		}()
+
+		select {
+		case s.slots <- struct{}{}:
+			defer func() { <-s.slots }()
+		case <-s.ctx.Done():
+			return
+		}
+
+		s.run(&monitor)
+	}()
+
+	return true
+}
+
+// run разбирает страницу монитора, сравнивает блоки с последней успешной проверкой
+// и сохраняет изменения, если они есть
+func (s *monitorService) run(monitor *models.Monitor) {
+	operationID, err := s.parseAndWait(monitor)
+
+	// Сравнение и сохранение не прерываются остановкой сервиса
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(s.ctx), saveTimeout)
+	defer cancel()
+
+	now := time.Now()
+	monitor.LastRunAt = &now
+	if err != nil {
+		log.Printf("Monitor %s check failed: %v", monitor.ID, err)
+		monitor.LastError = err.Error()
+		s.saveRun(ctx, monitor)
+		return
+	}
+
+	monitor.LastError = ""
+	if previousID := monitor.LastOperationID; previousID != nil {
+		if err := s.saveChanges(ctx, monitor, *previousID, operationID); err != nil {
+			// Прошлая операция могла быть удалена; новая все равно становится точкой отсчета
+			log.Printf("Monitor %s diff failed: %v", monitor.ID, err)
+			monitor.LastError = "ошибка сравнения с прошлой проверкой: " + err.Error()
+		}
+	}
+
+	monitor.LastOperationID = &operationID
+	s.saveRun(ctx, monitor)
+}
+
+// parseAndWait запускает разбор страницы и ждет завершения операции
+func (s *monitorService) parseAndWait(monitor *models.Monitor) (uuid.UUID, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, s.cfg.Monitor.RunTimeout)
+	defer cancel()
+
+	operationID, err := s.parser.ParseURL(ctx, monitor.URL, monitor.Options)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("ошибка запуска разбора: %w", err)
+	}
+
+	ticker := time.NewTicker(operationPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return uuid.Nil, fmt.Errorf("операция %s не завершилась: %w", operationID, ctx.Err())
+		case <-ticker.C:
+		}
+
+		operation, err := s.operations.GetOperationByID(ctx, operationID)
+		if err != nil {
+			continue
+		}
+
+		switch operation.Status {
+		case models.StatusCompleted:
+			return operationID, nil
+		case models.StatusError:
+			return uuid.Nil, fmt.Errorf("операция %s завершилась с ошибкой", operationID)
+		}
+	}
+}
+
+// saveChanges сравнивает операции и сохраняет изменения в историю монитора
+func (s *monitorService) saveChanges(ctx context.Context, monitor *models.Monitor, previousID, operationID uuid.UUID) error {
+	diff, err := s.parser.DiffOperations(ctx, previousID, operationID)
+	if err != nil {
+		return err
+	}
+	if !diff.Summary.HasChanges() {
+		return nil
+	}
+
+	change := &models.MonitorChange{
+		MonitorID:           monitor.ID,
+		OperationID:         &operationID,
+		PreviousOperationID: &previousID,
+		Summary:             diff.Summary,
+		Changes:             diff.Changes,
+	}
+	if err := s.repo.SaveMonitorChange(ctx, change); err != nil {
+		return err
+	}
+
+	monitor.LastChangedAt = &change.CreatedAt
+	return nil
+}
+
+// saveRun сохраняет результат проверки
+func (s *monitorService) saveRun(ctx context.Context, monitor *models.Monitor) {
+	if err := s.repo.SaveMonitorRun(ctx, monitor); err != nil {
+		log.Printf("Error saving monitor %s run: %v", monitor.ID, err)
+	}
+}
+
+// Module регистрирует сервис мониторов и планировщик проверок
+var Module = fx.Module("monitor",
+	fx.Provide(
+		NewMonitorService,
+		func(service *monitorService) MonitorService {
+			return service
+		},
+	),
+	fx.Invoke(func(lc fx.Lifecycle, service *monitorService) {
+		lc.Append(fx.Hook{
+			OnStart: func(ctx context.Context) error {
+				service.Start()
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				service.Close()
+				return nil
+			},
+		})
+	}),
+)
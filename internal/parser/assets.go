@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"website-scraper/internal/assets"
+	"website-scraper/internal/models"
+)
+
+// attachAssets добавляет в контент блока инвентаризацию его ресурсов
+func attachAssets(block *models.Block, pageURL string) {
+	setContentField(block, "assets", assets.Extract(block.HTML, pageURL))
+}
+
+// blockAssets извлекает инвентаризацию ресурсов из контента блока
+func blockAssets(block models.Block) *models.BlockAssets {
+	var blockAssets models.BlockAssets
+	if !decodeContentField(block.Content, "assets", &blockAssets) {
+		return nil
+	}
+	return &blockAssets
+}
+
+// operationBlockAssets возвращает ресурсы каждого блока операции.
+// Блоки, сохраненные до появления инвентаризации, разбираются на лету
+func operationBlockAssets(blocks []models.Block, pageURL string) []*models.BlockAssets {
+	items := make([]*models.BlockAssets, 0, len(blocks))
+	for _, block := range blocks {
+		blockAssets := blockAssets(block)
+		if blockAssets == nil {
+			blockAssets = assets.Extract(block.HTML, pageURL)
+		}
+		items = append(items, blockAssets)
+	}
+	return items
+}
+
+// GetOperationAssets возвращает ресурсы всех блоков операции и сводку по ним
+func (s *parserService) GetOperationAssets(ctx context.Context, operationID uuid.UUID) (*models.OperationAssetsResponse, error) {
+	operation, err := s.repo.GetOperationByID(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := s.repo.GetBlocksByOperationID(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := operationBlockAssets(blocks, operation.URL)
+
+	response := &models.OperationAssetsResponse{
+		OperationID: operation.ID,
+		URL:         operation.URL,
+		Blocks:      make([]models.BlockAssetsEntry, 0, len(blocks)),
+		Totals:      assets.Totals(items),
+	}
+
+	for i, block := range blocks {
+		response.Blocks = append(response.Blocks, models.BlockAssetsEntry{
+			BlockID:   block.ID,
+			BlockType: block.BlockType,
+			Assets:    items[i],
+		})
+	}
+
+	return response, nil
+}
@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"website-scraper/internal/repo"
+)
+
+// backfillBatchSize задает число блоков, обрабатываемых за один запрос к БД
+const backfillBatchSize = 200
+
+// backfillService дописывает блокам, сохраненным до появления отпечатков, структурных подписей
+// или полнотекстового индекса, вычисляемые при сохранении значения. Работает в фоне один раз после запуска сервиса
+type backfillService struct {
+	repo repo.ParserRepo
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+// newBackfillService создает фоновое заполнение блоков
+func newBackfillService(repo repo.ParserRepo) *backfillService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &backfillService{
+		repo:   repo,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start запускает заполнение блоков в фоне
+func (s *backfillService) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run()
+	}()
+}
+
+// Close прерывает заполнение и дожидается его остановки
+func (s *backfillService) Close() {
+	s.once.Do(func() {
+		s.cancel()
+		s.wg.Wait()
+	})
+}
+
+// run обходит блоки по возрастанию ID. Блок, который не удалось обработать, пропускается
+// до следующего запуска сервиса
+func (s *backfillService) run() {
+	after := uuid.Nil
+	updated := 0
+
+	for s.ctx.Err() == nil {
+		blocks, err := s.repo.GetBlocksToBackfill(s.ctx, after, backfillBatchSize)
+		if err != nil {
+			if s.ctx.Err() == nil {
+				log.Printf("Error getting blocks to backfill: %v", err)
+			}
+			return
+		}
+		if len(blocks) == 0 {
+			break
+		}
+
+		for i := range blocks {
+			block := &blocks[i]
+			after = block.ID
+
+			block.Fingerprint = blockFingerprint(block.HTML)
+			block.StructureSignature = structureSignature(block.HTML)
+			block.SearchText = blockSearchText(block.HTML)
+
+			if err := s.repo.BackfillBlock(s.ctx, block); err != nil {
+				if s.ctx.Err() != nil {
+					return
+				}
+				log.Printf("Error backfilling block %s: %v", block.ID, err)
+				continue
+			}
+			updated++
+		}
+	}
+
+	if updated > 0 {
+		log.Printf("Backfilled fingerprints, structure signatures and search index of %d blocks", updated)
+	}
+}
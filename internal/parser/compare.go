@@ -0,0 +1,270 @@
+package parser
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/google/uuid"
+
+	"website-scraper/internal/models"
+)
+
+// compareTextLength ограничивает длину текста блока, по которому блоки сопоставляются
+const compareTextLength = 300
+
+// layoutBlock представляет блок операции, подготовленный к сравнению верстки
+type layoutBlock struct {
+	block    models.Block
+	selector string
+	text     string
+	hidden   bool
+	visual   *models.BoundingBox
+	// position содержит место среди видимых блоков страницы, 0 для скрытых
+	position int
+	match    *layoutBlock
+}
+
+// CompareLayouts сравнивает блоки двух операций одной страницы, например десктопной и мобильной
+// версии: какие блоки скрыты, пропали, добавлены или стоят в другом порядке
+func (s *parserService) CompareLayouts(ctx context.Context, baseID, otherID uuid.UUID) (*models.LayoutComparison, error) {
+	base, err := s.repo.GetOperationByID(ctx, baseID)
+	if err != nil {
+		return nil, err
+	}
+	other, err := s.repo.GetOperationByID(ctx, otherID)
+	if err != nil {
+		return nil, err
+	}
+
+	baseBlocks, err := s.repo.GetBlocksByOperationID(ctx, base.ID)
+	if err != nil {
+		return nil, err
+	}
+	otherBlocks, err := s.repo.GetBlocksByOperationID(ctx, other.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	baseLayout, baseVisuals := prepareLayout(baseBlocks)
+	otherLayout, otherVisuals := prepareLayout(otherBlocks)
+	matchLayouts(baseLayout, otherLayout)
+
+	comparison := &models.LayoutComparison{
+		BaseOperationID:  base.ID,
+		OtherOperationID: other.ID,
+		BaseViewport:     operationViewport(base, baseBlocks),
+		OtherViewport:    operationViewport(other, otherBlocks),
+		Blocks:           []models.BlockLayoutDiff{},
+		Summary:          make(map[models.LayoutChange]int),
+		VisualsAvailable: baseVisuals && otherVisuals,
+	}
+
+	moved := movedBlocks(baseLayout)
+
+	for _, item := range baseLayout {
+		diff := models.BlockLayoutDiff{
+			BlockType:    item.block.BlockType,
+			Selector:     item.selector,
+			BaseBlockID:  &item.block.ID,
+			BasePosition: item.position,
+		}
+
+		if match := item.match; match == nil {
+			diff.Change = models.LayoutMissing
+		} else {
+			diff.OtherBlockID = &match.block.ID
+			diff.OtherPosition = match.position
+			switch {
+			case !item.hidden && match.hidden:
+				diff.Change = models.LayoutHidden
+			case item.hidden && !match.hidden:
+				diff.Change = models.LayoutShown
+			case moved[item]:
+				diff.Change = models.LayoutMoved
+			default:
+				diff.Change = models.LayoutSame
+			}
+		}
+
+		comparison.Blocks = append(comparison.Blocks, diff)
+		comparison.Summary[diff.Change]++
+	}
+
+	for _, item := range otherLayout {
+		if item.match != nil {
+			continue
+		}
+		comparison.Blocks = append(comparison.Blocks, models.BlockLayoutDiff{
+			BlockType:     item.block.BlockType,
+			Selector:      item.selector,
+			OtherBlockID:  &item.block.ID,
+			OtherPosition: item.position,
+			Change:        models.LayoutAdded,
+		})
+		comparison.Summary[models.LayoutAdded]++
+	}
+
+	return comparison, nil
+}
+
+// operationViewport возвращает устройство операции; для старых операций берется отметка блоков
+func operationViewport(operation *models.Operation, blocks []models.Block) models.DevicePreset {
+	if operation.Device != nil && operation.Device.Preset != "" {
+		return operation.Device.Preset
+	}
+	if len(blocks) > 0 && blocks[0].Viewport != "" {
+		return blocks[0].Viewport
+	}
+	return models.DeviceDesktop
+}
+
+// prepareLayout извлекает из блоков текст, селектор и видимость и упорядочивает блоки
+// по положению на странице. Если положение известно не для всех блоков,
+// сохраняется порядок документа. Второе значение сообщает, что у блоков есть снимки
+func prepareLayout(blocks []models.Block) ([]*layoutBlock, bool) {
+	layout := make([]*layoutBlock, 0, len(blocks))
+	withVisuals := len(blocks) > 0
+
+	for _, block := range blocks {
+		item := &layoutBlock{block: block, text: blockCompareText(block.HTML)}
+
+		if content, ok := block.Content.(map[string]interface{}); ok {
+			item.selector, _ = content["selector"].(string)
+		}
+
+		var visual models.BlockVisual
+		if decodeContentField(block.Content, "visual", &visual) {
+			item.hidden = visual.Hidden
+			item.visual = visual.BoundingBox
+		}
+		if item.visual == nil {
+			withVisuals = false
+		}
+
+		layout = append(layout, item)
+	}
+
+	if withVisuals {
+		sort.SliceStable(layout, func(i, j int) bool {
+			if layout[i].visual.Y != layout[j].visual.Y {
+				return layout[i].visual.Y < layout[j].visual.Y
+			}
+			return layout[i].visual.X < layout[j].visual.X
+		})
+	}
+
+	position := 0
+	for _, item := range layout {
+		if !item.hidden {
+			position++
+			item.position = position
+		}
+	}
+
+	return layout, withVisuals
+}
+
+// matchLayouts сопоставляет блоки двух операций: сначала по типу и тексту,
+// затем оставшиеся по типу и CSS-селектору
+func matchLayouts(base, other []*layoutBlock) {
+	keys := []func(*layoutBlock) string{
+		func(item *layoutBlock) string {
+			if item.text == "" {
+				return ""
+			}
+			return string(item.block.BlockType) + "|" + item.text
+		},
+		func(item *layoutBlock) string {
+			if item.selector == "" {
+				return ""
+			}
+			return string(item.block.BlockType) + "|" + item.selector
+		},
+	}
+
+	for _, key := range keys {
+		// Блоки с одинаковым ключом сопоставляются по порядку на странице
+		pending := make(map[string][]*layoutBlock)
+		for _, item := range other {
+			if k := key(item); k != "" && item.match == nil {
+				pending[k] = append(pending[k], item)
+			}
+		}
+
+		for _, item := range base {
+			k := key(item)
+			if k == "" || item.match != nil || len(pending[k]) == 0 {
+				continue
+			}
+			item.match = pending[k][0]
+			item.match.match = item
+			pending[k] = pending[k][1:]
+		}
+	}
+}
+
+// movedBlocks находит блоки, переставленные на втором устройстве. Блоки, сохранившие взаимный
+// порядок, образуют наибольшую возрастающую подпоследовательность позиций; остальные считаются
+// перемещенными, чтобы перестановка одного блока не отмечала все блоки между старым и новым местом
+func movedBlocks(base []*layoutBlock) map[*layoutBlock]bool {
+	var visible []*layoutBlock
+	var positions []int
+	for _, item := range base {
+		if item.match != nil && !item.hidden && !item.match.hidden {
+			visible = append(visible, item)
+			positions = append(positions, item.match.position)
+		}
+	}
+
+	kept := increasingSubsequence(positions)
+
+	moved := make(map[*layoutBlock]bool)
+	for i, item := range visible {
+		if !kept[i] {
+			moved[item] = true
+		}
+	}
+	return moved
+}
+
+// increasingSubsequence отмечает элементы наибольшей возрастающей подпоследовательности значений
+func increasingSubsequence(values []int) []bool {
+	n := len(values)
+	length := make([]int, n)
+	prev := make([]int, n)
+	best := -1
+	for i := range values {
+		length[i], prev[i] = 1, -1
+		for j := 0; j < i; j++ {
+			if values[j] < values[i] && length[j]+1 > length[i] {
+				length[i], prev[i] = length[j]+1, j
+			}
+		}
+		if best == -1 || length[i] > length[best] {
+			best = i
+		}
+	}
+
+	kept := make([]bool, n)
+	for i := best; i >= 0; i = prev[i] {
+		kept[i] = true
+	}
+	return kept
+}
+
+// blockCompareText возвращает нормализованный видимый текст блока для сопоставления
+func blockCompareText(html string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return ""
+	}
+	doc.Find("script, style, noscript, template").Remove()
+
+	text := []rune(strings.ToLower(strings.Join(strings.Fields(doc.Text()), " ")))
+	if len(text) > compareTextLength {
+		text = text[:compareTextLength]
+	}
+	return string(text)
+}
@@ -11,7 +11,16 @@ import (
 // ParserService представляет интерфейс для сервиса парсинга
 type ParserService interface {
 	// ParseURL парсит URL и сохраняет результаты в базу данных
-	ParseURL(ctx context.Context, url string) (uuid.UUID, error)
+	ParseURL(ctx context.Context, url string, opts models.ParseOptions) (uuid.UUID, error)
+
+	// ParseUpload разбирает HTML-файл или ZIP-архив сохраненного сайта, переданный вместо URL
+	ParseUpload(ctx context.Context, upload models.UploadedPage, opts models.ParseOptions) (uuid.UUID, error)
+
+	// ReparseOperation повторно разбирает сохраненный HTML операции в новую связанную операцию
+	ReparseOperation(ctx context.Context, operationID uuid.UUID, opts models.ParseOptions) (uuid.UUID, error)
+
+	// ListOperations получает страницу списка операций с фильтрами; cursor — курсор предыдущей страницы
+	ListOperations(ctx context.Context, query models.OperationListQuery, cursor string) (*models.OperationListResponse, error)
 
 	// GetOperationResult получает результаты операции по ID
 	GetOperationResult(ctx context.Context, operationID uuid.UUID) (*models.GetOperationResultResponse, error)
@@ -19,6 +28,29 @@ type ParserService interface {
 	// ExportOperation экспортирует результаты операции в файл
 	ExportOperation(ctx context.Context, operationID uuid.UUID, format string) ([]byte, string, error)
 
+	// ExportProducts экспортирует товары, найденные в операциях, в CSV или Excel
+	ExportProducts(ctx context.Context, operationIDs []uuid.UUID, format string) ([]byte, string, error)
+
+	// CompareLayouts сравнивает блоки двух операций одной страницы на разных устройствах
+	CompareLayouts(ctx context.Context, baseID, otherID uuid.UUID) (*models.LayoutComparison, error)
+
+	// DiffOperations сравнивает блоки двух операций одной страницы, снятых в разное время
+	DiffOperations(ctx context.Context, oldID, newID uuid.UUID) (*models.OperationDiff, error)
+
+	// DuplicateReport показывает, какие блоки сайта повторяются на всех страницах, какие на нескольких,
+	// а какие встречаются на одной странице
+	DuplicateReport(ctx context.Context, domain string) (*models.DuplicateReport, error)
+
+	// SimilarBlocks находит блоки всех сохраненных операций с той же структурой тегов и классов,
+	// что и у исходного блока, например блоки одного шаблона Tilda на разных сайтах
+	SimilarBlocks(ctx context.Context, blockID uuid.UUID, query models.SimilarBlocksQuery) (*models.SimilarBlocksResponse, error)
+
+	// SearchBlocks ищет блоки всех сохраненных операций по полнотекстовому запросу и фильтрам
+	SearchBlocks(ctx context.Context, query models.BlockSearchQuery) (*models.BlockSearchResponse, error)
+
+	// GetOperationAssets возвращает ресурсы блоков операции и сводку по ним
+	GetOperationAssets(ctx context.Context, operationID uuid.UUID) (*models.OperationAssetsResponse, error)
+
 	// DetectPlatform определяет платформу сайта по HTML
 	DetectPlatform(html string) models.Platform
 
@@ -30,4 +62,7 @@ type ParserService interface {
 
 	// SaveBlocks сохраняет блоки на диск
 	SaveBlocks(ctx context.Context, blocks []models.Block) error
+
+	// MirrorBlocks сохраняет автономные копии блоков операции с локальными ресурсами и встроенным CSS
+	MirrorBlocks(ctx context.Context, operationID uuid.UUID) (int, error)
 }
@@ -0,0 +1,404 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"website-scraper/internal/models"
+)
+
+const (
+	// diffSimilarity задает минимальное сходство блоков одного типа, чтобы считать блок измененным,
+	// а не удаленным и добавленным
+	diffSimilarity = 0.5
+	// diffTemplateSimilarity задает минимальное сходство блоков, совпадающих по шаблону
+	diffTemplateSimilarity = 0.2
+	// maxDiffCells ограничивает размер таблицы построчного сравнения
+	maxDiffCells = 4_000_000
+)
+
+// ErrOperationNotCompleted возвращается при сравнении операции, разбор которой не завершен успешно
+var ErrOperationNotCompleted = errors.New("операция не завершена")
+
+// diffBlock представляет блок операции, подготовленный к сравнению содержимого
+type diffBlock struct {
+	block    models.Block
+	template string
+	// normalized содержит HTML блока без различий в пробелах
+	normalized string
+	text       []string
+	markup     []string
+	structure  []string
+	tokens     map[string]struct{}
+	// position содержит порядковый номер блока в операции, начиная с 1
+	position   int
+	match      *diffBlock
+	similarity float64
+	moved      bool
+}
+
+// DiffOperations сравнивает блоки двух операций одной или разных страниц: какие блоки добавлены,
+// удалены, переставлены или изменены и как изменились их текст, HTML и структура
+func (s *parserService) DiffOperations(ctx context.Context, oldID, newID uuid.UUID) (*models.OperationDiff, error) {
+	oldOperation, err := s.repo.GetOperationByID(ctx, oldID)
+	if err != nil {
+		return nil, err
+	}
+	newOperation, err := s.repo.GetOperationByID(ctx, newID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, operation := range []*models.Operation{oldOperation, newOperation} {
+		if operation.Status != models.StatusCompleted {
+			return nil, fmt.Errorf("%w: %s (%s)", ErrOperationNotCompleted, operation.ID, operation.Status)
+		}
+	}
+
+	oldBlocks, err := s.repo.GetBlocksByOperationID(ctx, oldOperation.ID)
+	if err != nil {
+		return nil, err
+	}
+	newBlocks, err := s.repo.GetBlocksByOperationID(ctx, newOperation.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := diffBlocks(oldBlocks, newBlocks)
+	diff.OldOperationID = oldOperation.ID
+	diff.NewOperationID = newOperation.ID
+	diff.OldURL = oldOperation.URL
+	diff.NewURL = newOperation.URL
+	return diff, nil
+}
+
+// diffBlocks сопоставляет блоки и описывает изменения. Измененные, переставленные и добавленные
+// блоки перечисляются в порядке новой операции, удаленные — после них в порядке старой
+func diffBlocks(oldBlocks, newBlocks []models.Block) *models.OperationDiff {
+	oldItems := prepareDiffBlocks(oldBlocks)
+	newItems := prepareDiffBlocks(newBlocks)
+	matchDiffBlocks(oldItems, newItems)
+	markMovedBlocks(oldItems)
+
+	diff := &models.OperationDiff{Changes: []models.BlockChange{}}
+
+	for _, item := range newItems {
+		if item.match == nil {
+			diff.Changes = append(diff.Changes, models.BlockChange{
+				Change:       models.BlockAdded,
+				BlockType:    item.block.BlockType,
+				TemplateName: item.template,
+				NewBlockID:   &item.block.ID,
+				NewPosition:  item.position,
+			})
+			diff.Summary.Added++
+			continue
+		}
+
+		old := item.match
+		change := models.BlockChange{
+			BlockType:    item.block.BlockType,
+			TemplateName: item.template,
+			OldBlockID:   &old.block.ID,
+			NewBlockID:   &item.block.ID,
+			OldPosition:  old.position,
+			NewPosition:  item.position,
+		}
+
+		switch {
+		case old.normalized != item.normalized:
+			change.Change = models.BlockModified
+			change.Moved = item.moved
+			change.Similarity = item.similarity
+			change.TextDiff = diffLines(old.text, item.text)
+			change.HTMLDiff = diffLines(old.markup, item.markup)
+			change.StructureDiff = diffLines(old.structure, item.structure)
+			diff.Summary.Modified++
+		case item.moved:
+			change.Change = models.BlockMoved
+			diff.Summary.Moved++
+		default:
+			diff.Summary.Unchanged++
+			continue
+		}
+
+		diff.Changes = append(diff.Changes, change)
+	}
+
+	for _, item := range oldItems {
+		if item.match != nil {
+			continue
+		}
+		diff.Changes = append(diff.Changes, models.BlockChange{
+			Change:       models.BlockRemoved,
+			BlockType:    item.block.BlockType,
+			TemplateName: item.template,
+			OldBlockID:   &item.block.ID,
+			OldPosition:  item.position,
+		})
+		diff.Summary.Removed++
+	}
+
+	return diff
+}
+
+// markMovedBlocks отмечает сопоставленные блоки, порядок которых изменился. Как и при сравнении
+// верстки, блоки вне наибольшей возрастающей подпоследовательности новых позиций считаются перемещенными
+func markMovedBlocks(oldItems []*diffBlock) {
+	var matched []*diffBlock
+	var positions []int
+	for _, item := range oldItems {
+		if item.match != nil {
+			matched = append(matched, item)
+			positions = append(positions, item.match.position)
+		}
+	}
+
+	kept := increasingSubsequence(positions)
+	for i, item := range matched {
+		item.match.moved = !kept[i]
+	}
+}
+
+// prepareDiffBlocks извлекает из блоков строки текста, структуру тегов и шаблон
+func prepareDiffBlocks(blocks []models.Block) []*diffBlock {
+	items := make([]*diffBlock, 0, len(blocks))
+
+	for i, block := range blocks {
+		item := &diffBlock{
+			block:      block,
+			normalized: strings.Join(strings.Fields(block.HTML), " "),
+			markup:     markupLines(block.HTML),
+			tokens:     make(map[string]struct{}),
+			position:   i + 1,
+		}
+		if content, ok := block.Content.(map[string]interface{}); ok {
+			item.template, _ = content["template_name"].(string)
+		}
+
+		item.text, item.structure = blockLines(block.HTML)
+		for _, line := range item.text {
+			for _, word := range strings.Fields(strings.ToLower(line)) {
+				item.tokens["t:"+word] = struct{}{}
+			}
+		}
+		for _, line := range item.structure {
+			item.tokens["s:"+line] = struct{}{}
+		}
+
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// blockLines возвращает видимый текст блока построчно и дерево его тегов: по строке на элемент
+// с отступом по глубине, именем тега и отсортированными классами
+func blockLines(source string) (text, structure []string) {
+	nodes, err := html.ParseFragment(strings.NewReader(source), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return nil, nil
+	}
+
+	var walk func(node *html.Node, depth int)
+	walk = func(node *html.Node, depth int) {
+		switch node.Type {
+		case html.TextNode:
+			if line := strings.Join(strings.Fields(node.Data), " "); line != "" {
+				text = append(text, line)
+			}
+			return
+		case html.ElementNode:
+			structure = append(structure, strings.Repeat("  ", depth)+elementSignature(node))
+			switch node.Data {
+			case "script", "style", "noscript", "template":
+				return
+			}
+			depth++
+		default:
+			return
+		}
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child, depth)
+		}
+	}
+
+	for _, node := range nodes {
+		walk(node, 0)
+	}
+	return text, structure
+}
+
+// markupLines разбивает HTML блока на строки: по строке на каждый тег и каждый фрагмент текста
+func markupLines(source string) []string {
+	var lines []string
+
+	tokenizer := html.NewTokenizer(strings.NewReader(source))
+	for {
+		if tokenizer.Next() == html.ErrorToken {
+			return lines
+		}
+		if line := strings.Join(strings.Fields(tokenizer.Token().String()), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+}
+
+// elementSignature возвращает имя тега с отсортированными классами, например div.card.wide
+func elementSignature(node *html.Node) string {
+	var classes []string
+	for _, attr := range node.Attr {
+		if attr.Key == "class" {
+			classes = strings.Fields(attr.Val)
+			break
+		}
+	}
+	sort.Strings(classes)
+
+	if len(classes) == 0 {
+		return node.Data
+	}
+	return node.Data + "." + strings.Join(classes, ".")
+}
+
+// matchDiffBlocks сопоставляет блоки двух операций: сначала неизменные блоки того же типа,
+// затем блоки того же шаблона и, наконец, самые похожие блоки того же типа
+func matchDiffBlocks(oldItems, newItems []*diffBlock) {
+	// Одинаковые блоки сопоставляются по порядку на странице
+	pending := make(map[string][]*diffBlock)
+	for _, item := range oldItems {
+		key := string(item.block.BlockType) + "|" + item.normalized
+		pending[key] = append(pending[key], item)
+	}
+	for _, item := range newItems {
+		key := string(item.block.BlockType) + "|" + item.normalized
+		if len(pending[key]) == 0 {
+			continue
+		}
+		item.match, pending[key][0].match = pending[key][0], item
+		item.similarity = 1
+		pending[key] = pending[key][1:]
+	}
+
+	matchSimilar(oldItems, newItems, diffTemplateSimilarity, func(old, item *diffBlock) bool {
+		return old.template != "" && old.template == item.template && old.block.BlockType == item.block.BlockType
+	})
+	matchSimilar(oldItems, newItems, diffSimilarity, func(old, item *diffBlock) bool {
+		return old.block.BlockType == item.block.BlockType
+	})
+}
+
+// matchSimilar жадно сопоставляет свободные блоки, подходящие друг другу, начиная с самых похожих
+func matchSimilar(oldItems, newItems []*diffBlock, threshold float64, compatible func(old, item *diffBlock) bool) {
+	type candidate struct {
+		old, item  *diffBlock
+		similarity float64
+	}
+
+	var candidates []candidate
+	for _, item := range newItems {
+		if item.match != nil {
+			continue
+		}
+		for _, old := range oldItems {
+			if old.match != nil || !compatible(old, item) {
+				continue
+			}
+			if similarity := jaccard(old.tokens, item.tokens); similarity >= threshold {
+				candidates = append(candidates, candidate{old: old, item: item, similarity: similarity})
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].similarity > candidates[j].similarity
+	})
+
+	for _, c := range candidates {
+		if c.old.match != nil || c.item.match != nil {
+			continue
+		}
+		c.item.match, c.old.match = c.old, c.item
+		c.item.similarity = c.similarity
+	}
+}
+
+// jaccard возвращает долю общих элементов двух множеств
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+
+	common := 0
+	for key := range a {
+		if _, ok := b[key]; ok {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// diffLines возвращает удаленные и добавленные строки по наибольшей общей подпоследовательности.
+// Слишком большие блоки сравниваются без поиска общих строк в середине
+func diffLines(a, b []string) []models.DiffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	lines := []models.DiffLine{}
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			lines = append(lines, models.DiffLine{Op: models.DiffDelete, Text: line})
+		}
+		for _, line := range b {
+			lines = append(lines, models.DiffLine{Op: models.DiffInsert, Text: line})
+		}
+		return lines
+	}
+
+	// common[i][j] содержит длину общей подпоследовательности a[i:] и b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j < len(b) && (i == len(a) || common[i][j+1] >= common[i+1][j]):
+			lines = append(lines, models.DiffLine{Op: models.DiffInsert, Text: b[j]})
+			j++
+		default:
+			lines = append(lines, models.DiffLine{Op: models.DiffDelete, Text: a[i]})
+			i++
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,122 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"strings"
+
+	"website-scraper/internal/models"
+)
+
+const (
+	// fingerprintShingle задает число слов в признаке отпечатка по соседним словам
+	fingerprintShingle = 2
+	// siteWideShare задает долю страниц сайта, начиная с которой блок считается общим для сайта
+	siteWideShare = 0.5
+)
+
+// blockFingerprint возвращает simhash блока по дереву тегов с классами, словам и парам соседних слов текста.
+// Одинаковые блоки получают одинаковый отпечаток, блоки с небольшими отличиями — отличающийся
+// в нескольких битах. Для блока без тегов и текста возвращается 0
+func blockFingerprint(html string) uint64 {
+	text, structure := blockLines(html)
+
+	features := structure
+	words := strings.Fields(strings.ToLower(strings.Join(text, " ")))
+	for _, word := range words {
+		features = append(features, "word:"+word)
+	}
+	for i := 0; i+fingerprintShingle <= len(words); i++ {
+		features = append(features, "text:"+strings.Join(words[i:i+fingerprintShingle], " "))
+	}
+
+	return simhash(features)
+}
+
+// simhash объединяет хеши признаков в 64-битный отпечаток: бит устанавливается, если он установлен
+// в хешах большинства признаков. Для пустого списка признаков возвращается 0
+func simhash(features []string) uint64 {
+	if len(features) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	for _, feature := range features {
+		hash := fnv.New64a()
+		hash.Write([]byte(feature))
+		sum := hash.Sum64()
+
+		for bit := range weights {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
+
+// DuplicateReport строит отчет о повторяющихся блоках сайта. Страницы считаются по адресам
+// завершенных операций, поэтому повторный разбор страницы не увеличивает их число
+func (s *parserService) DuplicateReport(ctx context.Context, domain string) (*models.DuplicateReport, error) {
+	domain = normalizeDomain(domain)
+	if domain == "" {
+		return nil, fmt.Errorf("domain is required")
+	}
+
+	report, err := s.repo.GetSiteBlockGroups(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	report.DistinctBlocks = len(report.Groups)
+	report.Templates = make(map[models.BlockType]int)
+	report.Summary = map[models.BlockScope]int{
+		models.ScopeSiteWide:     0,
+		models.ScopeShared:       0,
+		models.ScopePageSpecific: 0,
+	}
+
+	for i := range report.Groups {
+		group := &report.Groups[i]
+		group.Scope = blockScope(group.Pages, report.Pages)
+
+		report.Templates[group.BlockType]++
+		report.Summary[group.Scope]++
+	}
+
+	return report, nil
+}
+
+// blockScope определяет распространенность группы блоков по числу страниц с ней
+func blockScope(pages, totalPages int) models.BlockScope {
+	switch {
+	case pages <= 1:
+		return models.ScopePageSpecific
+	case float64(pages) >= siteWideShare*float64(totalPages):
+		return models.ScopeSiteWide
+	default:
+		return models.ScopeShared
+	}
+}
+
+// normalizeDomain приводит домен или адрес страницы к домену без www, как в отборе операций сайта
+func normalizeDomain(domain string) string {
+	domain = strings.TrimSpace(strings.ToLower(domain))
+	if strings.Contains(domain, "://") {
+		if parsed, err := url.Parse(domain); err == nil {
+			domain = parsed.Hostname()
+		}
+	}
+	return strings.TrimPrefix(domain, "www.")
+}
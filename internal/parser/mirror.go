@@ -0,0 +1,40 @@
+package parser
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// MirrorBlocks сохраняет автономные копии всех блоков операции: ресурсы скачиваются
+// к файлам блоков операции, а критический CSS страницы встраивается в каждый блок
+func (s *parserService) MirrorBlocks(ctx context.Context, operationID uuid.UUID) (int, error) {
+	operation, err := s.repo.GetOperationByID(ctx, operationID)
+	if err != nil {
+		return 0, err
+	}
+
+	blocks, err := s.repo.GetBlocksByOperationID(ctx, operationID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Стили берем из сохраненного HTML страницы; без него блоки получат только свои ресурсы
+	pageHTML, err := s.downloader.LoadSnapshot(ctx, operation.ID, operation.URL)
+	if err != nil {
+		log.Printf("Error loading page HTML for %s: %v", operation.URL, err)
+	}
+	styles := s.downloader.CollectPageStyles(ctx, operation.URL, pageHTML)
+
+	mirrored := 0
+	for i := range blocks {
+		if err := s.downloader.SaveStandaloneBlock(ctx, &blocks[i], operation.URL, styles); err != nil {
+			log.Printf("Error mirroring block %s: %v", blocks[i].ID, err)
+			continue
+		}
+		mirrored++
+	}
+
+	return mirrored, nil
+}
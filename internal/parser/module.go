@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"context"
+
 	"go.uber.org/fx"
 
 	"website-scraper/internal/downloader"
@@ -37,5 +39,18 @@ var Module = fx.Module("parser",
 				deps.HTML5Parser,
 			)
 		},
+		newBackfillService,
 	),
+	fx.Invoke(func(lc fx.Lifecycle, service *backfillService) {
+		lc.Append(fx.Hook{
+			OnStart: func(ctx context.Context) error {
+				service.Start()
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				service.Close()
+				return nil
+			},
+		})
+	}),
 )
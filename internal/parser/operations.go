@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"website-scraper/internal/models"
+)
+
+// ErrInvalidCursor возвращается для курсора, не выданного списком операций с той же сортировкой
+var ErrInvalidCursor = errors.New("некорректный курсор")
+
+// ListOperations получает страницу списка операций. Курсор следующей страницы выдается,
+// только если после последней операции страницы есть еще операции
+func (s *parserService) ListOperations(ctx context.Context, query models.OperationListQuery, cursor string) (*models.OperationListResponse, error) {
+	if query.Sort == "" {
+		query.Sort = models.SortCreatedDesc
+	}
+	query.URL = strings.TrimSpace(query.URL)
+	query.Domain = normalizeDomain(query.Domain)
+
+	if cursor != "" {
+		after, err := decodeOperationCursor(cursor, query.Sort)
+		if err != nil {
+			return nil, err
+		}
+		query.After = after
+	}
+
+	// Лишняя операция показывает, есть ли следующая страница
+	limit := query.Limit
+	query.Limit++
+
+	operations, err := s.repo.ListOperations(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.OperationListResponse{
+		Operations: operations,
+		Sort:       query.Sort,
+		Limit:      limit,
+	}
+	if len(operations) > limit {
+		response.Operations = operations[:limit]
+		response.NextCursor = encodeOperationCursor(operations[limit-1].Operation, query.Sort)
+	}
+
+	return response, nil
+}
+
+// encodeOperationCursor кодирует положение операции в списке с заданной сортировкой
+func encodeOperationCursor(operation models.Operation, sort models.OperationSort) string {
+	value := operation.CreatedAt.Format(time.RFC3339Nano)
+	if sort == models.SortURLAsc || sort == models.SortURLDesc {
+		value = operation.URL
+	}
+
+	data, _ := json.Marshal(models.OperationCursor{Value: value, ID: operation.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeOperationCursor разбирает курсор и проверяет, что его значение подходит сортировке
+func decodeOperationCursor(cursor string, sort models.OperationSort) (*models.OperationCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var after models.OperationCursor
+	if err := json.Unmarshal(data, &after); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if sort == models.SortCreatedAsc || sort == models.SortCreatedDesc {
+		if _, err := time.Parse(time.RFC3339Nano, after.Value); err != nil {
+			return nil, fmt.Errorf("%w: курсор выдан для другой сортировки", ErrInvalidCursor)
+		}
+	}
+
+	return &after, nil
+}
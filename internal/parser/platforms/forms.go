@@ -0,0 +1,303 @@
+package platforms
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"website-scraper/internal/models"
+)
+
+// formWidgetMarkers содержит признаки встраиваемых форм CRM, которые рендерятся скриптом без тега form
+var formWidgetMarkers = map[models.FormIntegration][]string{
+	models.FormIntegrationBitrix24: {
+		"data-b24-form",
+		"b24-form",
+		"b24-web-form",
+		"bitrix24.ru/b24",
+		"crm_form_loader",
+	},
+	models.FormIntegrationAmoCRM: {
+		"amoforms",
+		"amocrm.ru/forms",
+		"gso.amocrm.ru",
+		"amo_forms",
+	},
+}
+
+// ExtractForms извлекает структурированное описание всех форм выборки
+func ExtractForms(section *goquery.Selection) []models.FormInfo {
+	var forms []models.FormInfo
+
+	section.Find("form").Each(func(i int, form *goquery.Selection) {
+		if isSearchForm(form) {
+			return
+		}
+		forms = append(forms, parseForm(form))
+	})
+
+	// Виджеты CRM могут не содержать тега form до выполнения скрипта
+	if len(forms) == 0 {
+		sectionHTML, err := goquery.OuterHtml(section)
+		if err != nil {
+			return nil
+		}
+
+		for _, integration := range []models.FormIntegration{models.FormIntegrationBitrix24, models.FormIntegrationAmoCRM} {
+			if containsAny(sectionHTML, formWidgetMarkers[integration]) {
+				forms = append(forms, models.FormInfo{
+					Fields:      []models.FormField{},
+					Integration: integration,
+				})
+				break
+			}
+		}
+	}
+
+	return forms
+}
+
+// AttachForms добавляет в содержимое шапки или подвала, разобранных парсером платформы,
+// описание их форм: обратного звонка, подписки и других
+func AttachForms(block *models.Block) {
+	content, ok := block.Content.(map[string]interface{})
+	if !ok || block.HTML == "" {
+		return
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(block.HTML))
+	if err != nil {
+		return
+	}
+	if forms := ExtractForms(doc.Selection); len(forms) > 0 {
+		content["forms"] = forms
+	}
+}
+
+// hasFormWidget проверяет, содержит ли выборка встраиваемую форму CRM
+func hasFormWidget(section *goquery.Selection) bool {
+	sectionHTML, err := goquery.OuterHtml(section)
+	if err != nil {
+		return false
+	}
+
+	for _, markers := range formWidgetMarkers {
+		if containsAny(sectionHTML, markers) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseForm разбирает отдельную форму
+func parseForm(form *goquery.Selection) models.FormInfo {
+	info := models.FormInfo{
+		Fields:       []models.FormField{},
+		HiddenFields: make(map[string]string),
+	}
+
+	info.Action, _ = form.Attr("action")
+	info.Method = strings.ToUpper(strings.TrimSpace(form.AttrOr("method", "")))
+	if info.Method == "" {
+		info.Method = "GET"
+	}
+
+	form.Find("input, select, textarea").Each(func(i int, input *goquery.Selection) {
+		tag := goquery.NodeName(input)
+		fieldType := tag
+		if tag == "input" {
+			fieldType = strings.ToLower(input.AttrOr("type", "text"))
+		}
+
+		name, _ := input.Attr("name")
+
+		switch fieldType {
+		case "hidden":
+			if name != "" {
+				info.HiddenFields[name] = input.AttrOr("value", "")
+			}
+			return
+		case "submit", "image":
+			if info.SubmitText == "" {
+				info.SubmitText = strings.TrimSpace(input.AttrOr("value", input.AttrOr("alt", "")))
+			}
+			return
+		case "button", "reset":
+			return
+		}
+
+		field := models.FormField{
+			Name:        name,
+			Type:        fieldType,
+			Label:       findFieldLabel(form, input),
+			Placeholder: input.AttrOr("placeholder", ""),
+			Required:    isRequiredField(input),
+		}
+
+		if tag == "select" {
+			input.Find("option").Each(func(j int, option *goquery.Selection) {
+				text := strings.TrimSpace(option.Text())
+				if text != "" {
+					field.Options = append(field.Options, text)
+				}
+			})
+		}
+
+		info.Fields = append(info.Fields, field)
+	})
+
+	if info.SubmitText == "" {
+		submit := form.Find("button[type='submit'], button:not([type]), .t-submit, .wpcf7-submit").First()
+		if submit.Length() > 0 {
+			info.SubmitText = strings.TrimSpace(submit.Text())
+		}
+	}
+
+	info.CaptchaType = detectCaptcha(form)
+	info.HasCaptcha = info.CaptchaType != ""
+	info.Integration = detectFormIntegration(form, info)
+
+	if len(info.HiddenFields) == 0 {
+		info.HiddenFields = nil
+	}
+
+	return info
+}
+
+// findFieldLabel ищет подпись к полю формы
+func findFieldLabel(form, input *goquery.Selection) string {
+	// Подпись, связанная через атрибут for
+	if id, exists := input.Attr("id"); exists && id != "" {
+		label := form.Find("label").FilterFunction(func(i int, l *goquery.Selection) bool {
+			return l.AttrOr("for", "") == id
+		}).First()
+		if label.Length() > 0 {
+			return cleanLabel(label.Text())
+		}
+	}
+
+	// Поле внутри тега label
+	if label := input.Closest("label"); label.Length() > 0 {
+		return cleanLabel(label.Text())
+	}
+
+	if ariaLabel, exists := input.Attr("aria-label"); exists && ariaLabel != "" {
+		return cleanLabel(ariaLabel)
+	}
+
+	// Подписи Tilda и Bitrix в обертке поля
+	wrapper := input.Closest(".t-input-group, .form-group, .bx-webform-field, .wpcf7-form-control-wrap, p")
+	if wrapper.Length() > 0 {
+		title := wrapper.Find(".t-input-title, .t-input-name, .bx-webform-label, label").First()
+		if title.Length() > 0 {
+			return cleanLabel(title.Text())
+		}
+	}
+
+	return ""
+}
+
+// cleanLabel нормализует текст подписи поля
+func cleanLabel(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.TrimSpace(strings.TrimSuffix(text, "*"))
+}
+
+// isRequiredField проверяет, является ли поле обязательным
+func isRequiredField(input *goquery.Selection) bool {
+	if _, exists := input.Attr("required"); exists {
+		return true
+	}
+	if input.AttrOr("aria-required", "") == "true" {
+		return true
+	}
+	if input.AttrOr("data-tilda-req", "") == "1" {
+		return true
+	}
+	if input.HasClass("wpcf7-validates-as-required") || input.HasClass("required") {
+		return true
+	}
+
+	return false
+}
+
+// isSearchForm проверяет, является ли форма формой поиска
+func isSearchForm(form *goquery.Selection) bool {
+	if form.AttrOr("role", "") == "search" {
+		return true
+	}
+
+	fields := form.Find("input:not([type='hidden']):not([type='submit']):not([type='button']), textarea, select")
+	return fields.Length() == 1 && fields.Is("input[type='search'], input[name='q'], input[name='s']")
+}
+
+// detectCaptcha определяет тип CAPTCHA в форме
+func detectCaptcha(form *goquery.Selection) string {
+	captchaSelectors := map[string]string{
+		"recaptcha":           ".g-recaptcha, [data-sitekey][class*='recaptcha'], input[name='g-recaptcha-response'], script[src*='recaptcha']",
+		"hcaptcha":            ".h-captcha, script[src*='hcaptcha']",
+		"turnstile":           ".cf-turnstile",
+		"yandex_smartcaptcha": ".smart-captcha, script[src*='smartcaptcha']",
+		"bitrix":              "input[name='captcha_sid'], input[name='captcha_word'], img[src*='captcha.php']",
+	}
+
+	for _, captchaType := range []string{"recaptcha", "hcaptcha", "turnstile", "yandex_smartcaptcha", "bitrix"} {
+		if form.Find(captchaSelectors[captchaType]).Length() > 0 {
+			return captchaType
+		}
+	}
+
+	// Tilda подключает reCAPTCHA через атрибут формы
+	if _, exists := form.Attr("data-tilda-captchakey"); exists {
+		return "recaptcha"
+	}
+
+	return ""
+}
+
+// detectFormIntegration определяет, каким сервисом обрабатывается форма
+func detectFormIntegration(form *goquery.Selection, info models.FormInfo) models.FormIntegration {
+	formHTML, err := goquery.OuterHtml(form)
+	if err != nil {
+		formHTML = ""
+	}
+	action := strings.ToLower(info.Action)
+
+	switch {
+	case form.HasClass("t-form") || strings.Contains(action, "tildacdn.com") ||
+		strings.Contains(formHTML, "data-tilda-formskey") || strings.Contains(formHTML, "js-form-proccess"):
+		return models.FormIntegrationTilda
+	case form.HasClass("wpcf7-form") || form.Closest(".wpcf7").Length() > 0 || hasHiddenPrefix(info, "_wpcf7"):
+		return models.FormIntegrationContactForm7
+	case containsAny(formHTML, formWidgetMarkers[models.FormIntegrationBitrix24]) || strings.Contains(action, "bitrix24"):
+		return models.FormIntegrationBitrix24
+	case hasHiddenPrefix(info, "WEB_FORM_ID") || strings.Contains(formHTML, "web_form_submit") ||
+		strings.Contains(action, "/bitrix/"):
+		return models.FormIntegrationBitrixWebForm
+	case containsAny(formHTML, formWidgetMarkers[models.FormIntegrationAmoCRM]) || strings.Contains(action, "amocrm"):
+		return models.FormIntegrationAmoCRM
+	}
+
+	return models.FormIntegrationNative
+}
+
+// hasHiddenPrefix проверяет наличие скрытого поля с указанным префиксом имени
+func hasHiddenPrefix(info models.FormInfo, prefix string) bool {
+	for name := range info.HiddenFields {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAny проверяет, содержит ли строка хотя бы один из паттернов
+func containsAny(s string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.Contains(s, pattern) {
+			return true
+		}
+	}
+	return false
+}
@@ -238,12 +238,29 @@ func (p *HTML5Parser) ParseAndClassifyPage(html string, templates []models.Block
 		}
 	}
 
-	// Шаг 3: Находим контентные секции
+	// Шаг 3: Находим контентные секции вне шапки и подвала
+	inLayout := func(section *goquery.Selection) bool {
+		return header.Length() > 0 && isDescendantOf(section, header) ||
+			footerElement != nil && isDescendantOf(section, footerElement)
+	}
+	blocks = append(blocks, p.classifySections(doc, inLayout, templates, models.PlatformHTML5)...)
+
+	return blocks, nil
+}
+
+// classifySections выделяет и классифицирует контентные секции документа. Секции, для которых
+// inLayout возвращает true, относятся к шапке или подвалу и пропускаются
+func (p *HTML5Parser) classifySections(doc *goquery.Document, inLayout func(section *goquery.Selection) bool, templates []models.BlockTemplate, platform models.Platform) []*models.Block {
+	var blocks []*models.Block
+
 	potentialBlocks := doc.Find("section, div.section, div[class*='section'], div[class*='block'], div[class*='container'], div.content, main > div")
 
 	// Отслеживаем уже обработанные элементы
 	processedElements := make(map[string]bool)
 
+	// JSON-LD товара обычно размещается в head, вне контентных секций
+	pageProducts := extractJSONLDProducts(doc.Selection)
+
 	// Обрабатываем каждый потенциальный блок
 	potentialBlocks.Each(func(i int, section *goquery.Selection) {
 		// Генерируем уникальный ключ для элемента
@@ -258,15 +275,8 @@ func (p *HTML5Parser) ParseAndClassifyPage(html string, templates []models.Block
 		}
 
 		// Пропускаем элементы внутри шапки или подвала
-		if header.Length() > 0 {
-			if isDescendantOf(section, header) {
-				return
-			}
-		}
-		if footerElement != nil {
-			if isDescendantOf(section, footerElement) {
-				return
-			}
+		if inLayout(section) {
+			return
 		}
 
 		// Пропускаем маленькие секции
@@ -308,14 +318,73 @@ func (p *HTML5Parser) ParseAndClassifyPage(html string, templates []models.Block
 			}
 		}
 
+		// Извлекаем структуру форм обратной связи
+		if forms := ExtractForms(section); len(forms) > 0 {
+			content["forms"] = forms
+		}
+
+		// Извлекаем товары для каталогов и карточек товаров
+		templateName, _ := content["template_name"].(string)
+		if IsProductTemplate(templateName) || hasSchemaProduct(section) {
+			products := ExtractProducts(section)
+			if len(products) == 0 && templateName == "Карточка товара" {
+				products = pageProducts
+			}
+			if len(products) > 0 {
+				content["products"] = products
+			}
+		}
+
 		blocks = append(blocks, &models.Block{
 			BlockType: models.BlockTypeContent,
-			Platform:  models.PlatformHTML5,
+			Platform:  platform,
 			Content:   content,
 			HTML:      sectionHTML,
 		})
 	})
 
+	return blocks
+}
+
+// platformLayoutSelector находит шапку и подвал страниц платформ, которые разбирает парсер платформы
+const platformLayoutSelector = "header, footer, #t-header, #t-footer, .t-site-header-wrapper"
+
+// ParseFormAndProductBlocks выделяет секции страницы Tilda, Bitrix или WordPress с формами и товарами.
+// Шапку и подвал таких страниц разбирает парсер платформы, поэтому остальные секции классифицируются
+// так же, как на странице HTML5, но в результат попадают только секции с формами или товарами.
+// Из вложенных друг в друга секций остается самая внутренняя
+func (p *HTML5Parser) ParseFormAndProductBlocks(html string, templates []models.BlockTemplate, platform models.Platform) ([]*models.Block, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, err
+	}
+
+	inLayout := func(section *goquery.Selection) bool {
+		return section.Closest(platformLayoutSelector).Length() > 0
+	}
+
+	var candidates []*models.Block
+	for _, block := range p.classifySections(doc, inLayout, templates, platform) {
+		content, _ := block.Content.(map[string]interface{})
+		if content["forms"] != nil || content["products"] != nil {
+			candidates = append(candidates, block)
+		}
+	}
+
+	var blocks []*models.Block
+	for i, block := range candidates {
+		outer := false
+		for j, other := range candidates {
+			if i != j && len(other.HTML) < len(block.HTML) && strings.Contains(block.HTML, other.HTML) {
+				outer = true
+				break
+			}
+		}
+		if !outer {
+			blocks = append(blocks, block)
+		}
+	}
+
 	return blocks, nil
 }
 
@@ -456,7 +525,7 @@ func (p *HTML5Parser) classifyBlockByHeuristics(section *goquery.Selection) stri
 	// Логика классификации
 	if hasMap {
 		return "Карта"
-	} else if formCount > 0 {
+	} else if formCount > 0 || hasFormWidget(section) {
 		return "Форма обратной связи"
 	} else if hasContactInfo {
 		return "Контакты"
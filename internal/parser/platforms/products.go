@@ -0,0 +1,462 @@
+package platforms
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"website-scraper/internal/models"
+)
+
+const (
+	productSourceSchema = "schema.org"
+	productSourceDOM    = "dom"
+)
+
+// productTemplates содержит названия шаблонов блоков с товарами
+var productTemplates = map[string]bool{
+	"Товары":          true,
+	"Карточка товара": true,
+}
+
+// productCardSelectors содержит селекторы карточек товаров популярных платформ
+var productCardSelectors = []string{
+	".t-store__card",
+	".t-store__product-snippet",
+	".js-product",
+	"li.product",
+	".product-item",
+	".product-card",
+	".bx_catalog_item",
+	".catalog-item",
+	".catalog-section-item",
+	"[class*='product']",
+	".card",
+}
+
+// currencySymbols сопоставляет обозначения валют с кодами ISO 4217. Более длинные обозначения
+// идут раньше тех, что в них входят: "бел. руб" проверяется до "руб". Обозначения с afterNumber
+// встречаются и в обычных словах, поэтому учитываются только сразу после числа: "990 р.", но не "пр."
+var currencySymbols = []struct {
+	pattern     string
+	code        string
+	afterNumber bool
+}{
+	{"byn", "BYN", false}, {"бел. руб", "BYN", false}, {"бел.руб", "BYN", false},
+	{"₽", "RUB", false}, {"руб", "RUB", false}, {"р.", "RUB", true}, {"rub", "RUB", false},
+	{"$", "USD", false}, {"usd", "USD", false},
+	{"€", "EUR", false}, {"eur", "EUR", false},
+	{"£", "GBP", false}, {"gbp", "GBP", false},
+	{"₸", "KZT", false}, {"тг", "KZT", false}, {"kzt", "KZT", false},
+	{"₴", "UAH", false}, {"грн", "UAH", false}, {"uah", "UAH", false},
+}
+
+// priceNumberRegexp находит число цены целиком: группы цифр через точку или запятую и группы
+// по три цифры через пробел. Какой из разделителей десятичный, решает parsePriceNumber
+var priceNumberRegexp = regexp.MustCompile(`\d+(?:[.,]\d+|[\s\x{00A0}\x{2009}\x{202F}]\d{3}\b)*`)
+
+// priceSpaces содержит пробелы, которыми отделяют разряды и валюту от числа
+const priceSpaces = " \u00A0\u2009\u202F"
+
+// IsProductTemplate проверяет, относится ли шаблон к блокам с товарами
+func IsProductTemplate(templateName string) bool {
+	return productTemplates[templateName]
+}
+
+// ExtractProducts извлекает товары из выборки. Данные schema.org имеют приоритет перед эвристиками по DOM
+func ExtractProducts(section *goquery.Selection) []models.Product {
+	if products := extractJSONLDProducts(section); len(products) > 0 {
+		return products
+	}
+
+	if products := extractMicrodataProducts(section); len(products) > 0 {
+		return products
+	}
+
+	return extractDOMProducts(section)
+}
+
+// hasSchemaProduct проверяет наличие разметки schema.org Product в выборке
+func hasSchemaProduct(section *goquery.Selection) bool {
+	if section.Find("[itemtype*='schema.org/Product']").Length() > 0 {
+		return true
+	}
+	return len(extractJSONLDProducts(section)) > 0
+}
+
+// extractJSONLDProducts извлекает товары из JSON-LD разметки
+func extractJSONLDProducts(section *goquery.Selection) []models.Product {
+	var products []models.Product
+
+	section.Find("script[type='application/ld+json']").Each(func(i int, script *goquery.Selection) {
+		var data interface{}
+		if err := json.Unmarshal([]byte(script.Text()), &data); err != nil {
+			return
+		}
+		products = append(products, collectJSONLDProducts(data)...)
+	})
+
+	return products
+}
+
+// collectJSONLDProducts рекурсивно обходит JSON-LD и собирает объекты Product
+func collectJSONLDProducts(data interface{}) []models.Product {
+	var products []models.Product
+
+	switch value := data.(type) {
+	case []interface{}:
+		for _, item := range value {
+			products = append(products, collectJSONLDProducts(item)...)
+		}
+	case map[string]interface{}:
+		if hasJSONLDType(value, "Product") {
+			// Как и в микроразметке, товар без названия не учитываем
+			if product := jsonLDProduct(value); product.Name != "" {
+				products = append(products, product)
+			}
+			return products
+		}
+		if graph, ok := value["@graph"]; ok {
+			products = append(products, collectJSONLDProducts(graph)...)
+		}
+		if items, ok := value["itemListElement"]; ok {
+			products = append(products, collectJSONLDProducts(items)...)
+		}
+		if item, ok := value["item"]; ok {
+			products = append(products, collectJSONLDProducts(item)...)
+		}
+	}
+
+	return products
+}
+
+// hasJSONLDType проверяет значение @type объекта JSON-LD
+func hasJSONLDType(obj map[string]interface{}, typeName string) bool {
+	switch t := obj["@type"].(type) {
+	case string:
+		return t == typeName
+	case []interface{}:
+		for _, item := range t {
+			if s, ok := item.(string); ok && s == typeName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jsonLDProduct преобразует объект JSON-LD Product в модель товара
+func jsonLDProduct(obj map[string]interface{}) models.Product {
+	product := models.Product{
+		Name:   jsonLDString(obj["name"]),
+		Image:  jsonLDString(obj["image"]),
+		Link:   jsonLDString(obj["url"]),
+		SKU:    jsonLDString(obj["sku"]),
+		Source: productSourceSchema,
+	}
+
+	offers := obj["offers"]
+	if list, ok := offers.([]interface{}); ok && len(list) > 0 {
+		offers = list[0]
+	}
+
+	if offer, ok := offers.(map[string]interface{}); ok {
+		price := jsonLDString(offer["price"])
+		if price == "" {
+			price = jsonLDString(offer["lowPrice"])
+		}
+		product.Price, _ = ParsePrice(price)
+		product.Currency = strings.ToUpper(jsonLDString(offer["priceCurrency"]))
+		product.Availability = normalizeAvailability(jsonLDString(offer["availability"]))
+		if product.Link == "" {
+			product.Link = jsonLDString(offer["url"])
+		}
+	}
+
+	return product
+}
+
+// jsonLDString приводит значение JSON-LD к строке
+func jsonLDString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		if len(v) > 0 {
+			return jsonLDString(v[0])
+		}
+	case map[string]interface{}:
+		if url, ok := v["url"]; ok {
+			return jsonLDString(url)
+		}
+		if id, ok := v["@id"]; ok {
+			return jsonLDString(id)
+		}
+	}
+	return ""
+}
+
+// extractMicrodataProducts извлекает товары из микроразметки schema.org
+func extractMicrodataProducts(section *goquery.Selection) []models.Product {
+	var products []models.Product
+
+	section.Find("[itemtype*='schema.org/Product']").Each(func(i int, item *goquery.Selection) {
+		product := models.Product{
+			Name:   itempropValue(item, "name"),
+			Image:  itempropValue(item, "image"),
+			Link:   itempropValue(item, "url"),
+			SKU:    itempropValue(item, "sku"),
+			Source: productSourceSchema,
+		}
+
+		offer := item.Find("[itemprop='offers']").First()
+		if offer.Length() == 0 {
+			offer = item
+		}
+
+		price := itempropValue(offer, "price")
+		if price == "" {
+			price = itempropValue(offer, "lowPrice")
+		}
+		product.Price, product.Currency = ParsePrice(price)
+		if currency := itempropValue(offer, "priceCurrency"); currency != "" {
+			product.Currency = strings.ToUpper(currency)
+		}
+		product.Availability = normalizeAvailability(itempropValue(offer, "availability"))
+
+		if product.Link == "" {
+			product.Link = item.Find("a[href]").First().AttrOr("href", "")
+		}
+
+		if product.Name != "" {
+			products = append(products, product)
+		}
+	})
+
+	return products
+}
+
+// itempropValue возвращает значение свойства микроразметки
+func itempropValue(item *goquery.Selection, prop string) string {
+	node := item.Find("[itemprop='" + prop + "']").First()
+	if node.Length() == 0 {
+		return ""
+	}
+
+	for _, attr := range []string{"content", "src", "href", "data-src"} {
+		if value, exists := node.Attr(attr); exists && value != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+
+	return strings.Join(strings.Fields(node.Text()), " ")
+}
+
+// extractDOMProducts извлекает товары по эвристикам для карточек каталога
+func extractDOMProducts(section *goquery.Selection) []models.Product {
+	// Карточкой считаем элемент, в котором нашлись и название, и цена
+	cards := section.Find(strings.Join(productCardSelectors, ", ")).FilterFunction(func(i int, card *goquery.Selection) bool {
+		product, ok := domProduct(card)
+		return ok && product.Price > 0
+	})
+
+	// Оставляем только самые вложенные карточки, чтобы обертки списка не считались товаром
+	cards = cards.FilterFunction(func(i int, card *goquery.Selection) bool {
+		nested := false
+		card.Find("*").EachWithBreak(func(j int, child *goquery.Selection) bool {
+			if cards.IndexOfSelection(child) >= 0 {
+				nested = true
+				return false
+			}
+			return true
+		})
+		return !nested
+	})
+
+	if cards.Length() == 0 {
+		// Одиночная карточка товара без обертки
+		if product, ok := domProduct(section); ok && product.Price > 0 {
+			return []models.Product{product}
+		}
+		return nil
+	}
+
+	var products []models.Product
+	cards.Each(func(i int, card *goquery.Selection) {
+		if product, ok := domProduct(card); ok {
+			products = append(products, product)
+		}
+	})
+
+	return products
+}
+
+// domProduct собирает товар из карточки
+func domProduct(card *goquery.Selection) (models.Product, bool) {
+	product := models.Product{Source: productSourceDOM}
+
+	nameNode := card.Find(".t-store__card__title, .t-store__prod-popup__name, .product-title, .product-name, .woocommerce-loop-product__title, [class*='title'], [class*='name'], h1, h2, h3, h4, h5").First()
+	if nameNode.Length() == 0 {
+		nameNode = card.Find("a").FilterFunction(func(i int, a *goquery.Selection) bool {
+			return findPriceNode(a).Length() == 0
+		}).First()
+	}
+	product.Name = strings.Join(strings.Fields(nameNode.Text()), " ")
+	if product.Name == "" {
+		return product, false
+	}
+
+	product.Price, product.Currency = ParsePrice(findPriceNode(card).Text())
+	if currencyNode := card.Find(".t-store__card__price-currency, [class*='currency']").First(); currencyNode.Length() > 0 {
+		if _, currency := ParsePrice("0 " + currencyNode.Text()); currency != "" {
+			product.Currency = currency
+		}
+	}
+
+	if oldPriceNode := card.Find(".t-store__card__price_old, del, s, [class*='old-price'], [class*='price-old'], [class*='price_old']").First(); oldPriceNode.Length() > 0 {
+		product.OldPrice, _ = ParsePrice(oldPriceNode.Text())
+	}
+
+	if img := card.Find("img").First(); img.Length() > 0 {
+		for _, attr := range []string{"data-original", "data-src", "src"} {
+			if src, exists := img.Attr(attr); exists && src != "" && !strings.HasPrefix(src, "data:") {
+				product.Image = src
+				break
+			}
+		}
+	}
+	if product.Image == "" {
+		product.Image = card.Find("[data-original]").First().AttrOr("data-original", "")
+	}
+
+	if href, exists := card.Attr("href"); exists {
+		product.Link = href
+	} else {
+		product.Link = card.Find("a[href]").First().AttrOr("href", "")
+	}
+
+	product.SKU = card.AttrOr("data-product-sku", "")
+	if product.SKU == "" {
+		skuNode := card.Find(".t-store__card__sku, [class*='sku'], [class*='article']").First()
+		product.SKU = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(skuNode.Text()), "Артикул:"))
+	}
+
+	product.Availability = domAvailability(card)
+
+	return product, true
+}
+
+// findPriceNode ищет элемент с текущей ценой товара
+func findPriceNode(card *goquery.Selection) *goquery.Selection {
+	return card.Find(".t-store__card__price-value, .woocommerce-Price-amount, [itemprop='price'], [class*='price']").FilterFunction(func(i int, node *goquery.Selection) bool {
+		class := node.AttrOr("class", "")
+		if strings.Contains(class, "old") || node.Closest("del, s").Length() > 0 {
+			return false
+		}
+		price, _ := ParsePrice(node.Text())
+		return price > 0
+	}).First()
+}
+
+// domAvailability определяет наличие товара по классам и тексту карточки
+func domAvailability(card *goquery.Selection) models.ProductAvailability {
+	class := strings.ToLower(card.AttrOr("class", ""))
+	switch {
+	case strings.Contains(class, "outofstock"):
+		return models.AvailabilityOutOfStock
+	case strings.Contains(class, "instock"):
+		return models.AvailabilityInStock
+	}
+
+	return normalizeAvailability(card.Find("[class*='stock'], [class*='avail'], [class*='nalich']").First().Text())
+}
+
+// normalizeAvailability приводит наличие товара к общему виду
+func normalizeAvailability(value string) models.ProductAvailability {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch {
+	case value == "":
+		return ""
+	case strings.Contains(value, "outofstock"), strings.Contains(value, "soldout"),
+		strings.Contains(value, "нет в наличии"), strings.Contains(value, "распродан"):
+		return models.AvailabilityOutOfStock
+	case strings.Contains(value, "preorder"), strings.Contains(value, "под заказ"), strings.Contains(value, "предзаказ"):
+		return models.AvailabilityPreOrder
+	case strings.Contains(value, "instock"), strings.Contains(value, "в наличии"):
+		return models.AvailabilityInStock
+	}
+	return models.ProductAvailability(value)
+}
+
+// ParsePrice извлекает числовое значение цены и код валюты из текста
+func ParsePrice(text string) (float64, string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, ""
+	}
+
+	var currency string
+	lower := strings.ToLower(text)
+	for _, symbol := range currencySymbols {
+		if symbol.afterNumber && followsNumber(lower, symbol.pattern) ||
+			!symbol.afterNumber && strings.Contains(lower, symbol.pattern) {
+			currency = symbol.code
+			break
+		}
+	}
+
+	match := priceNumberRegexp.FindString(text)
+	if match == "" {
+		return 0, currency
+	}
+
+	price, err := parsePriceNumber(match)
+	if err != nil {
+		return 0, currency
+	}
+
+	return price, currency
+}
+
+// parsePriceNumber разбирает число цены. Последняя точка или запятая десятичная, только если
+// за ней одна или две цифры: "1,234.56" — 1234.56, "12.990" — 12990. Остальные разделители
+// и пробелы отделяют разряды
+func parsePriceNumber(match string) (float64, error) {
+	number := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(priceSpaces, r) {
+			return -1
+		}
+		return r
+	}, match)
+
+	integer, fraction := number, ""
+	if i := strings.LastIndexAny(number, ".,"); i >= 0 && len(number)-i-1 <= 2 {
+		integer, fraction = number[:i], number[i+1:]
+	}
+	integer = strings.NewReplacer(".", "", ",", "").Replace(integer)
+
+	if fraction != "" {
+		return strconv.ParseFloat(integer+"."+fraction, 64)
+	}
+	return strconv.ParseFloat(integer, 64)
+}
+
+// followsNumber проверяет, встречается ли pattern в тексте сразу после числа, возможно через пробел
+func followsNumber(text, pattern string) bool {
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], pattern)
+		if i < 0 {
+			return false
+		}
+		before := strings.TrimRight(text[:offset+i], priceSpaces)
+		if before != "" && before[len(before)-1] >= '0' && before[len(before)-1] <= '9' {
+			return true
+		}
+		offset += i + len(pattern)
+	}
+}
@@ -0,0 +1,42 @@
+package platforms
+
+import "testing"
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		text     string
+		price    float64
+		currency string
+	}{
+		{"", 0, ""},
+		{"1990 ₽", 1990, "RUB"},
+		{"1 990 руб.", 1990, "RUB"},
+		{"1 990,50 ₽", 1990.5, "RUB"},
+		{"990 р.", 990, "RUB"},
+		{"1 990р.", 1990, "RUB"},
+		// "р." внутри слова — не валюта
+		{"пр. 5", 5, ""},
+		{"Цена: 1,5", 1.5, ""},
+		// Разделители разрядов
+		{"12.990 ₽", 12990, "RUB"},
+		{"12,990 ₽", 12990, "RUB"},
+		{"1.234.567 ₽", 1234567, "RUB"},
+		{"1,234.56 $", 1234.56, "USD"},
+		{"1.234,56 €", 1234.56, "EUR"},
+		{"$1,234,567.8", 1234567.8, "USD"},
+		{"1 234 567.89 ₸", 1234567.89, "KZT"},
+		{"99.90 BYN", 99.9, "BYN"},
+		{"25 бел. руб.", 25, "BYN"},
+		{"150 грн", 150, "UAH"},
+		{"по запросу", 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			price, currency := ParsePrice(tt.text)
+			if price != tt.price || currency != tt.currency {
+				t.Errorf("ParsePrice(%q) = %v, %q, want %v, %q", tt.text, price, currency, tt.price, tt.currency)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+
+	"website-scraper/internal/models"
+)
+
+// productRow представляет строку экспорта товаров
+type productRow struct {
+	OperationID  uuid.UUID
+	PageURL      string
+	BlockID      uuid.UUID
+	TemplateName string
+	Product      models.Product
+}
+
+// productExportHeaders содержит заголовки колонок экспорта товаров
+var productExportHeaders = []string{
+	"Operation ID", "Page URL", "Block ID", "Template", "Name", "Price", "Old Price",
+	"Currency", "Image", "Link", "SKU", "Availability", "Source",
+}
+
+// ExportProducts экспортирует товары, найденные в операциях, в CSV или Excel
+func (s *parserService) ExportProducts(ctx context.Context, operationIDs []uuid.UUID, format string) ([]byte, string, error) {
+	if len(operationIDs) == 0 {
+		return nil, "", fmt.Errorf("no operations specified")
+	}
+
+	var rows []productRow
+	for _, operationID := range operationIDs {
+		result, err := s.GetOperationResult(ctx, operationID)
+		if err != nil {
+			return nil, "", err
+		}
+
+		for _, block := range result.Blocks {
+			products := blockProducts(block)
+			templateName := ""
+			if content, ok := block.Content.(map[string]interface{}); ok {
+				templateName, _ = content["template_name"].(string)
+			}
+
+			for _, product := range products {
+				product.Image = resolveURL(result.Operation.URL, product.Image)
+				product.Link = resolveURL(result.Operation.URL, product.Link)
+				rows = append(rows, productRow{
+					OperationID:  operationID,
+					PageURL:      result.Operation.URL,
+					BlockID:      block.ID,
+					TemplateName: templateName,
+					Product:      product,
+				})
+			}
+		}
+	}
+
+	// Имя файла строится по первой операции
+	baseName := "products_" + operationIDs[0].String()
+	if len(operationIDs) > 1 {
+		baseName = fmt.Sprintf("products_%d_operations", len(operationIDs))
+	}
+
+	switch format {
+	case "csv":
+		var buffer bytes.Buffer
+		writer := csv.NewWriter(&buffer)
+		if err := writer.Write(productExportHeaders); err != nil {
+			return nil, "", fmt.Errorf("failed to write CSV header: %w", err)
+		}
+		for _, row := range rows {
+			if err := writer.Write(productRowValues(row)); err != nil {
+				return nil, "", fmt.Errorf("failed to write CSV row: %w", err)
+			}
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return nil, "", fmt.Errorf("failed to write CSV file: %w", err)
+		}
+
+		return buffer.Bytes(), baseName + ".csv", nil
+
+	case "excel":
+		f := excelize.NewFile()
+		defer f.Close()
+
+		sheet := "Товары"
+		f.SetSheetName("Sheet1", sheet)
+
+		for i, header := range productExportHeaders {
+			cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+			f.SetCellValue(sheet, cell, header)
+		}
+
+		for i, row := range rows {
+			for j, value := range productRowValues(row) {
+				cell, _ := excelize.CoordinatesToCellName(j+1, i+2)
+				f.SetCellValue(sheet, cell, value)
+			}
+			// Цены записываем числами, чтобы по ним можно было считать
+			if row.Product.Price > 0 {
+				f.SetCellValue(sheet, fmt.Sprintf("F%d", i+2), row.Product.Price)
+			}
+			if row.Product.OldPrice > 0 {
+				f.SetCellValue(sheet, fmt.Sprintf("G%d", i+2), row.Product.OldPrice)
+			}
+		}
+
+		f.SetColWidth(sheet, "A", "C", 36)
+		f.SetColWidth(sheet, "E", "E", 40)
+		f.SetColWidth(sheet, "I", "J", 50)
+
+		buffer, err := f.WriteToBuffer()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to write Excel file: %w", err)
+		}
+
+		return buffer.Bytes(), baseName + ".xlsx", nil
+
+	default:
+		return nil, "", fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// blockProducts извлекает товары из контента блока
+func blockProducts(block models.Block) []models.Product {
+	var products []models.Product
+	decodeContentField(block.Content, "products", &products)
+	return products
+}
+
+// decodeContentField декодирует поле контента блока в типизированную структуру.
+// После чтения из БД контент хранится как map[string]interface{}, поэтому преобразуем через JSON
+func decodeContentField(content interface{}, key string, target interface{}) bool {
+	contentMap, ok := content.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	value, exists := contentMap[key]
+	if !exists || value == nil {
+		return false
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+
+	return json.Unmarshal(data, target) == nil
+}
+
+// productRowValues возвращает значения колонок строки экспорта
+func productRowValues(row productRow) []string {
+	formatPrice := func(price float64) string {
+		if price == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(price, 'f', -1, 64)
+	}
+
+	return []string{
+		row.OperationID.String(),
+		row.PageURL,
+		row.BlockID.String(),
+		row.TemplateName,
+		row.Product.Name,
+		formatPrice(row.Product.Price),
+		formatPrice(row.Product.OldPrice),
+		row.Product.Currency,
+		row.Product.Image,
+		row.Product.Link,
+		row.Product.SKU,
+		string(row.Product.Availability),
+		row.Product.Source,
+	}
+}
+
+// resolveURL приводит ссылку к абсолютному виду относительно URL страницы
+func resolveURL(pageURL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return ref
+	}
+
+	parsed, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+
+	return base.ResolveReference(parsed).String()
+}
@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+
+	"website-scraper/internal/models"
+)
+
+// ReparseOperation повторно разбирает сохраненный HTML операции текущими шаблонами.
+// Результат записывается в новую операцию, связанную с исходной; страница заново не загружается
+func (s *parserService) ReparseOperation(ctx context.Context, operationID uuid.UUID, opts models.ParseOptions) (uuid.UUID, error) {
+	parent, err := s.repo.GetOperationByID(ctx, operationID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	html, err := s.downloader.LoadSnapshot(ctx, parent.ID, parent.URL)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("сохраненный HTML операции не найден: %w", err)
+	}
+
+	newOperationID, err := s.repo.CreateDerivedOperation(ctx, parent.URL, parent.ID, models.SourceReparse)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := s.repo.UpdateOperationStatus(ctx, newOperationID, models.StatusProcessing); err != nil {
+		return newOperationID, err
+	}
+
+	// HTML снят на устройстве исходной операции, блоки помечаются тем же устройством
+	if opts.Device == nil {
+		opts.Device = parent.Device
+	}
+	s.saveOperationDevice(ctx, newOperationID, opts.Device)
+
+	go func() {
+		goCtx := context.Background()
+
+		// Снимок нужен новой операции для следующих повторных разборов и автономных копий блоков
+		if err := s.downloader.SaveSnapshot(goCtx, newOperationID, html); err != nil {
+			log.Printf("Error saving HTML snapshot: %v", err)
+		}
+
+		if _, err := s.processHTML(goCtx, newOperationID, parent.URL, html, opts); err != nil {
+			log.Printf("Error reparsing operation %s: %v", parent.ID, err)
+			s.repo.UpdateOperationStatus(goCtx, newOperationID, models.StatusError)
+			return
+		}
+
+		if err := s.repo.UpdateOperationStatus(goCtx, newOperationID, models.StatusCompleted); err != nil {
+			log.Printf("Error updating operation status: %v", err)
+		}
+	}()
+
+	return newOperationID, nil
+}
@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"context"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"website-scraper/internal/models"
+)
+
+// searchAttributes перечисляет атрибуты, текст которых видит или слышит посетитель и который
+// попадает в полнотекстовый индекс вместе с текстом блока
+var searchAttributes = map[string]bool{
+	"alt":         true,
+	"title":       true,
+	"placeholder": true,
+	"aria-label":  true,
+}
+
+// searchTokenAttributes перечисляет атрибуты разметки, по которым блок можно найти по HTML:
+// классы и идентификаторы оформления, адреса ссылок и изображений, имена полей форм
+var searchTokenAttributes = map[string]bool{
+	"class":  true,
+	"id":     true,
+	"href":   true,
+	"src":    true,
+	"action": true,
+	"name":   true,
+}
+
+// blockSearchText возвращает текст блока для полнотекстового индекса: видимый текст без скриптов
+// и стилей, значения текстовых атрибутов, например подписи изображений и подсказки полей,
+// и токены разметки — классы, адреса ссылок и имена полей. Используется и при сохранении блока,
+// и при фоновом заполнении индекса старых блоков
+func blockSearchText(source string) string {
+	nodes, err := html.ParseFragment(strings.NewReader(source), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return ""
+	}
+
+	var parts []string
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		switch node.Type {
+		case html.TextNode:
+			if text := strings.TrimSpace(node.Data); text != "" {
+				parts = append(parts, text)
+			}
+			return
+		case html.ElementNode:
+			switch node.Data {
+			case "script", "style", "noscript", "template":
+				return
+			}
+			for _, attr := range node.Attr {
+				if (searchAttributes[attr.Key] || searchTokenAttributes[attr.Key]) && strings.TrimSpace(attr.Val) != "" {
+					parts = append(parts, strings.TrimSpace(attr.Val))
+				}
+			}
+		default:
+			return
+		}
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+
+	for _, node := range nodes {
+		walk(node)
+	}
+	return strings.Join(parts, " ")
+}
+
+// SearchBlocks ищет блоки всех сохраненных операций по тексту, платформе, типу, шаблону, сайту и времени
+func (s *parserService) SearchBlocks(ctx context.Context, query models.BlockSearchQuery) (*models.BlockSearchResponse, error) {
+	query.Query = strings.TrimSpace(query.Query)
+	query.TemplateName = strings.TrimSpace(query.TemplateName)
+	query.Domain = normalizeDomain(query.Domain)
+
+	return s.repo.SearchBlocks(ctx, query)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/xuri/excelize/v2"
 
+	"website-scraper/internal/assets"
 	"website-scraper/internal/downloader"
 	"website-scraper/internal/models"
 	"website-scraper/internal/parser/platforms"
@@ -49,7 +50,7 @@ func NewParserService(
 }
 
 // ParseURL парсит URL и сохраняет результаты в базу данных
-func (s *parserService) ParseURL(ctx context.Context, url string) (uuid.UUID, error) {
+func (s *parserService) ParseURL(ctx context.Context, url string, opts models.ParseOptions) (uuid.UUID, error) {
 	// Создаем операцию в БД
 	operationID, err := s.repo.CreateOperation(ctx, url)
 	if err != nil {
@@ -62,116 +63,64 @@ func (s *parserService) ParseURL(ctx context.Context, url string) (uuid.UUID, er
 		return operationID, err
 	}
 
+	s.saveOperationDevice(ctx, operationID, opts.Device)
+
 	// Запускаем парсинг в отдельной горутине
 	go func() {
 		// Создаем новый контекст для горутины
 		goCtx := context.Background()
 
 		// Загружаем страницу
-		html, err := s.downloader.DownloadPage(goCtx, url)
+		page, err := s.downloader.Fetch(goCtx, url, opts)
 		if err != nil {
 			log.Printf("Error downloading %s: %v", url, err)
 			s.repo.UpdateOperationStatus(goCtx, operationID, models.StatusError)
 			return
 		}
+		// Вкладка браузера остается открытой для снимков блоков до конца разбора
+		defer page.Close()
+		html := page.HTML
 
-		// Определяем платформу сайта
-		platform := s.DetectPlatform(html)
-
-		// Парсим шапку и подвал в зависимости от платформы
-		var headerBlock, footerBlock *models.Block
-
-		switch platform {
-		case models.PlatformWordPress:
-			headerBlock, err = s.wordpressParser.ParseHeader(html)
-			if err != nil {
-				log.Printf("Error parsing WordPress header: %v", err)
-			}
-
-			footerBlock, err = s.wordpressParser.ParseFooter(html)
-			if err != nil {
-				log.Printf("Error parsing WordPress footer: %v", err)
-			}
-		case models.PlatformTilda:
-			headerBlock, err = s.tildaParser.ParseHeader(html)
-			if err != nil {
-				log.Printf("Error parsing Tilda header: %v", err)
-			}
+		if err := s.repo.UpdateOperationFetchStrategy(goCtx, operationID, page.Strategy); err != nil {
+			log.Printf("Error saving fetch strategy: %v", err)
+		}
 
-			footerBlock, err = s.tildaParser.ParseFooter(html)
-			if err != nil {
-				log.Printf("Error parsing Tilda footer: %v", err)
-			}
-		case models.PlatformBitrix:
-			headerBlock, err = s.bitrixParser.ParseHeader(html)
-			if err != nil {
-				log.Printf("Error parsing Bitrix header: %v", err)
+		// HAR сохраняется и для страниц с ошибкой, чтобы было видно, что загрузил браузер
+		if page.HAR != nil {
+			if err := s.downloader.SaveHAR(goCtx, operationID, page.HAR); err != nil {
+				log.Printf("Error saving HAR: %v", err)
 			}
+		}
 
-			footerBlock, err = s.bitrixParser.ParseFooter(html)
-			if err != nil {
-				log.Printf("Error parsing Bitrix footer: %v", err)
-			}
-		case models.PlatformHTML5:
-			var blocks []*models.Block
-			templates, err := s.templateService.GetTemplates(platform)
-			if err != nil {
-				log.Printf("Error getting templates: %v", err)
-				s.repo.UpdateOperationStatus(goCtx, operationID, models.StatusError)
-				return
+		if page.Response != nil {
+			if err := s.repo.UpdateOperationResponse(goCtx, operationID, page.Response); err != nil {
+				log.Printf("Error saving response metadata: %v", err)
 			}
 
-			blocks, err = s.html5Parser.ParseAndClassifyPage(html, templates)
-			if err != nil {
-				log.Printf("Error parsing HTML5 page: %v", err)
+			// Страница ошибки (404, 500, заглушка) не должна разбираться как настоящая
+			if page.Response.IsError && !opts.AllowHTTPErrors {
+				log.Printf("Page %s returned status %d, operation failed", url, page.Response.StatusCode)
 				s.repo.UpdateOperationStatus(goCtx, operationID, models.StatusError)
 				return
 			}
+		}
 
-			// Сохраняем найденные блоки в БД
-			for _, block := range blocks {
-				if block != nil {
-					block.OperationID = operationID
-
-					err = s.repo.SaveBlock(goCtx, block)
-					if err != nil {
-						log.Printf("Error saving block: %v", err)
-					}
-
-					// Сохраняем блок на диск
-					if err := s.downloader.SaveBlock(block); err != nil {
-						log.Printf("Error saving block to disk: %v", err)
-					}
-				}
-			}
+		if err := s.downloader.SaveSnapshot(goCtx, operationID, html); err != nil {
+			log.Printf("Error saving HTML snapshot: %v", err)
 		}
 
-		// Сохраняем найденные блоки в БД
-		if headerBlock != nil {
-			headerBlock.OperationID = operationID
-			err = s.repo.SaveBlock(goCtx, headerBlock)
-			if err != nil {
-				log.Printf("Error saving header block: %v", err)
-			} else {
-				// Сохраняем блок на диск
-				if err := s.downloader.SaveBlock(headerBlock); err != nil {
-					log.Printf("Error saving header block to disk: %v", err)
-				}
-			}
+		found, err := s.processHTML(goCtx, operationID, url, html, opts)
+		if err != nil {
+			log.Printf("Error processing %s: %v", url, err)
+			s.repo.UpdateOperationStatus(goCtx, operationID, models.StatusError)
+			return
 		}
 
-		if footerBlock != nil {
-			footerBlock.OperationID = operationID
-			err = s.repo.SaveBlock(goCtx, footerBlock)
-			if err != nil {
-				log.Printf("Error saving footer block: %v", err)
-			} else {
-				// Сохраняем блок на диск
-				if err := s.downloader.SaveBlock(footerBlock); err != nil {
-					log.Printf("Error saving footer block to disk: %v", err)
-				}
-			}
+		// Для страниц, загруженных по HTTP, браузер не запускается и снимки не делаются
+		if s.downloader.ScreenshotsEnabled() && page.Strategy == models.FetchBrowser {
+			s.captureVisuals(goCtx, operationID, page, found)
 		}
+		page.Close()
 
 		// Обновляем статус операции
 		err = s.repo.UpdateOperationStatus(goCtx, operationID, models.StatusCompleted)
@@ -183,6 +132,147 @@ func (s *parserService) ParseURL(ctx context.Context, url string) (uuid.UUID, er
 	return operationID, nil
 }
 
+// processHTML определяет платформу, выделяет блоки из HTML страницы и сохраняет их в операцию
+func (s *parserService) processHTML(ctx context.Context, operationID uuid.UUID, url, html string, opts models.ParseOptions) ([]*models.Block, error) {
+	var err error
+
+	// Определяем платформу сайта
+	platform := s.DetectPlatform(html)
+
+	// Парсим шапку и подвал в зависимости от платформы
+	var headerBlock, footerBlock *models.Block
+	var found []*models.Block
+
+	switch platform {
+	case models.PlatformWordPress:
+		headerBlock, err = s.wordpressParser.ParseHeader(html)
+		if err != nil {
+			log.Printf("Error parsing WordPress header: %v", err)
+		}
+
+		footerBlock, err = s.wordpressParser.ParseFooter(html)
+		if err != nil {
+			log.Printf("Error parsing WordPress footer: %v", err)
+		}
+	case models.PlatformTilda:
+		headerBlock, err = s.tildaParser.ParseHeader(html)
+		if err != nil {
+			log.Printf("Error parsing Tilda header: %v", err)
+		}
+
+		footerBlock, err = s.tildaParser.ParseFooter(html)
+		if err != nil {
+			log.Printf("Error parsing Tilda footer: %v", err)
+		}
+	case models.PlatformBitrix:
+		headerBlock, err = s.bitrixParser.ParseHeader(html)
+		if err != nil {
+			log.Printf("Error parsing Bitrix header: %v", err)
+		}
+
+		footerBlock, err = s.bitrixParser.ParseFooter(html)
+		if err != nil {
+			log.Printf("Error parsing Bitrix footer: %v", err)
+		}
+	case models.PlatformHTML5:
+		var blocks []*models.Block
+		templates, err := s.templateService.GetTemplates(platform)
+		if err != nil {
+			return nil, fmt.Errorf("error getting templates: %w", err)
+		}
+
+		blocks, err = s.html5Parser.ParseAndClassifyPage(html, templates)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing HTML5 page: %w", err)
+		}
+
+		for _, block := range blocks {
+			if block != nil {
+				found = append(found, block)
+			}
+		}
+	}
+
+	if headerBlock != nil {
+		platforms.AttachForms(headerBlock)
+		found = append(found, headerBlock)
+	}
+	if footerBlock != nil {
+		platforms.AttachForms(footerBlock)
+		found = append(found, footerBlock)
+	}
+
+	// Парсеры платформ разбирают только шапку и подвал: формы и товары остальной страницы
+	// ищутся в ее секциях
+	switch platform {
+	case models.PlatformWordPress, models.PlatformTilda, models.PlatformBitrix:
+		// Без шаблонов секции классифицируются эвристиками
+		templates, err := s.templateService.GetTemplates(platform)
+		if err != nil {
+			log.Printf("Error getting %s templates: %v", platform, err)
+		}
+
+		blocks, err := s.html5Parser.ParseFormAndProductBlocks(html, templates, platform)
+		if err != nil {
+			log.Printf("Error parsing %s forms and products: %v", platform, err)
+		}
+		found = append(found, blocks...)
+	}
+
+	// Стили страницы нужны только для автономных копий блоков
+	var styles *downloader.PageStyles
+	if opts.MirrorAssets && len(found) > 0 {
+		styles = s.downloader.CollectPageStyles(ctx, url, html)
+	}
+
+	// Запоминаем положение блоков в документе для снимков в браузере
+	attachSelectors(html, found)
+
+	// Сохраняем найденные блоки в БД и на диск
+	for _, block := range found {
+		block.OperationID = operationID
+		block.Viewport = downloader.Viewport(opts.Device)
+		s.saveBlock(ctx, block, url, styles)
+	}
+
+	return found, nil
+}
+
+// saveOperationDevice сохраняет в операцию эмулированное устройство, если оно задано
+func (s *parserService) saveOperationDevice(ctx context.Context, operationID uuid.UUID, device *models.DeviceOptions) {
+	if device == nil {
+		return
+	}
+	if err := s.repo.UpdateOperationDevice(ctx, operationID, device); err != nil {
+		log.Printf("Error saving operation device: %v", err)
+	}
+}
+
+// saveBlock сохраняет блок в БД и на диск. Если переданы стили страницы,
+// рядом сохраняется автономная копия блока с локальными ресурсами
+func (s *parserService) saveBlock(ctx context.Context, block *models.Block, pageURL string, styles *downloader.PageStyles) {
+	attachAssets(block, pageURL)
+	block.Fingerprint = blockFingerprint(block.HTML)
+	block.StructureSignature = structureSignature(block.HTML)
+	block.SearchText = blockSearchText(block.HTML)
+
+	// Файлы блока без записи в БД не сохраняем: их нельзя ни скачать, ни удалить вместе с операцией
+	if err := s.repo.SaveBlock(ctx, block); err != nil {
+		log.Printf("Error saving %s block: %v", block.BlockType, err)
+		return
+	}
+
+	if err := s.downloader.SaveBlock(ctx, block); err != nil {
+		log.Printf("Error saving %s block to disk: %v", block.BlockType, err)
+	}
+
+	if styles != nil {
+		if err := s.downloader.SaveStandaloneBlock(ctx, block, pageURL, styles); err != nil {
+			log.Printf("Error mirroring %s block assets: %v", block.BlockType, err)
+		}
+	}
+}
+
 // GetOperationResult получает результаты операции по ID
 func (s *parserService) GetOperationResult(ctx context.Context, operationID uuid.UUID) (*models.GetOperationResultResponse, error) {
 	// Получаем операцию из БД
@@ -199,8 +289,9 @@ func (s *parserService) GetOperationResult(ctx context.Context, operationID uuid
 
 	// Формируем ответ
 	response := &models.GetOperationResultResponse{
-		Operation: *operation,
-		Blocks:    blocks,
+		Operation:   *operation,
+		Blocks:      blocks,
+		AssetTotals: assets.Totals(operationBlockAssets(blocks, operation.URL)),
 	}
 
 	return response, nil
@@ -341,31 +432,10 @@ func (s *parserService) GetBlocksByOperationID(ctx context.Context, operationID
 
 // GetBlockByID получает конкретный блок по ID
 func (s *parserService) GetBlockByID(ctx context.Context, blockID uuid.UUID) (*models.Block, error) {
-	// Этот метод нужно реализовать в репозитории
-	// Временная реализация - ищем среди всех операций
-
-	operations, err := s.repo.GetAllOperations(ctx) // Если этого метода нет, нужно его добавить
-	if err != nil {
-		return nil, err
-	}
-
-	for _, op := range operations {
-		blocks, err := s.repo.GetBlocksByOperationID(ctx, op.ID)
-		if err != nil {
-			continue
-		}
-
-		for _, block := range blocks {
-			if block.ID == blockID {
-				return &block, nil
-			}
-		}
-	}
-
-	return nil, fmt.Errorf("block not found: %s", blockID)
+	return s.repo.GetBlockByID(ctx, blockID)
 }
 
-// SaveBlocks сохраняет блоки на диск
+// SaveBlocks сохраняет файлы блоков в хранилище
 func (s *parserService) SaveBlocks(ctx context.Context, blocks []models.Block) error {
-	return s.downloader.SaveBlocks(blocks)
+	return s.downloader.SaveBlocks(ctx, blocks)
 }
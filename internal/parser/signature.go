@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"website-scraper/internal/models"
+)
+
+// MaxStructureDistance задает наибольшее расстояние поиска: подпись индексируется частями по 8 бит,
+// и при большем расстоянии похожие блоки могут не найтись
+const MaxStructureDistance = 7
+
+// generatedNumber находит длинные числа в классах: идентификаторы элементов конструкторов
+// вроде tn-elem__1234567 различаются на каждом сайте, а шаблон остается тем же
+var generatedNumber = regexp.MustCompile(`[0-9]{5,}`)
+
+// structureSignature возвращает simhash блока только по дереву тегов и словарю классов.
+// Текст, изображения, скрипты и стили не учитываются, поэтому блоки одного шаблона
+// с разным содержимым получают одинаковые или близкие подписи. Для блока без тегов возвращается 0
+func structureSignature(source string) uint64 {
+	nodes, err := html.ParseFragment(strings.NewReader(source), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return 0
+	}
+
+	// Признаки учитываются без повторов: число пунктов меню или карточек в списке
+	// зависит от содержимого, а не от шаблона
+	seen := make(map[string]bool)
+	var features []string
+	add := func(feature string) {
+		if !seen[feature] {
+			seen[feature] = true
+			features = append(features, feature)
+		}
+	}
+
+	var walk func(node *html.Node, parent string, depth int)
+	walk = func(node *html.Node, parent string, depth int) {
+		if node.Type != html.ElementNode {
+			return
+		}
+		switch node.Data {
+		case "img", "picture", "source", "svg", "video", "audio", "canvas", "iframe",
+			"script", "style", "noscript", "template":
+			return
+		}
+
+		var names []string
+		for _, attr := range node.Attr {
+			if attr.Key == "class" {
+				for _, class := range strings.Fields(attr.Val) {
+					names = append(names, generatedNumber.ReplaceAllString(class, "#"))
+				}
+				break
+			}
+		}
+		sort.Strings(names)
+		for _, class := range names {
+			add("class:" + class)
+		}
+
+		element := node.Data
+		if len(names) > 0 {
+			element += "." + strings.Join(names, ".")
+		}
+		add(fmt.Sprintf("tag:%d:%s", depth, element))
+		add("edge:" + parent + ">" + element)
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child, element, depth+1)
+		}
+	}
+
+	for _, node := range nodes {
+		walk(node, "", 0)
+	}
+
+	return simhash(features)
+}
+
+// SimilarBlocks находит блоки всех операций, построенные по той же структуре, что и исходный блок.
+// Подпись блоков, сохраненных до ее появления, вычисляется по их HTML
+func (s *parserService) SimilarBlocks(ctx context.Context, blockID uuid.UUID, query models.SimilarBlocksQuery) (*models.SimilarBlocksResponse, error) {
+	if query.MaxDistance < 0 || query.MaxDistance > MaxStructureDistance {
+		return nil, fmt.Errorf("max_distance must be between 0 and %d", MaxStructureDistance)
+	}
+
+	block, err := s.repo.GetBlockByID(ctx, blockID)
+	if err != nil {
+		return nil, err
+	}
+	if block.StructureSignature == 0 {
+		block.StructureSignature = structureSignature(block.HTML)
+	}
+	if block.StructureSignature == 0 {
+		return nil, fmt.Errorf("block %s has no markup to compare", blockID)
+	}
+
+	blocks, err := s.repo.FindSimilarBlocks(ctx, block, query)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.SimilarBlocksResponse{
+		BlockID:            block.ID,
+		OperationID:        block.OperationID,
+		BlockType:          block.BlockType,
+		StructureSignature: block.StructureSignature,
+		MaxDistance:        query.MaxDistance,
+		Domains:            make(map[string]int),
+		Blocks:             blocks,
+		Count:              len(blocks),
+	}
+	for _, similar := range blocks {
+		response.Domains[similar.Domain]++
+	}
+
+	return response, nil
+}
@@ -0,0 +1,208 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/google/uuid"
+	"golang.org/x/net/html/charset"
+
+	"website-scraper/internal/models"
+)
+
+// maxUploadPageSize ограничивает размер HTML-страницы, извлекаемой из архива
+const maxUploadPageSize = 20 << 20
+
+// ErrInvalidUpload возвращается, если из переданного файла нельзя получить HTML-страницу
+var ErrInvalidUpload = errors.New("некорректный файл страницы")
+
+// ParseUpload разбирает страницу, переданную пользователем, так же как загруженную по URL.
+// Результат сохраняется обычной операцией с источником upload
+func (s *parserService) ParseUpload(ctx context.Context, upload models.UploadedPage, opts models.ParseOptions) (uuid.UUID, error) {
+	html, name, err := readUpload(upload)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	// Без явного адреса берем адрес, указанный в самой странице
+	pageURL := upload.URL
+	if pageURL == "" {
+		pageURL = pageURLFromHTML(html)
+	}
+	if pageURL == "" {
+		pageURL = "upload://" + name
+	}
+
+	operationID, err := s.repo.CreateOperationFromSource(ctx, pageURL, models.SourceUpload)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := s.repo.UpdateOperationStatus(ctx, operationID, models.StatusProcessing); err != nil {
+		return operationID, err
+	}
+
+	// Устройство, на котором сохранена страница, указывается пользователем
+	s.saveOperationDevice(ctx, operationID, opts.Device)
+
+	go func() {
+		goCtx := context.Background()
+
+		if err := s.downloader.SaveSnapshot(goCtx, operationID, html); err != nil {
+			log.Printf("Error saving HTML snapshot: %v", err)
+		}
+
+		if _, err := s.processHTML(goCtx, operationID, pageURL, html, opts); err != nil {
+			log.Printf("Error processing uploaded page %s: %v", name, err)
+			s.repo.UpdateOperationStatus(goCtx, operationID, models.StatusError)
+			return
+		}
+
+		if err := s.repo.UpdateOperationStatus(goCtx, operationID, models.StatusCompleted); err != nil {
+			log.Printf("Error updating operation status: %v", err)
+		}
+	}()
+
+	return operationID, nil
+}
+
+// readUpload возвращает HTML страницы и ее имя. ZIP-архив распознается по сигнатуре
+func readUpload(upload models.UploadedPage) (string, string, error) {
+	if len(upload.Data) == 0 {
+		return "", "", fmt.Errorf("%w: файл пуст", ErrInvalidUpload)
+	}
+
+	if bytes.HasPrefix(upload.Data, []byte("PK\x03\x04")) {
+		return readZipUpload(upload)
+	}
+
+	name := upload.Filename
+	if name == "" {
+		name = "page.html"
+	}
+	return decodeHTML(upload.Data), name, nil
+}
+
+// readZipUpload извлекает страницу из архива сохраненного сайта. Если страница не указана,
+// выбирается HTML-файл с наименьшей вложенностью, при равенстве — index.html или самый большой
+func readZipUpload(upload models.UploadedPage) (string, string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(upload.Data), int64(len(upload.Data)))
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidUpload, err)
+	}
+
+	var pages []*zip.File
+	for _, file := range archive.File {
+		name := file.Name
+		if file.FileInfo().IsDir() || strings.HasPrefix(name, "__MACOSX/") {
+			continue
+		}
+
+		if upload.Entry != "" {
+			if path.Clean(name) == path.Clean(strings.TrimPrefix(upload.Entry, "/")) {
+				pages = []*zip.File{file}
+				break
+			}
+			continue
+		}
+
+		ext := strings.ToLower(path.Ext(name))
+		if ext == ".html" || ext == ".htm" {
+			pages = append(pages, file)
+		}
+	}
+
+	if len(pages) == 0 {
+		if upload.Entry != "" {
+			return "", "", fmt.Errorf("%w: в архиве нет файла %s", ErrInvalidUpload, upload.Entry)
+		}
+		return "", "", fmt.Errorf("%w: в архиве нет HTML-страниц", ErrInvalidUpload)
+	}
+
+	sort.SliceStable(pages, func(i, j int) bool {
+		di, dj := strings.Count(pages[i].Name, "/"), strings.Count(pages[j].Name, "/")
+		if di != dj {
+			return di < dj
+		}
+		ii, ij := isIndexPage(pages[i].Name), isIndexPage(pages[j].Name)
+		if ii != ij {
+			return ii
+		}
+		return pages[i].UncompressedSize64 > pages[j].UncompressedSize64
+	})
+
+	page := pages[0]
+	if page.UncompressedSize64 > maxUploadPageSize {
+		return "", "", fmt.Errorf("%w: страница %s больше %d МБ", ErrInvalidUpload, page.Name, maxUploadPageSize>>20)
+	}
+
+	reader, err := page.Open()
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidUpload, err)
+	}
+	defer reader.Close()
+
+	// Размер в заголовке архива может не совпадать с фактическим
+	data, err := io.ReadAll(io.LimitReader(reader, maxUploadPageSize+1))
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidUpload, err)
+	}
+	if len(data) > maxUploadPageSize {
+		return "", "", fmt.Errorf("%w: страница %s больше %d МБ", ErrInvalidUpload, page.Name, maxUploadPageSize>>20)
+	}
+
+	return decodeHTML(data), page.Name, nil
+}
+
+// isIndexPage проверяет, что файл является главной страницей директории
+func isIndexPage(name string) bool {
+	base := strings.ToLower(path.Base(name))
+	return base == "index.html" || base == "index.htm"
+}
+
+// decodeHTML переводит страницу в UTF-8 по BOM или кодировке, указанной в meta
+func decodeHTML(data []byte) string {
+	encoding, name, _ := charset.DetermineEncoding(data, "text/html")
+	if name == "utf-8" || encoding == nil {
+		return string(data)
+	}
+
+	decoded, err := encoding.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(decoded)
+}
+
+// pageURLFromHTML возвращает адрес страницы из canonical, og:url или base
+func pageURLFromHTML(html string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return ""
+	}
+
+	candidates := []string{
+		doc.Find(`link[rel="canonical"]`).AttrOr("href", ""),
+		doc.Find(`meta[property="og:url"]`).AttrOr("content", ""),
+		doc.Find("base[href]").AttrOr("href", ""),
+	}
+
+	for _, candidate := range candidates {
+		parsed, err := url.Parse(strings.TrimSpace(candidate))
+		if err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "" {
+			return parsed.String()
+		}
+	}
+
+	return ""
+}
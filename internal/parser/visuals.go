@@ -0,0 +1,151 @@
+package parser
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/google/uuid"
+
+	"website-scraper/internal/downloader"
+	"website-scraper/internal/models"
+)
+
+// cssIdentRegexp проверяет, можно ли использовать id элемента в селекторе без экранирования
+var cssIdentRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]*$`)
+
+// attachSelectors находит блоки в HTML страницы и сохраняет в их контенте CSS-селектор элемента.
+// Парсеры платформ возвращают внутренний или внешний HTML элемента, поэтому блок ищется по обоим
+func attachSelectors(pageHTML string, blocks []*models.Block) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(pageHTML))
+	if err != nil {
+		return
+	}
+
+	// Блоки с одинаковым HTML сопоставляются с элементами по порядку в документе
+	pending := make(map[string][]*models.Block)
+	for _, block := range blocks {
+		key := strings.TrimSpace(block.HTML)
+		if key != "" {
+			pending[key] = append(pending[key], block)
+		}
+	}
+
+	doc.Find("body, body *").EachWithBreak(func(i int, el *goquery.Selection) bool {
+		if len(pending) == 0 {
+			return false
+		}
+
+		inner, _ := el.Html()
+		outer, _ := goquery.OuterHtml(el)
+
+		for _, key := range []string{strings.TrimSpace(inner), strings.TrimSpace(outer)} {
+			queue, ok := pending[key]
+			if !ok {
+				continue
+			}
+
+			setContentField(queue[0], "selector", cssPath(el))
+			if len(queue) == 1 {
+				delete(pending, key)
+			} else {
+				pending[key] = queue[1:]
+			}
+			break
+		}
+
+		return true
+	})
+}
+
+// cssPath строит однозначный CSS-селектор элемента от ближайшего предка с id
+func cssPath(el *goquery.Selection) string {
+	var parts []string
+
+	for node := el; node.Length() > 0; node = node.Parent() {
+		name := goquery.NodeName(node)
+		if name == "html" || name == "#document" {
+			break
+		}
+		if name == "body" {
+			parts = append(parts, "body")
+			break
+		}
+
+		if id, exists := node.Attr("id"); exists && cssIdentRegexp.MatchString(id) {
+			parts = append(parts, "#"+id)
+			break
+		}
+
+		index := node.PrevAllFiltered(name).Length() + 1
+		parts = append(parts, name+":nth-of-type("+strconv.Itoa(index)+")")
+	}
+
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+
+	return strings.Join(parts, " > ")
+}
+
+// setContentField записывает значение в контент блока
+func setContentField(block *models.Block, key string, value interface{}) {
+	content, ok := block.Content.(map[string]interface{})
+	if !ok {
+		content = make(map[string]interface{})
+		block.Content = content
+	}
+	content[key] = value
+}
+
+// captureVisuals сохраняет снимок страницы, снимает блоки во вкладке, в которой загружена страница,
+// и дописывает в их контент положение, вычисленные стили и путь к скриншоту
+func (s *parserService) captureVisuals(ctx context.Context, operationID uuid.UUID, page *downloader.PageResult, blocks []*models.Block) {
+	if len(page.Screenshot) > 0 {
+		if _, err := s.downloader.SavePageScreenshot(ctx, operationID, page.Screenshot); err != nil {
+			log.Printf("Error saving page screenshot: %v", err)
+		}
+	}
+
+	selectors := make(map[uuid.UUID]string)
+	byID := make(map[uuid.UUID]*models.Block)
+	for _, block := range blocks {
+		if block.ID == uuid.Nil {
+			continue
+		}
+		if content, ok := block.Content.(map[string]interface{}); ok {
+			if selector, ok := content["selector"].(string); ok && selector != "" {
+				selectors[block.ID] = selector
+				byID[block.ID] = block
+			}
+		}
+	}
+
+	if len(selectors) == 0 {
+		return
+	}
+
+	visuals, err := s.downloader.CaptureBlocks(ctx, page, operationID, selectors)
+	if err != nil {
+		log.Printf("Error capturing blocks: %v", err)
+		return
+	}
+
+	for blockID, visual := range visuals {
+		block := byID[blockID]
+		setContentField(block, "visual", visual)
+
+		if err := s.repo.UpdateBlockContent(ctx, block.ID, block.Content); err != nil {
+			log.Printf("Error updating block %s content: %v", block.ID, err)
+			continue
+		}
+
+		// Обновляем метаданные блока в хранилище
+		if err := s.downloader.SaveBlock(ctx, block); err != nil {
+			log.Printf("Error saving block %s to disk: %v", block.ID, err)
+		}
+	}
+}
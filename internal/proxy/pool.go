@@ -0,0 +1,340 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"website-scraper/internal/config"
+	"website-scraper/internal/models"
+)
+
+// checkTimeout ограничивает время проверки одного прокси
+const checkTimeout = 10 * time.Second
+
+// Pool выбирает прокси для загрузок из списка в конфигурации. Прокси используются по очереди,
+// за доменом закрепляется прокси его первой загрузки, а прокси с ошибками подряд исключаются
+// из выбора до успешной проверки
+type Pool struct {
+	cfg *config.Config
+
+	mu      sync.Mutex
+	entries []*entry
+	// next содержит позицию следующего прокси при поочередном выборе
+	next int
+	// pins содержит прокси, закрепленные за доменами
+	pins map[string]*entry
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+// entry представляет прокси пула и его состояние
+type entry struct {
+	proxy     *Proxy
+	transport *http.Transport
+
+	healthy   bool
+	failures  int
+	requests  int64
+	errors    int64
+	lastError string
+	checkedAt time.Time
+}
+
+// NewPool создает пул из прокси конфигурации. Некорректные адреса пропускаются
+func NewPool(cfg *config.Config) *Pool {
+	pool := &Pool{
+		cfg:  cfg,
+		pins: make(map[string]*entry),
+		stop: make(chan struct{}),
+	}
+
+	for _, raw := range cfg.Proxy.URLs {
+		proxy, err := Parse(raw)
+		if err != nil {
+			// Адрес может содержать пароль, поэтому в лог пишем только ошибку
+			log.Printf("Прокси из конфигурации пропущен: %v", err)
+			continue
+		}
+		pool.entries = append(pool.entries, &entry{
+			proxy:     proxy,
+			transport: proxy.Transport(),
+			healthy:   true,
+		})
+	}
+
+	if len(pool.entries) > 0 {
+		log.Printf("Пул прокси: %d, поочередный выбор: %t, закрепление доменов: %t",
+			len(pool.entries), cfg.Proxy.Rotate, cfg.Proxy.PinDomains)
+	}
+
+	return pool
+}
+
+// Start запускает периодическую проверку прокси
+func (p *Pool) Start() {
+	interval := p.cfg.Proxy.CheckInterval
+	if interval <= 0 || len(p.entries) == 0 || p.cfg.Proxy.CheckURL == "" {
+		return
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.checkHealth()
+			}
+		}
+	}()
+}
+
+// Close останавливает проверку прокси и закрывает соединения
+func (p *Pool) Close() {
+	p.once.Do(func() {
+		close(p.stop)
+		p.wg.Wait()
+
+		for _, e := range p.entries {
+			e.transport.CloseIdleConnections()
+		}
+	})
+}
+
+// Select возвращает прокси для загрузки адреса. override задает прокси запроса: адрес прокси
+// или direct. Без прокси в запросе и в конфигурации возвращается nil
+func (p *Pool) Select(pageURL, override string) (*Proxy, error) {
+	switch override {
+	case "":
+	case Direct:
+		return nil, nil
+	default:
+		return Parse(override)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.entries) == 0 {
+		return nil, nil
+	}
+
+	domain := domainOf(pageURL)
+	if p.cfg.Proxy.PinDomains && domain != "" {
+		if pinned, ok := p.pins[domain]; ok && pinned.healthy {
+			pinned.requests++
+			return pinned.proxy, nil
+		}
+	}
+
+	chosen := p.pick()
+	if p.cfg.Proxy.PinDomains && domain != "" {
+		p.pins[domain] = chosen
+	}
+	chosen.requests++
+
+	return chosen.proxy, nil
+}
+
+// pick выбирает доступный прокси. Если доступных нет, берется прокси с наименьшим числом
+// ошибок подряд, чтобы загрузки не останавливались. Вызывается под блокировкой
+func (p *Pool) pick() *entry {
+	count := len(p.entries)
+	start := 0
+	if p.cfg.Proxy.Rotate {
+		start = p.next
+	}
+
+	for i := 0; i < count; i++ {
+		e := p.entries[(start+i)%count]
+		if !e.healthy {
+			continue
+		}
+		if p.cfg.Proxy.Rotate {
+			p.next = (start + i + 1) % count
+		}
+		return e
+	}
+
+	best := p.entries[0]
+	for _, e := range p.entries[1:] {
+		if e.failures < best.failures {
+			best = e
+		}
+	}
+	return best
+}
+
+// Validate проверяет прокси, переданный в запросе
+func Validate(override string) error {
+	if override == "" || override == Direct {
+		return nil
+	}
+	_, err := Parse(override)
+	return err
+}
+
+// Report учитывает результат загрузки через прокси. Прокси, не входящие в пул, и отмена
+// запроса вызывающей стороной не учитываются
+func (p *Pool) Report(proxy *Proxy, err error) {
+	if proxy == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e := p.find(proxy)
+	if e == nil {
+		return
+	}
+
+	if err == nil {
+		e.failures = 0
+		e.healthy = true
+		return
+	}
+
+	e.errors++
+	p.fail(e, err)
+}
+
+// fail отмечает ошибку прокси и исключает его из выбора после MaxFailures ошибок подряд.
+// Вызывается под блокировкой
+func (p *Pool) fail(e *entry, err error) {
+	e.failures++
+	e.lastError = err.Error()
+
+	maxFailures := p.cfg.Proxy.MaxFailures
+	if maxFailures <= 0 {
+		maxFailures = 1
+	}
+
+	if e.healthy && e.failures >= maxFailures {
+		e.healthy = false
+		log.Printf("Прокси %s исключен из пула после %d ошибок подряд: %v", e.proxy, e.failures, err)
+	}
+}
+
+// find возвращает запись пула для прокси. Вызывается под блокировкой
+func (p *Pool) find(proxy *Proxy) *entry {
+	for _, e := range p.entries {
+		if e.proxy == proxy {
+			return e
+		}
+	}
+	return nil
+}
+
+// transport возвращает транспорт прокси. Прокси пула переиспользуют соединения, для прокси
+// из запроса соединения не сохраняются
+func (p *Pool) transport(proxy *Proxy) *http.Transport {
+	p.mu.Lock()
+	e := p.find(proxy)
+	p.mu.Unlock()
+
+	if e != nil {
+		return e.transport
+	}
+
+	transport := proxy.Transport()
+	transport.DisableKeepAlives = true
+	return transport
+}
+
+// Status возвращает состояние прокси пула
+func (p *Pool) Status() []models.ProxyStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	domains := make(map[*entry][]string)
+	for domain, e := range p.pins {
+		domains[e] = append(domains[e], domain)
+	}
+
+	statuses := make([]models.ProxyStatus, 0, len(p.entries))
+	for _, e := range p.entries {
+		sort.Strings(domains[e])
+		statuses = append(statuses, models.ProxyStatus{
+			Proxy:     e.proxy.String(),
+			Healthy:   e.healthy,
+			Failures:  e.failures,
+			Requests:  e.requests,
+			Errors:    e.errors,
+			LastError: e.lastError,
+			CheckedAt: e.checkedAt,
+			Domains:   domains[e],
+		})
+	}
+	return statuses
+}
+
+// checkHealth запрашивает проверочный адрес через каждый прокси. Исключенные прокси
+// возвращаются в пул после успешной проверки
+func (p *Pool) checkHealth() {
+	for _, e := range p.entries {
+		err := p.probe(e)
+
+		p.mu.Lock()
+		e.checkedAt = time.Now()
+		if err == nil {
+			if !e.healthy {
+				log.Printf("Прокси %s снова доступен", e.proxy)
+			}
+			e.healthy = true
+			e.failures = 0
+		} else {
+			p.fail(e, err)
+		}
+		p.mu.Unlock()
+	}
+}
+
+// probe выполняет проверочный запрос через прокси
+func (p *Pool) probe(e *entry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.Proxy.CheckURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", p.cfg.Scraper.UserAgent)
+
+	client := &http.Client{Transport: e.transport, Timeout: checkTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusProxyAuthRequired || resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("проверочный запрос вернул статус %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// domainOf возвращает домен адреса без www для закрепления прокси
+func domainOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
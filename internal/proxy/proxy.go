@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.uber.org/fx"
+)
+
+// Direct отключает прокси для отдельного запроса
+const Direct = "direct"
+
+// ErrProxyAuth означает, что прокси отклонил авторизацию
+var ErrProxyAuth = errors.New("прокси отклонил авторизацию")
+
+// ResponseError возвращает ошибку запроса HTTP-клиента для учета в пуле, считая ответ 407 ошибкой прокси
+func ResponseError(resp *http.Response, err error) error {
+	if err == nil && resp.StatusCode == http.StatusProxyAuthRequired {
+		return ErrProxyAuth
+	}
+	return err
+}
+
+// Proxy представляет прокси-сервер, через который выполняются загрузки
+type Proxy struct {
+	url *url.URL
+}
+
+// Parse разбирает адрес прокси. Поддерживаются схемы http, https и socks5
+func Parse(raw string) (*Proxy, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("некорректный адрес прокси")
+	}
+
+	switch parsed.Scheme {
+	case "http", "https":
+	case "socks5":
+		// Chrome не умеет авторизоваться на SOCKS5-прокси, а прокси должен работать одинаково
+		// для браузера и HTTP-клиента
+		if parsed.User != nil {
+			return nil, fmt.Errorf("авторизация на SOCKS5-прокси не поддерживается")
+		}
+	default:
+		return nil, fmt.Errorf("неподдерживаемая схема прокси %q (допустимо: http, https, socks5)", parsed.Scheme)
+	}
+
+	if parsed.Hostname() == "" || parsed.Port() == "" {
+		return nil, fmt.Errorf("в адресе прокси нужно указать хост и порт")
+	}
+
+	return &Proxy{url: &url.URL{Scheme: parsed.Scheme, User: parsed.User, Host: parsed.Host}}, nil
+}
+
+// URL возвращает адрес прокси вместе с учетными данными для HTTP-клиента
+func (p *Proxy) URL() *url.URL {
+	return p.url
+}
+
+// Server возвращает адрес прокси без учетных данных в формате параметра --proxy-server Chrome
+func (p *Proxy) Server() string {
+	return p.url.Scheme + "://" + p.url.Host
+}
+
+// Credentials возвращает логин и пароль прокси, если они заданы
+func (p *Proxy) Credentials() (username, password string, ok bool) {
+	if p == nil || p.url.User == nil {
+		return "", "", false
+	}
+	password, _ = p.url.User.Password()
+	return p.url.User.Username(), password, true
+}
+
+// String возвращает адрес прокси со скрытым паролем для логов и API
+func (p *Proxy) String() string {
+	return p.url.Redacted()
+}
+
+// Transport создает транспорт HTTP-клиента, отправляющий запросы через прокси
+func (p *Proxy) Transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(p.url)
+	return transport
+}
+
+// Client возвращает копию клиента, отправляющую запросы через прокси. Без прокси возвращается сам клиент
+func (p *Pool) Client(base *http.Client, proxy *Proxy) *http.Client {
+	if proxy == nil {
+		return base
+	}
+
+	client := *base
+	client.Transport = p.transport(proxy)
+	return &client
+}
+
+// Module регистрирует пул прокси и его периодическую проверку
+var Module = fx.Module("proxy",
+	fx.Provide(
+		NewPool,
+	),
+	fx.Invoke(func(lc fx.Lifecycle, pool *Pool) {
+		lc.Append(fx.Hook{
+			OnStart: func(ctx context.Context) error {
+				pool.Start()
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				pool.Close()
+				return nil
+			},
+		})
+	}),
+)
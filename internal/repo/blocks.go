@@ -0,0 +1,430 @@
+package repo
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"website-scraper/internal/models"
+)
+
+// maxGroupDistance задает наибольшее число различающихся бит отпечатков блоков одной группы.
+// Отпечаток разбит на восемь частей по 8 бит, поэтому при расстоянии до семи бит
+// хотя бы одна часть совпадает и кандидаты находятся по индексу
+const (
+	maxGroupDistance     = 7
+	fingerprintBandCount = 8
+)
+
+// maxGroupPageURLs ограничивает число адресов страниц группы в отчете
+const maxGroupPageURLs = 10
+
+// blockColumns перечисляет колонки блока в порядке scanBlock. HTML старых блоков хранится
+// в самой таблице blocks, новых — в block_html
+const blockColumns = `b.id, b.operation_id, b.block_type, b.platform, b.content, COALESCE(h.html, b.html),
+		b.viewport, b.fingerprint, b.structure_signature, b.group_id, b.created_at`
+
+// scanBlock читает блок из строки выборки blockColumns
+func scanBlock(row rowScanner) (*models.Block, error) {
+	var block models.Block
+	var blockType, platform string
+	var contentJSON []byte
+	var fingerprint, structureSignature sql.NullInt64
+	var groupID uuid.NullUUID
+
+	err := row.Scan(
+		&block.ID,
+		&block.OperationID,
+		&blockType,
+		&platform,
+		&contentJSON,
+		&block.HTML,
+		&block.Viewport,
+		&fingerprint,
+		&structureSignature,
+		&groupID,
+		&block.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan block: %w", err)
+	}
+
+	block.BlockType = models.BlockType(blockType)
+	block.Platform = models.Platform(platform)
+	block.Fingerprint = uint64(fingerprint.Int64)
+	block.StructureSignature = uint64(structureSignature.Int64)
+	if groupID.Valid {
+		block.GroupID = &groupID.UUID
+	}
+
+	if err := json.Unmarshal(contentJSON, &block.Content); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal block content: %w", err)
+	}
+
+	return &block, nil
+}
+
+// blockHTMLHash возвращает ключ HTML блока в block_html
+func blockHTMLHash(html string) string {
+	sum := sha256.Sum256([]byte(html))
+	return hex.EncodeToString(sum[:])
+}
+
+// blockHTMLLockKey возвращает ключ блокировки общего HTML блоков
+func blockHTMLLockKey(hash string) string {
+	return "block_html:" + hash
+}
+
+// blockGroupsLockKey возвращает ключ блокировки групп блоков одного типа
+func blockGroupsLockKey(blockType models.BlockType) string {
+	return "block_groups:" + string(blockType)
+}
+
+// lockBlockKeys берет advisory-блокировки до конца транзакции. Сохранение блока и удаление операции
+// берут блокировки общего HTML и групп блоков, чтобы удаление не сочло неиспользуемыми строки,
+// на которые ссылается еще не сохраненный блок. Ключи блокируются по порядку, поэтому встречные
+// транзакции не ждут друг друга по кругу
+func lockBlockKeys(ctx context.Context, tx *sql.Tx, keys ...string) error {
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, key); err != nil {
+			return fmt.Errorf("failed to lock %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// fingerprintBands разбивает отпечаток на части по 8 бит
+func fingerprintBands(fingerprint uint64) []interface{} {
+	bands := make([]interface{}, fingerprintBandCount)
+	for i := range bands {
+		bands[i] = int(fingerprint >> (8 * i) & 0xff)
+	}
+	return bands
+}
+
+// structureBands возвращает части структурной подписи для колонки structure_bands: номер части
+// входит в значение, чтобы совпадение искалось только среди частей с тем же номером
+func structureBands(signature uint64) pq.Int64Array {
+	bands := make(pq.Int64Array, fingerprintBandCount)
+	for i := range bands {
+		bands[i] = int64(i<<8) | int64(signature>>(8*i)&0xff)
+	}
+	return bands
+}
+
+// assignBlockGroup находит группу блоков того же типа с ближайшим отпечатком или создает новую.
+// Поиск и создание группы выполняются под блокировкой типа блока до конца транзакции, иначе
+// параллельные разборы одного сайта создали бы несколько групп для одной шапки
+// htmlHash пуст у старых блоков, HTML которых хранится в самой таблице blocks
+func assignBlockGroup(ctx context.Context, tx *sql.Tx, blockType models.BlockType, fingerprint uint64, htmlHash sql.NullString) (uuid.UUID, error) {
+	if err := lockBlockKeys(ctx, tx, blockGroupsLockKey(blockType)); err != nil {
+		return uuid.Nil, err
+	}
+
+	bands := fingerprintBands(fingerprint)
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, fingerprint
+		FROM block_groups
+		WHERE block_type = $1 AND (band0 = $2 OR band1 = $3 OR band2 = $4 OR band3 = $5
+			OR band4 = $6 OR band5 = $7 OR band6 = $8 OR band7 = $9)
+	`, append([]interface{}{blockType}, bands...)...)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to find block group: %w", err)
+	}
+
+	bestID, bestDistance := uuid.Nil, maxGroupDistance+1
+	for rows.Next() {
+		var id uuid.UUID
+		var candidate int64
+		if err := rows.Scan(&id, &candidate); err != nil {
+			rows.Close()
+			return uuid.Nil, fmt.Errorf("failed to scan block group: %w", err)
+		}
+		if distance := bits.OnesCount64(uint64(candidate) ^ fingerprint); distance < bestDistance {
+			bestID, bestDistance = id, distance
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return uuid.Nil, fmt.Errorf("error iterating block groups: %w", err)
+	}
+
+	if bestID != uuid.Nil {
+		return bestID, nil
+	}
+
+	var groupID uuid.UUID
+	args := append([]interface{}{blockType, int64(fingerprint), htmlHash}, bands...)
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO block_groups (block_type, fingerprint, html_hash, band0, band1, band2, band3, band4, band5, band6, band7)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id
+	`, args...).Scan(&groupID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create block group: %w", err)
+	}
+
+	return groupID, nil
+}
+
+// GetBlocksToBackfill получает блоки, сохраненные до появления отпечатков, структурных подписей
+// или полнотекстового индекса, с ID больше after в порядке ID
+func (r *PostgresRepo) GetBlocksToBackfill(ctx context.Context, after uuid.UUID, limit int) ([]models.Block, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+blockColumns+`
+		FROM blocks b
+		LEFT JOIN block_html h ON h.hash = b.html_hash
+		WHERE b.id > $1 AND (b.fingerprint IS NULL OR b.structure_signature IS NULL OR b.search_vector IS NULL)
+		ORDER BY b.id
+		LIMIT $2
+	`, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blocks to backfill: %w", err)
+	}
+	defer rows.Close()
+
+	var blocks []models.Block
+	for rows.Next() {
+		block, err := scanBlock(rows)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, *block)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating blocks: %w", err)
+	}
+
+	return blocks, nil
+}
+
+// BackfillBlock сохраняет отпечаток, структурную подпись и полнотекстовый индекс блока, вычисленные
+// после его сохранения, и включает блок в группу. Уже заполненные значения не меняются
+func (r *PostgresRepo) BackfillBlock(ctx context.Context, block *models.Block) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var hasFingerprint, hasSignature, hasSearchVector bool
+	var htmlHash sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT fingerprint IS NOT NULL, structure_signature IS NOT NULL, search_vector IS NOT NULL, html_hash
+		FROM blocks WHERE id = $1 FOR UPDATE
+	`, block.ID).Scan(&hasFingerprint, &hasSignature, &hasSearchVector, &htmlHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("block %w: %s", ErrNotFound, block.ID)
+		}
+		return fmt.Errorf("failed to lock block: %w", err)
+	}
+
+	if !hasFingerprint {
+		var groupID *uuid.UUID
+		if block.Fingerprint != 0 {
+			id, err := assignBlockGroup(ctx, tx, block.BlockType, block.Fingerprint, htmlHash)
+			if err != nil {
+				return err
+			}
+			groupID = &id
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE blocks SET fingerprint = $2, group_id = $3 WHERE id = $1
+		`, block.ID, int64(block.Fingerprint), groupID)
+		if err != nil {
+			return fmt.Errorf("failed to backfill block fingerprint: %w", err)
+		}
+		block.GroupID = groupID
+	}
+
+	if !hasSignature {
+		var bands interface{}
+		if block.StructureSignature != 0 {
+			bands = structureBands(block.StructureSignature)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE blocks SET structure_signature = $2, structure_bands = $3 WHERE id = $1
+		`, block.ID, int64(block.StructureSignature), bands)
+		if err != nil {
+			return fmt.Errorf("failed to backfill block structure signature: %w", err)
+		}
+	}
+
+	if !hasSearchVector {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE blocks SET search_vector = to_tsvector('russian', $2) || to_tsvector('english', $2) WHERE id = $1
+		`, block.ID, block.SearchText)
+		if err != nil {
+			return fmt.Errorf("failed to backfill block search vector: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit block backfill: %w", err)
+	}
+
+	return nil
+}
+
+// operationDomainSQL извлекает из адреса операции домен без www для отбора операций сайта
+const operationDomainSQL = `lower(substring(o.url from '^[a-zA-Z]+://(?:[^@/]*@)?(?:www\.)?([^/:?#]+)'))`
+
+// GetSiteBlockGroups получает группы блоков завершенных операций сайта и сводку по его блокам
+func (r *PostgresRepo) GetSiteBlockGroups(ctx context.Context, domain string) (*models.DuplicateReport, error) {
+	report := &models.DuplicateReport{
+		Domain: domain,
+		Groups: []models.BlockGroupUsage{},
+	}
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT o.url), COUNT(b.id), COUNT(DISTINCT COALESCE(b.html_hash, b.id::text)),
+			COUNT(b.id) FILTER (WHERE b.group_id IS NULL)
+		FROM operations o
+		LEFT JOIN blocks b ON b.operation_id = o.id
+		WHERE o.status = $1 AND `+operationDomainSQL+` = $2
+	`, models.StatusCompleted, domain).Scan(&report.Pages, &report.Blocks, &report.StoredHTML, &report.Ungrouped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get site blocks: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT g.id, g.block_type, g.fingerprint,
+			COALESCE(MIN(b.content->>'template_name'), ''),
+			COUNT(DISTINCT o.url), COUNT(b.id), COUNT(DISTINCT b.html_hash),
+			(array_agg(b.id ORDER BY b.created_at))[1],
+			(array_agg(DISTINCT o.url))[1:`+fmt.Sprint(maxGroupPageURLs)+`]
+		FROM blocks b
+		JOIN operations o ON o.id = b.operation_id
+		JOIN block_groups g ON g.id = b.group_id
+		WHERE o.status = $1 AND `+operationDomainSQL+` = $2
+		GROUP BY g.id, g.block_type, g.fingerprint
+		ORDER BY COUNT(DISTINCT o.url) DESC, g.block_type
+	`, models.StatusCompleted, domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block groups: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var group models.BlockGroupUsage
+		var blockType string
+		var fingerprint int64
+		var urls pq.StringArray
+
+		err := rows.Scan(
+			&group.GroupID,
+			&blockType,
+			&fingerprint,
+			&group.TemplateName,
+			&group.Pages,
+			&group.Occurrences,
+			&group.Variants,
+			&group.SampleBlockID,
+			&urls,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan block group: %w", err)
+		}
+
+		group.BlockType = models.BlockType(blockType)
+		group.Fingerprint = uint64(fingerprint)
+		group.PageURLs = urls
+		report.Groups = append(report.Groups, group)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating block groups: %w", err)
+	}
+
+	return report, nil
+}
+
+// FindSimilarBlocks находит блоки завершенных операций, структурная подпись которых отличается
+// от подписи исходного блока не более чем в query.MaxDistance битах, начиная с самых близких.
+// Кандидаты отбираются по совпадению хотя бы одной части подписи, поэтому расстояние больше семи бит
+// не гарантирует полноты поиска
+func (r *PostgresRepo) FindSimilarBlocks(ctx context.Context, source *models.Block, query models.SimilarBlocksQuery) ([]models.SimilarBlock, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		WITH candidates AS (
+			SELECT b.id, b.operation_id, o.url, `+operationDomainSQL+` AS domain, b.block_type, b.platform,
+				COALESCE(b.content->>'template_name', '') AS template_name, b.structure_signature,
+				bit_count((b.structure_signature # $1)::bit(64)) AS distance, b.created_at
+			FROM blocks b
+			JOIN operations o ON o.id = b.operation_id
+			WHERE b.structure_bands && $2 AND b.id <> $3 AND o.status = $4
+				AND ($5 = '' OR b.block_type = $5)
+		)
+		SELECT id, operation_id, url, COALESCE(domain, ''), block_type, platform, template_name,
+			structure_signature, distance, created_at
+		FROM candidates
+		WHERE distance <= $6 AND (NOT $7 OR domain IS DISTINCT FROM (
+			SELECT `+operationDomainSQL+` FROM operations o WHERE o.id = $8
+		))
+		ORDER BY distance, created_at DESC
+		LIMIT $9
+	`,
+		int64(source.StructureSignature),
+		structureBands(source.StructureSignature),
+		source.ID,
+		models.StatusCompleted,
+		string(query.BlockType),
+		query.MaxDistance,
+		query.OtherSites,
+		source.OperationID,
+		query.Limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find similar blocks: %w", err)
+	}
+	defer rows.Close()
+
+	blocks := []models.SimilarBlock{}
+	for rows.Next() {
+		var block models.SimilarBlock
+		var blockType, platform string
+		var signature int64
+
+		err := rows.Scan(
+			&block.BlockID,
+			&block.OperationID,
+			&block.URL,
+			&block.Domain,
+			&blockType,
+			&platform,
+			&block.TemplateName,
+			&signature,
+			&block.Distance,
+			&block.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan similar block: %w", err)
+		}
+
+		block.BlockType = models.BlockType(blockType)
+		block.Platform = models.Platform(platform)
+		block.StructureSignature = uint64(signature)
+		block.Similarity = 1 - float64(block.Distance)/64
+		blocks = append(blocks, block)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating similar blocks: %w", err)
+	}
+
+	return blocks, nil
+}
@@ -2,35 +2,73 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/google/uuid"
 
 	"website-scraper/internal/models"
 )
 
+// ErrNotFound возвращается, если запись не найдена
+var ErrNotFound = errors.New("not found")
+
 // ParserRepo представляет интерфейс для репозитория парсера
 type ParserRepo interface {
 	// CreateOperation создает новую операцию парсинга
 	CreateOperation(ctx context.Context, url string) (uuid.UUID, error)
+	// CreateOperationFromSource создает операцию для HTML, полученного не загрузкой страницы
+	CreateOperationFromSource(ctx context.Context, url string, source models.OperationSource) (uuid.UUID, error)
+	// CreateDerivedOperation создает операцию, разбирающую HTML другой операции
+	CreateDerivedOperation(ctx context.Context, url string, parentID uuid.UUID, source models.OperationSource) (uuid.UUID, error)
 
 	// UpdateOperationStatus обновляет статус операции
 	UpdateOperationStatus(ctx context.Context, operationID uuid.UUID, status models.OperationStatus) error
 
+	// UpdateOperationFetchStrategy сохраняет способ загрузки страницы, использованный операцией
+	UpdateOperationFetchStrategy(ctx context.Context, operationID uuid.UUID, strategy models.FetchStrategy) error
+	// UpdateOperationResponse сохраняет метаданные ответа на запрос страницы
+	UpdateOperationResponse(ctx context.Context, operationID uuid.UUID, response *models.ResponseMeta) error
+	// UpdateOperationDevice сохраняет устройство, эмулированное при загрузке страницы
+	UpdateOperationDevice(ctx context.Context, operationID uuid.UUID, device *models.DeviceOptions) error
+
 	// GetOperationByID получает операцию по ID
 	GetOperationByID(ctx context.Context, operationID uuid.UUID) (*models.Operation, error)
 
 	// GetAllOperations получает все операции
 	GetAllOperations(ctx context.Context) ([]models.Operation, error)
+	// ListOperations получает страницу операций с фильтрами и числом блоков каждой операции
+	ListOperations(ctx context.Context, query models.OperationListQuery) ([]models.OperationSummary, error)
+	// CountOperationsByURL считает операции страницы с указанным адресом
+	CountOperationsByURL(ctx context.Context, url string) (int, error)
+	// DeleteOperation удаляет операцию с блоками и ссылками, а также HTML и группы блоков,
+	// на которые больше не ссылается ни один блок. Возвращает число удаленных блоков
+	DeleteOperation(ctx context.Context, operationID uuid.UUID) (int, error)
 
 	// SaveBlock сохраняет блок, найденный при парсинге
 	SaveBlock(ctx context.Context, block *models.Block) error
 
+	// UpdateBlockContent обновляет контент блока
+	UpdateBlockContent(ctx context.Context, blockID uuid.UUID, content interface{}) error
+
 	// GetBlocksByOperationID получает все блоки по ID операции
 	GetBlocksByOperationID(ctx context.Context, operationID uuid.UUID) ([]models.Block, error)
 
 	// GetBlockByID получает блок по ID
 	GetBlockByID(ctx context.Context, blockID uuid.UUID) (*models.Block, error)
 
+	// GetBlocksToBackfill получает блоки, сохраненные до появления вычисляемых при сохранении колонок
+	GetBlocksToBackfill(ctx context.Context, after uuid.UUID, limit int) ([]models.Block, error)
+	// BackfillBlock дописывает в сохраненный блок незаполненные вычисляемые колонки
+	BackfillBlock(ctx context.Context, block *models.Block) error
+
+	// GetSiteBlockGroups получает группы одинаковых и почти одинаковых блоков на страницах сайта
+	GetSiteBlockGroups(ctx context.Context, domain string) (*models.DuplicateReport, error)
+	// FindSimilarBlocks находит блоки всех операций со структурной подписью, близкой к подписи исходного блока
+	FindSimilarBlocks(ctx context.Context, source *models.Block, query models.SimilarBlocksQuery) ([]models.SimilarBlock, error)
+	// SearchBlocks ищет блоки всех операций по тексту и атрибутам
+	SearchBlocks(ctx context.Context, query models.BlockSearchQuery) (*models.BlockSearchResponse, error)
+
 	// GetAllTemplates получает все HTML теги для парсера блоков страницы
 	GetAllTemplates(platform models.Platform) ([]models.BlockTemplate, error)
 }
@@ -44,6 +82,32 @@ type CrawlerRepo interface {
 	GetLinksByOperationID(ctx context.Context, operationID uuid.UUID) ([]models.Link, error)
 }
 
+// MonitorRepo представляет интерфейс для репозитория мониторов страниц
+type MonitorRepo interface {
+	// CreateMonitor создает монитор
+	CreateMonitor(ctx context.Context, monitor *models.Monitor) error
+	// GetMonitorByID получает монитор по ID
+	GetMonitorByID(ctx context.Context, monitorID uuid.UUID) (*models.Monitor, error)
+	// GetAllMonitors получает все мониторы
+	GetAllMonitors(ctx context.Context) ([]models.Monitor, error)
+	// UpdateMonitor сохраняет расписание, параметры разбора и включенность монитора
+	UpdateMonitor(ctx context.Context, monitor *models.Monitor) error
+	// DeleteMonitor удаляет монитор вместе с историей изменений
+	DeleteMonitor(ctx context.Context, monitorID uuid.UUID) error
+
+	// GetDueMonitors получает включенные мониторы, время проверки которых наступило
+	GetDueMonitors(ctx context.Context, now time.Time) ([]models.Monitor, error)
+	// ScheduleMonitor сохраняет время следующей проверки монитора
+	ScheduleMonitor(ctx context.Context, monitorID uuid.UUID, nextRunAt *time.Time) error
+	// SaveMonitorRun сохраняет результат проверки: последнюю операцию, время и ошибку
+	SaveMonitorRun(ctx context.Context, monitor *models.Monitor) error
+
+	// SaveMonitorChange сохраняет изменения, найденные при проверке
+	SaveMonitorChange(ctx context.Context, change *models.MonitorChange) error
+	// GetMonitorChanges получает историю изменений монитора, начиная с последних
+	GetMonitorChanges(ctx context.Context, monitorID uuid.UUID, limit int) ([]models.MonitorChange, error)
+}
+
 // DBConnection интерфейс для подключения к базе данных
 type DBConnection interface {
 	Close() error
@@ -26,6 +26,9 @@ var Module = fx.Module("repo",
 			repo, err := NewPostgresRepo(cfg)
 			return repo, err
 		},
+		func(cfg *config.Config) (MonitorRepo, error) {
+			return NewPostgresRepo(cfg)
+		},
 	),
 	fx.Invoke(func(lc fx.Lifecycle, db *sql.DB) {
 		lc.Append(fx.Hook{
@@ -0,0 +1,334 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"website-scraper/internal/models"
+)
+
+// monitorColumns перечисляет колонки монитора в порядке scanMonitor
+const monitorColumns = `id, url, schedule, options, enabled, last_operation_id, last_run_at,
+		last_changed_at, next_run_at, last_error, created_at, updated_at`
+
+// rowScanner объединяет sql.Row и sql.Rows для чтения одной строки
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanMonitor читает монитор из строки выборки monitorColumns
+func scanMonitor(row rowScanner) (*models.Monitor, error) {
+	var monitor models.Monitor
+	var optionsJSON []byte
+	var lastOperationID uuid.NullUUID
+	var lastRunAt, lastChangedAt, nextRunAt sql.NullTime
+
+	err := row.Scan(
+		&monitor.ID,
+		&monitor.URL,
+		&monitor.Schedule,
+		&optionsJSON,
+		&monitor.Enabled,
+		&lastOperationID,
+		&lastRunAt,
+		&lastChangedAt,
+		&nextRunAt,
+		&monitor.LastError,
+		&monitor.CreatedAt,
+		&monitor.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(optionsJSON, &monitor.Options); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal monitor options: %w", err)
+	}
+	if lastOperationID.Valid {
+		monitor.LastOperationID = &lastOperationID.UUID
+	}
+	monitor.LastRunAt = nullTimePtr(lastRunAt)
+	monitor.LastChangedAt = nullTimePtr(lastChangedAt)
+	monitor.NextRunAt = nullTimePtr(nextRunAt)
+
+	return &monitor, nil
+}
+
+// nullTimePtr возвращает время или nil для NULL
+func nullTimePtr(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
+
+// CreateMonitor создает монитор
+func (r *PostgresRepo) CreateMonitor(ctx context.Context, monitor *models.Monitor) error {
+	optionsJSON, err := json.Marshal(monitor.Options)
+	if err != nil {
+		return fmt.Errorf("failed to marshal monitor options: %w", err)
+	}
+
+	query := `
+		INSERT INTO monitors (url, schedule, options, enabled, next_run_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+
+	err = r.db.QueryRowContext(
+		ctx,
+		query,
+		monitor.URL,
+		monitor.Schedule,
+		optionsJSON,
+		monitor.Enabled,
+		monitor.NextRunAt,
+	).Scan(&monitor.ID, &monitor.CreatedAt, &monitor.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create monitor: %w", err)
+	}
+
+	return nil
+}
+
+// GetMonitorByID получает монитор по ID
+func (r *PostgresRepo) GetMonitorByID(ctx context.Context, monitorID uuid.UUID) (*models.Monitor, error) {
+	query := `SELECT ` + monitorColumns + ` FROM monitors WHERE id = $1`
+
+	monitor, err := scanMonitor(r.db.QueryRowContext(ctx, query, monitorID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("monitor %w: %s", ErrNotFound, monitorID)
+		}
+		return nil, fmt.Errorf("failed to get monitor: %w", err)
+	}
+
+	return monitor, nil
+}
+
+// GetAllMonitors получает все мониторы
+func (r *PostgresRepo) GetAllMonitors(ctx context.Context) ([]models.Monitor, error) {
+	query := `SELECT ` + monitorColumns + ` FROM monitors ORDER BY created_at DESC`
+	return r.queryMonitors(ctx, query)
+}
+
+// GetDueMonitors получает включенные мониторы, время проверки которых наступило
+func (r *PostgresRepo) GetDueMonitors(ctx context.Context, now time.Time) ([]models.Monitor, error) {
+	query := `
+		SELECT ` + monitorColumns + `
+		FROM monitors
+		WHERE enabled AND next_run_at <= $1
+		ORDER BY next_run_at
+	`
+	return r.queryMonitors(ctx, query, now)
+}
+
+// queryMonitors выполняет выборку мониторов
+func (r *PostgresRepo) queryMonitors(ctx context.Context, query string, args ...interface{}) ([]models.Monitor, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get monitors: %w", err)
+	}
+	defer rows.Close()
+
+	monitors := []models.Monitor{}
+	for rows.Next() {
+		monitor, err := scanMonitor(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan monitor: %w", err)
+		}
+		monitors = append(monitors, *monitor)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating monitors: %w", err)
+	}
+
+	return monitors, nil
+}
+
+// UpdateMonitor сохраняет расписание, параметры разбора и включенность монитора
+func (r *PostgresRepo) UpdateMonitor(ctx context.Context, monitor *models.Monitor) error {
+	optionsJSON, err := json.Marshal(monitor.Options)
+	if err != nil {
+		return fmt.Errorf("failed to marshal monitor options: %w", err)
+	}
+
+	query := `
+		UPDATE monitors
+		SET schedule = $1, options = $2, enabled = $3, next_run_at = $4
+		WHERE id = $5
+		RETURNING updated_at
+	`
+
+	err = r.db.QueryRowContext(
+		ctx,
+		query,
+		monitor.Schedule,
+		optionsJSON,
+		monitor.Enabled,
+		monitor.NextRunAt,
+		monitor.ID,
+	).Scan(&monitor.UpdatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("monitor %w: %s", ErrNotFound, monitor.ID)
+		}
+		return fmt.Errorf("failed to update monitor: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteMonitor удаляет монитор вместе с историей изменений
+func (r *PostgresRepo) DeleteMonitor(ctx context.Context, monitorID uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM monitors WHERE id = $1`, monitorID)
+	if err != nil {
+		return fmt.Errorf("failed to delete monitor: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("monitor %w: %s", ErrNotFound, monitorID)
+	}
+
+	return nil
+}
+
+// ScheduleMonitor сохраняет время следующей проверки монитора
+func (r *PostgresRepo) ScheduleMonitor(ctx context.Context, monitorID uuid.UUID, nextRunAt *time.Time) error {
+	query := `
+		UPDATE monitors
+		SET next_run_at = $1
+		WHERE id = $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, nextRunAt, monitorID)
+	if err != nil {
+		return fmt.Errorf("failed to schedule monitor: %w", err)
+	}
+
+	return nil
+}
+
+// SaveMonitorRun сохраняет результат проверки: последнюю операцию, время и ошибку
+func (r *PostgresRepo) SaveMonitorRun(ctx context.Context, monitor *models.Monitor) error {
+	query := `
+		UPDATE monitors
+		SET last_operation_id = $1, last_run_at = $2, last_changed_at = $3, last_error = $4
+		WHERE id = $5
+	`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		monitor.LastOperationID,
+		monitor.LastRunAt,
+		monitor.LastChangedAt,
+		monitor.LastError,
+		monitor.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save monitor run: %w", err)
+	}
+
+	return nil
+}
+
+// SaveMonitorChange сохраняет изменения, найденные при проверке
+func (r *PostgresRepo) SaveMonitorChange(ctx context.Context, change *models.MonitorChange) error {
+	summaryJSON, err := json.Marshal(change.Summary)
+	if err != nil {
+		return fmt.Errorf("failed to marshal monitor change summary: %w", err)
+	}
+	changesJSON, err := json.Marshal(change.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal monitor changes: %w", err)
+	}
+
+	query := `
+		INSERT INTO monitor_changes (monitor_id, operation_id, previous_operation_id, summary, changes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err = r.db.QueryRowContext(
+		ctx,
+		query,
+		change.MonitorID,
+		change.OperationID,
+		change.PreviousOperationID,
+		summaryJSON,
+		changesJSON,
+	).Scan(&change.ID, &change.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to save monitor change: %w", err)
+	}
+
+	return nil
+}
+
+// GetMonitorChanges получает историю изменений монитора, начиная с последних
+func (r *PostgresRepo) GetMonitorChanges(ctx context.Context, monitorID uuid.UUID, limit int) ([]models.MonitorChange, error) {
+	query := `
+		SELECT id, monitor_id, operation_id, previous_operation_id, summary, changes, created_at
+		FROM monitor_changes
+		WHERE monitor_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, monitorID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get monitor changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []models.MonitorChange{}
+	for rows.Next() {
+		var change models.MonitorChange
+		var operationID, previousID uuid.NullUUID
+		var summaryJSON, changesJSON []byte
+
+		err := rows.Scan(
+			&change.ID,
+			&change.MonitorID,
+			&operationID,
+			&previousID,
+			&summaryJSON,
+			&changesJSON,
+			&change.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan monitor change: %w", err)
+		}
+
+		if operationID.Valid {
+			change.OperationID = &operationID.UUID
+		}
+		if previousID.Valid {
+			change.PreviousOperationID = &previousID.UUID
+		}
+		if err := json.Unmarshal(summaryJSON, &change.Summary); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal monitor change summary: %w", err)
+		}
+		if err := json.Unmarshal(changesJSON, &change.Changes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal monitor changes: %w", err)
+		}
+
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating monitor changes: %w", err)
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,255 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"website-scraper/internal/models"
+)
+
+// operationColumns перечисляет колонки операции в порядке scanOperation
+const operationColumns = `o.id, o.url, o.status, COALESCE(o.fetch_strategy, ''), o.response, o.source, o.parent_id,
+		o.device, o.created_at, o.updated_at`
+
+// scanOperation читает операцию из строки выборки operationColumns. Дополнительные
+// колонки выборки, следующие за колонками операции, читаются в extra
+func scanOperation(row rowScanner, extra ...interface{}) (*models.Operation, error) {
+	var operation models.Operation
+	var status, fetchStrategy string
+	var responseJSON, deviceJSON []byte
+	var parentID uuid.NullUUID
+
+	dest := []interface{}{
+		&operation.ID,
+		&operation.URL,
+		&status,
+		&fetchStrategy,
+		&responseJSON,
+		&operation.Source,
+		&parentID,
+		&deviceJSON,
+		&operation.CreatedAt,
+		&operation.UpdatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	operation.Status = models.OperationStatus(status)
+	operation.FetchStrategy = models.FetchStrategy(fetchStrategy)
+	if parentID.Valid {
+		operation.ParentID = &parentID.UUID
+	}
+	if err := decodeOperationResponse(&operation, responseJSON); err != nil {
+		return nil, err
+	}
+	if err := decodeOperationDevice(&operation, deviceJSON); err != nil {
+		return nil, err
+	}
+	return &operation, nil
+}
+
+// operationSortColumns задает колонку и направление сортировки списка операций. Курсор хранит
+// значение колонки строкой, в запросе оно приводится к типу колонки
+var operationSortColumns = map[models.OperationSort]struct {
+	column     string
+	cursorType string
+	desc       bool
+}{
+	models.SortCreatedDesc: {"o.created_at", "timestamptz", true},
+	models.SortCreatedAsc:  {"o.created_at", "timestamptz", false},
+	models.SortURLAsc:      {"o.url", "text", false},
+	models.SortURLDesc:     {"o.url", "text", true},
+}
+
+// ListOperations получает страницу операций с числом блоков каждого типа. Страницы выбираются
+// по курсору: следующая начинается после последней операции предыдущей в порядке сортировки
+func (r *PostgresRepo) ListOperations(ctx context.Context, query models.OperationListQuery) ([]models.OperationSummary, error) {
+	order, ok := operationSortColumns[query.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unsupported operation sort: %s", query.Sort)
+	}
+
+	var args []interface{}
+	conditions := []string{"TRUE"}
+	addCondition := func(condition string, values ...interface{}) {
+		placeholders := make([]interface{}, len(values))
+		for i, value := range values {
+			args = append(args, value)
+			placeholders[i] = len(args)
+		}
+		conditions = append(conditions, fmt.Sprintf(condition, placeholders...))
+	}
+
+	if query.Status != "" {
+		addCondition("o.status = $%d", query.Status)
+	}
+	if query.URL != "" {
+		addCondition("o.url ILIKE $%d", likePattern(query.URL))
+	}
+	if query.Domain != "" {
+		addCondition(operationDomainSQL+" = $%d", query.Domain)
+	}
+	if query.Platform != "" {
+		addCondition("EXISTS (SELECT 1 FROM blocks pb WHERE pb.operation_id = o.id AND pb.platform = $%d)", query.Platform)
+	}
+	if query.From != nil {
+		addCondition("o.created_at >= $%d", *query.From)
+	}
+	if query.To != nil {
+		addCondition("o.created_at < $%d", *query.To)
+	}
+
+	direction, comparison := "ASC", ">"
+	if order.desc {
+		direction, comparison = "DESC", "<"
+	}
+	if query.After != nil {
+		addCondition("("+order.column+", o.id) "+comparison+" ($%d::"+order.cursorType+", $%d)", query.After.Value, query.After.ID)
+	}
+
+	args = append(args, query.Limit)
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+operationColumns+`,
+			COALESCE(c.block_count, 0), c.block_types, c.platforms
+		FROM operations o
+		LEFT JOIN LATERAL (
+			SELECT SUM(t.blocks)::int AS block_count, json_object_agg(t.block_type, t.blocks) AS block_types,
+				(SELECT array_agg(DISTINCT platform) FROM blocks WHERE operation_id = o.id) AS platforms
+			FROM (
+				SELECT block_type, COUNT(*) AS blocks
+				FROM blocks
+				WHERE operation_id = o.id
+				GROUP BY block_type
+			) AS t
+		) AS c ON TRUE
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY `+order.column+` `+direction+`, o.id `+direction+`
+		LIMIT $`+fmt.Sprint(len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list operations: %w", err)
+	}
+	defer rows.Close()
+
+	operations := []models.OperationSummary{}
+	for rows.Next() {
+		var summary models.OperationSummary
+		var blockTypesJSON []byte
+		var platforms pq.StringArray
+
+		operation, err := scanOperation(rows, &summary.BlockCount, &blockTypesJSON, &platforms)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan operation: %w", err)
+		}
+
+		summary.Operation = *operation
+		summary.BlockTypes = make(map[models.BlockType]int)
+		summary.Platforms = []models.Platform{}
+		if len(blockTypesJSON) > 0 {
+			if err := json.Unmarshal(blockTypesJSON, &summary.BlockTypes); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal operation block types: %w", err)
+			}
+		}
+		for _, platform := range platforms {
+			summary.Platforms = append(summary.Platforms, models.Platform(platform))
+		}
+
+		operations = append(operations, summary)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating operations: %w", err)
+	}
+
+	return operations, nil
+}
+
+// CountOperationsByURL считает операции страницы с указанным адресом
+func (r *PostgresRepo) CountOperationsByURL(ctx context.Context, url string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM operations WHERE url = $1`, url).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count operations: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteOperation удаляет операцию. Блоки и ссылки удаляются вместе с ней, ссылки мониторов и
+// производных операций на нее очищаются. HTML и группы блоков, которыми пользовались только
+// блоки этой операции, удаляются в той же транзакции
+func (r *PostgresRepo) DeleteOperation(ctx context.Context, operationID uuid.UUID) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var blocks int
+	var hashes, groups, groupTypes pq.StringArray
+	err = tx.QueryRowContext(ctx, `
+		WITH deleted AS (
+			DELETE FROM blocks WHERE operation_id = $1
+			RETURNING html_hash, group_id, block_type
+		)
+		SELECT COUNT(*),
+			COALESCE(array_agg(DISTINCT html_hash) FILTER (WHERE html_hash IS NOT NULL), '{}'),
+			COALESCE(array_agg(DISTINCT group_id::text) FILTER (WHERE group_id IS NOT NULL), '{}'),
+			COALESCE(array_agg(DISTINCT block_type::text) FILTER (WHERE group_id IS NOT NULL), '{}')
+		FROM deleted
+	`, operationID).Scan(&blocks, &hashes, &groups, &groupTypes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete operation blocks: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM operations WHERE id = $1`, operationID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete operation: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return 0, fmt.Errorf("operation %w: %s", ErrNotFound, operationID)
+	}
+
+	// Неиспользуемые HTML и группы ищутся под теми же блокировками, что берет сохранение блока:
+	// параллельный разбор, который уже выбрал эти строки, успевает сохранить свой блок, и каждый
+	// следующий запрос видит его
+	keys := make([]string, 0, len(hashes)+len(groupTypes))
+	for _, hash := range hashes {
+		keys = append(keys, blockHTMLLockKey(hash))
+	}
+	for _, blockType := range groupTypes {
+		keys = append(keys, blockGroupsLockKey(models.BlockType(blockType)))
+	}
+	if err := lockBlockKeys(ctx, tx, keys...); err != nil {
+		return 0, err
+	}
+
+	// Группа удаляется раньше HTML, потому что ссылается на HTML своего первого блока
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM block_groups g
+		WHERE g.id = ANY($1::uuid[]) AND NOT EXISTS (SELECT 1 FROM blocks b WHERE b.group_id = g.id)
+	`, groups)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete unused block groups: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM block_html h
+		WHERE h.hash = ANY($1)
+			AND NOT EXISTS (SELECT 1 FROM blocks b WHERE b.html_hash = h.hash)
+			AND NOT EXISTS (SELECT 1 FROM block_groups g WHERE g.html_hash = h.hash)
+	`, hashes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete unused block html: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit operation deletion: %w", err)
+	}
+
+	return blocks, nil
+}
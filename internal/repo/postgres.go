@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -55,6 +56,42 @@ func (r *PostgresRepo) CreateOperation(ctx context.Context, url string) (uuid.UU
 	return operationID, nil
 }
 
+// CreateOperationFromSource создает операцию для HTML, полученного не загрузкой страницы
+func (r *PostgresRepo) CreateOperationFromSource(ctx context.Context, url string, source models.OperationSource) (uuid.UUID, error) {
+	var operationID uuid.UUID
+
+	query := `
+		INSERT INTO operations (url, status, source)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(ctx, query, url, models.StatusPending, source).Scan(&operationID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create operation: %w", err)
+	}
+
+	return operationID, nil
+}
+
+// CreateDerivedOperation создает операцию, разбирающую HTML другой операции
+func (r *PostgresRepo) CreateDerivedOperation(ctx context.Context, url string, parentID uuid.UUID, source models.OperationSource) (uuid.UUID, error) {
+	var operationID uuid.UUID
+
+	query := `
+		INSERT INTO operations (url, status, source, parent_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(ctx, query, url, models.StatusPending, source, parentID).Scan(&operationID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create derived operation: %w", err)
+	}
+
+	return operationID, nil
+}
+
 // UpdateOperationStatus обновляет статус операции
 func (r *PostgresRepo) UpdateOperationStatus(ctx context.Context, operationID uuid.UUID, status models.OperationStatus) error {
 	query := `
@@ -71,73 +108,226 @@ func (r *PostgresRepo) UpdateOperationStatus(ctx context.Context, operationID uu
 	return nil
 }
 
-// GetOperationByID получает операцию по ID
-func (r *PostgresRepo) GetOperationByID(ctx context.Context, operationID uuid.UUID) (*models.Operation, error) {
+// UpdateOperationFetchStrategy сохраняет способ загрузки страницы, использованный операцией
+func (r *PostgresRepo) UpdateOperationFetchStrategy(ctx context.Context, operationID uuid.UUID, strategy models.FetchStrategy) error {
+	query := `
+		UPDATE operations
+		SET fetch_strategy = $1, updated_at = NOW()
+		WHERE id = $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, strategy, operationID)
+	if err != nil {
+		return fmt.Errorf("failed to update operation fetch strategy: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateOperationResponse сохраняет метаданные ответа на запрос страницы
+func (r *PostgresRepo) UpdateOperationResponse(ctx context.Context, operationID uuid.UUID, response *models.ResponseMeta) error {
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to marshal operation response: %w", err)
+	}
+
 	query := `
-		SELECT id, url, status, created_at, updated_at
-		FROM operations
-		WHERE id = $1
+		UPDATE operations
+		SET response = $1, updated_at = NOW()
+		WHERE id = $2
 	`
 
-	var operation models.Operation
-	var status string
+	_, err = r.db.ExecContext(ctx, query, responseJSON, operationID)
+	if err != nil {
+		return fmt.Errorf("failed to update operation response: %w", err)
+	}
+
+	return nil
+}
+
+// decodeOperationResponse разбирает сохраненные метаданные ответа операции
+func decodeOperationResponse(operation *models.Operation, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var response models.ResponseMeta
+	if err := json.Unmarshal(data, &response); err != nil {
+		return fmt.Errorf("failed to unmarshal operation response: %w", err)
+	}
+	operation.Response = &response
+	return nil
+}
+
+// UpdateOperationDevice сохраняет устройство, эмулированное при загрузке страницы
+func (r *PostgresRepo) UpdateOperationDevice(ctx context.Context, operationID uuid.UUID, device *models.DeviceOptions) error {
+	deviceJSON, err := json.Marshal(device)
+	if err != nil {
+		return fmt.Errorf("failed to marshal operation device: %w", err)
+	}
+
+	query := `
+		UPDATE operations
+		SET device = $1, updated_at = NOW()
+		WHERE id = $2
+	`
+
+	_, err = r.db.ExecContext(ctx, query, deviceJSON, operationID)
+	if err != nil {
+		return fmt.Errorf("failed to update operation device: %w", err)
+	}
+
+	return nil
+}
 
-	err := r.db.QueryRowContext(ctx, query, operationID).Scan(
-		&operation.ID,
-		&operation.URL,
-		&status,
-		&operation.CreatedAt,
-		&operation.UpdatedAt,
-	)
+// decodeOperationDevice разбирает сохраненное устройство операции
+func decodeOperationDevice(operation *models.Operation, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
 
+	var device models.DeviceOptions
+	if err := json.Unmarshal(data, &device); err != nil {
+		return fmt.Errorf("failed to unmarshal operation device: %w", err)
+	}
+	operation.Device = &device
+	return nil
+}
+
+// GetOperationByID получает операцию по ID
+func (r *PostgresRepo) GetOperationByID(ctx context.Context, operationID uuid.UUID) (*models.Operation, error) {
+	query := `
+		SELECT ` + operationColumns + `
+		FROM operations o
+		WHERE o.id = $1
+	`
+
+	operation, err := scanOperation(r.db.QueryRowContext(ctx, query, operationID))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("operation not found: %s", operationID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("operation %w: %s", ErrNotFound, operationID)
 		}
 		return nil, fmt.Errorf("failed to get operation: %w", err)
 	}
 
-	operation.Status = models.OperationStatus(status)
-	return &operation, nil
+	return operation, nil
 }
 
-// SaveBlock сохраняет блок, найденный при парсинге
+// SaveBlock сохраняет блок, найденный при парсинге. HTML блока сохраняется в block_html один раз
+// для всех одинаковых блоков, а блок с отпечатком попадает в группу почти одинаковых блоков
 func (r *PostgresRepo) SaveBlock(ctx context.Context, block *models.Block) error {
 	contentJSON, err := json.Marshal(block.Content)
 	if err != nil {
 		return fmt.Errorf("failed to marshal block content: %w", err)
 	}
 
+	// Блоки без отметки устройства разобраны в десктопном окне
+	if block.Viewport == "" {
+		block.Viewport = models.DeviceDesktop
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	htmlHash := blockHTMLHash(block.HTML)
+	// Блокировки держатся до вставки блока: до этого удаление операции не должно удалить HTML
+	// или группу, которые блок уже считает существующими
+	if err := lockBlockKeys(ctx, tx, blockHTMLLockKey(htmlHash), blockGroupsLockKey(block.BlockType)); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO block_html (hash, html)
+		VALUES ($1, $2)
+		ON CONFLICT (hash) DO NOTHING
+	`, htmlHash, block.HTML)
+	if err != nil {
+		return fmt.Errorf("failed to save block html: %w", err)
+	}
+
+	// Нулевая подпись сохраняется без частей: такой блок не участвует в поиске похожих,
+	// а NULL остается признаком блока, подпись которого еще не вычислена
+	var bands interface{}
+	if block.StructureSignature != 0 {
+		bands = structureBands(block.StructureSignature)
+	}
+
+	block.GroupID = nil
+	if block.Fingerprint != 0 {
+		groupID, err := assignBlockGroup(ctx, tx, block.BlockType, block.Fingerprint, sql.NullString{String: htmlHash, Valid: true})
+		if err != nil {
+			return err
+		}
+		block.GroupID = &groupID
+	}
+
 	query := `
-		INSERT INTO blocks (operation_id, block_type, platform, content, html)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO blocks (operation_id, block_type, platform, content, html, viewport, html_hash, fingerprint,
+			structure_signature, structure_bands, group_id, search_vector)
+		VALUES ($1, $2, $3, $4, '', $5, $6, $7, $8, $9, $10, to_tsvector('russian', $11) || to_tsvector('english', $11))
 		RETURNING id, created_at
 	`
 
-	err = r.db.QueryRowContext(
+	err = tx.QueryRowContext(
 		ctx,
 		query,
 		block.OperationID,
 		block.BlockType,
 		block.Platform,
 		contentJSON,
-		block.HTML,
+		block.Viewport,
+		htmlHash,
+		// Нулевой отпечаток сохраняется как есть: NULL означает блок, отпечаток которого еще не вычислен
+		int64(block.Fingerprint),
+		int64(block.StructureSignature),
+		bands,
+		block.GroupID,
+		block.SearchText,
 	).Scan(&block.ID, &block.CreatedAt)
 
 	if err != nil {
 		return fmt.Errorf("failed to save block: %w", err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit block: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateBlockContent обновляет контент блока
+func (r *PostgresRepo) UpdateBlockContent(ctx context.Context, blockID uuid.UUID, content interface{}) error {
+	contentJSON, err := json.Marshal(content)
+	if err != nil {
+		return fmt.Errorf("failed to marshal block content: %w", err)
+	}
+
+	query := `
+		UPDATE blocks
+		SET content = $1
+		WHERE id = $2
+	`
+
+	_, err = r.db.ExecContext(ctx, query, contentJSON, blockID)
+	if err != nil {
+		return fmt.Errorf("failed to update block content: %w", err)
+	}
+
 	return nil
 }
 
 // GetBlocksByOperationID получает все блоки по ID операции
 func (r *PostgresRepo) GetBlocksByOperationID(ctx context.Context, operationID uuid.UUID) ([]models.Block, error) {
 	query := `
-		SELECT id, operation_id, block_type, platform, content, html, created_at
-		FROM blocks
-		WHERE operation_id = $1
-		ORDER BY created_at
+		SELECT ` + blockColumns + `
+		FROM blocks b
+		LEFT JOIN block_html h ON h.hash = b.html_hash
+		WHERE b.operation_id = $1
+		ORDER BY b.created_at
 	`
 
 	rows, err := r.db.QueryContext(ctx, query, operationID)
@@ -149,32 +339,11 @@ func (r *PostgresRepo) GetBlocksByOperationID(ctx context.Context, operationID u
 	var blocks []models.Block
 
 	for rows.Next() {
-		var block models.Block
-		var blockType, platform string
-		var contentJSON []byte
-
-		err := rows.Scan(
-			&block.ID,
-			&block.OperationID,
-			&blockType,
-			&platform,
-			&contentJSON,
-			&block.HTML,
-			&block.CreatedAt,
-		)
-
+		block, err := scanBlock(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan block: %w", err)
-		}
-
-		block.BlockType = models.BlockType(blockType)
-		block.Platform = models.Platform(platform)
-
-		if err := json.Unmarshal(contentJSON, &block.Content); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal block content: %w", err)
+			return nil, err
 		}
-
-		blocks = append(blocks, block)
+		blocks = append(blocks, *block)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -301,9 +470,9 @@ func (r *PostgresRepo) GetLinksByOperationID(ctx context.Context, operationID uu
 }
 func (r *PostgresRepo) GetAllOperations(ctx context.Context) ([]models.Operation, error) {
 	query := `
-		SELECT id, url, status, created_at, updated_at
-		FROM operations
-		ORDER BY created_at DESC
+		SELECT ` + operationColumns + `
+		FROM operations o
+		ORDER BY o.created_at DESC
 	`
 
 	rows, err := r.db.QueryContext(ctx, query)
@@ -315,23 +484,11 @@ func (r *PostgresRepo) GetAllOperations(ctx context.Context) ([]models.Operation
 	var operations []models.Operation
 
 	for rows.Next() {
-		var operation models.Operation
-		var status string
-
-		err := rows.Scan(
-			&operation.ID,
-			&operation.URL,
-			&status,
-			&operation.CreatedAt,
-			&operation.UpdatedAt,
-		)
-
+		operation, err := scanOperation(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan operation: %w", err)
 		}
-
-		operation.Status = models.OperationStatus(status)
-		operations = append(operations, operation)
+		operations = append(operations, *operation)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -344,38 +501,19 @@ func (r *PostgresRepo) GetAllOperations(ctx context.Context) ([]models.Operation
 // GetBlockByID получает блок по ID
 func (r *PostgresRepo) GetBlockByID(ctx context.Context, blockID uuid.UUID) (*models.Block, error) {
 	query := `
-		SELECT id, operation_id, block_type, platform, content, html, created_at
-		FROM blocks
-		WHERE id = $1
+		SELECT ` + blockColumns + `
+		FROM blocks b
+		LEFT JOIN block_html h ON h.hash = b.html_hash
+		WHERE b.id = $1
 	`
 
-	var block models.Block
-	var blockType, platform string
-	var contentJSON []byte
-
-	err := r.db.QueryRowContext(ctx, query, blockID).Scan(
-		&block.ID,
-		&block.OperationID,
-		&blockType,
-		&platform,
-		&contentJSON,
-		&block.HTML,
-		&block.CreatedAt,
-	)
-
+	block, err := scanBlock(r.db.QueryRowContext(ctx, query, blockID))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("block not found: %s", blockID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("block %w: %s", ErrNotFound, blockID)
 		}
-		return nil, fmt.Errorf("failed to get block: %w", err)
-	}
-
-	block.BlockType = models.BlockType(blockType)
-	block.Platform = models.Platform(platform)
-
-	if err := json.Unmarshal(contentJSON, &block.Content); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal block content: %w", err)
+		return nil, err
 	}
 
-	return &block, nil
+	return block, nil
 }
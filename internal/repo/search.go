@@ -0,0 +1,118 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"website-scraper/internal/models"
+)
+
+// blockSearchQuerySQL объединяет разбор запроса в русской и английской конфигурациях:
+// блок подходит, если совпадает хотя бы один вариант
+const blockSearchQuerySQL = `(websearch_to_tsquery('russian', $%[1]d) || websearch_to_tsquery('english', $%[1]d))`
+
+// likePattern экранирует спецсимволы LIKE и ищет значение как подстроку
+func likePattern(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+	return "%" + value + "%"
+}
+
+// SearchBlocks ищет блоки всех операций по полнотекстовому запросу и фильтрам. С запросом блоки
+// сортируются по релевантности, без него — начиная с последних сохраненных
+func (r *PostgresRepo) SearchBlocks(ctx context.Context, query models.BlockSearchQuery) (*models.BlockSearchResponse, error) {
+	var args []interface{}
+	conditions := []string{"TRUE"}
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	rank := "0"
+	order := "b.created_at DESC, b.id"
+	if query.Query != "" {
+		addCondition("b.search_vector @@ "+blockSearchQuerySQL, query.Query)
+		rank = fmt.Sprintf("ts_rank(b.search_vector, "+blockSearchQuerySQL+")", len(args))
+		order = "rank DESC, " + order
+	}
+	if query.Platform != "" {
+		addCondition("b.platform = $%d", query.Platform)
+	}
+	if query.BlockType != "" {
+		addCondition("b.block_type = $%d", query.BlockType)
+	}
+	if query.TemplateName != "" {
+		addCondition("b.content->>'template_name' ILIKE $%d", likePattern(query.TemplateName))
+	}
+	if query.Domain != "" {
+		addCondition(operationDomainSQL+" = $%d", query.Domain)
+	}
+	if query.From != nil {
+		addCondition("b.created_at >= $%d", *query.From)
+	}
+	if query.To != nil {
+		addCondition("b.created_at < $%d", *query.To)
+	}
+	where := strings.Join(conditions, " AND ")
+
+	response := &models.BlockSearchResponse{
+		Blocks: []models.BlockSearchResult{},
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	}
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM blocks b
+		JOIN operations o ON o.id = b.operation_id
+		WHERE `+where+`
+	`, args...).Scan(&response.Total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count blocks: %w", err)
+	}
+
+	args = append(args, query.Limit, query.Offset)
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT b.id, b.operation_id, o.url, COALESCE(`+operationDomainSQL+`, ''), b.block_type, b.platform,
+			COALESCE(b.content->>'template_name', ''), `+rank+` AS rank, b.created_at
+		FROM blocks b
+		JOIN operations o ON o.id = b.operation_id
+		WHERE `+where+`
+		ORDER BY `+order+`
+		LIMIT $%d OFFSET $%d
+	`, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search blocks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var result models.BlockSearchResult
+		var blockType, platform string
+
+		err := rows.Scan(
+			&result.BlockID,
+			&result.OperationID,
+			&result.URL,
+			&result.Domain,
+			&blockType,
+			&platform,
+			&result.TemplateName,
+			&result.Rank,
+			&result.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan found block: %w", err)
+		}
+
+		result.BlockType = models.BlockType(blockType)
+		result.Platform = models.Platform(platform)
+		response.Blocks = append(response.Blocks, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating found blocks: %w", err)
+	}
+
+	return response, nil
+}
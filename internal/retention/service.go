@@ -0,0 +1,332 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/fx"
+
+	"website-scraper/internal/config"
+	"website-scraper/internal/downloader"
+	"website-scraper/internal/models"
+	"website-scraper/internal/repo"
+)
+
+// retentionPageSize задает число операций, выбираемых за один запрос при применении политики
+const retentionPageSize = 100
+
+// ErrOperationRunning возвращается при удалении операции, разбор которой еще не завершен
+var ErrOperationRunning = errors.New("операция еще выполняется")
+
+// RetentionService интерфейс для удаления операций и политики хранения
+type RetentionService interface {
+	// DeleteOperation удаляет операцию вместе с блоками и файлами в хранилище
+	DeleteOperation(ctx context.Context, operationID uuid.UUID) (*models.PurgedOperation, error)
+	// Apply удаляет операции старше срока хранения и самые старые операции, пока файлы занимают
+	// больше допустимого места. В пробном запуске возвращает те же операции, ничего не удаляя
+	Apply(ctx context.Context, dryRun bool) (*models.RetentionReport, error)
+}
+
+// retentionService реализация RetentionService. Фоновая очистка периодически применяет политику хранения
+type retentionService struct {
+	cfg        *config.Config
+	repo       repo.ParserRepo
+	monitors   repo.MonitorRepo
+	downloader *downloader.Downloader
+
+	// mu не дает удалять операции одновременно из запроса и фоновой очистки
+	mu sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+// NewRetentionService создает новый экземпляр RetentionService
+func NewRetentionService(cfg *config.Config, operations repo.ParserRepo, monitors repo.MonitorRepo, downloader *downloader.Downloader) *retentionService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &retentionService{
+		cfg:        cfg,
+		repo:       operations,
+		monitors:   monitors,
+		downloader: downloader,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// DeleteOperation удаляет операцию. Последний HTML страницы по URL удаляется, только если
+// других операций этого адреса не осталось
+func (s *retentionService) DeleteOperation(ctx context.Context, operationID uuid.UUID) (*models.PurgedOperation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	operation, err := s.repo.GetOperationByID(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+	if !finished(operation.Status) {
+		return nil, ErrOperationRunning
+	}
+
+	purged, pageURL, err := s.describe(ctx, *operation, models.PurgeManual, make(map[string]int))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.remove(ctx, purged, pageURL); err != nil {
+		return nil, err
+	}
+
+	return purged, nil
+}
+
+// Apply применяет политику хранения. Операции перебираются от самых старых: сначала удаляются
+// операции старше срока хранения, затем, пока файлы занимают больше допустимого, следующие по возрасту
+func (s *retentionService) Apply(ctx context.Context, dryRun bool) (*models.RetentionReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	maxAge := s.cfg.Retention.MaxAge
+	maxDisk := int64(s.cfg.Retention.MaxDiskMB) << 20
+
+	report := &models.RetentionReport{
+		DryRun:       dryRun,
+		MaxDiskBytes: maxDisk,
+		Operations:   []models.PurgedOperation{},
+		StartedAt:    time.Now(),
+	}
+	if maxAge > 0 {
+		report.MaxAge = maxAge.String()
+	}
+
+	// Занятое место считается только при ограничении: в S3 для этого перечисляется весь бакет
+	var usage int64
+	if maxDisk > 0 {
+		disk, err := s.downloader.DiskUsage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		report.DiskBytes = disk.Bytes
+		usage = disk.Bytes
+	}
+
+	protected, err := s.monitorBaselines(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := report.StartedAt.Add(-maxAge)
+	remaining := make(map[string]int)
+	query := models.OperationListQuery{Sort: models.SortCreatedAsc, Limit: retentionPageSize}
+
+	for done := maxAge <= 0 && maxDisk <= 0; !done; {
+		operations, err := s.repo.ListOperations(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, summary := range operations {
+			var reason models.PurgeReason
+			switch {
+			case maxAge > 0 && summary.CreatedAt.Before(cutoff):
+				reason = models.PurgeMaxAge
+			case maxDisk > 0 && usage > maxDisk:
+				reason = models.PurgeMaxDisk
+			default:
+				// Следующие операции новее и места уже достаточно
+				done = true
+			}
+			if done {
+				break
+			}
+
+			if !finished(summary.Status) || protected[summary.ID] {
+				report.Kept++
+				continue
+			}
+
+			purged, pageURL, err := s.describe(ctx, summary.Operation, reason, remaining)
+			if err != nil {
+				return nil, err
+			}
+			purged.Blocks = summary.BlockCount
+
+			if !dryRun {
+				if err := s.remove(ctx, purged, pageURL); err != nil {
+					log.Printf("Error purging operation %s: %v", purged.OperationID, err)
+					continue
+				}
+			}
+
+			usage -= purged.Bytes
+			report.Operations = append(report.Operations, *purged)
+			report.Blocks += purged.Blocks
+			report.Files += purged.Files
+			report.Bytes += purged.Bytes
+		}
+
+		if len(operations) < query.Limit {
+			break
+		}
+		last := operations[len(operations)-1]
+		query.After = &models.OperationCursor{Value: last.CreatedAt.Format(time.RFC3339Nano), ID: last.ID}
+	}
+
+	if maxDisk > 0 {
+		report.DiskBytesAfter = usage
+	}
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// describe описывает удаление операции: ее файлы в хранилище и место, которое они занимают.
+// remaining считает оставшиеся операции каждого адреса, чтобы общий HTML страницы удалялся
+// вместе с последней из них. Возвращает адрес страницы, если ее HTML удаляется
+func (s *retentionService) describe(ctx context.Context, operation models.Operation, reason models.PurgeReason, remaining map[string]int) (*models.PurgedOperation, string, error) {
+	count, ok := remaining[operation.URL]
+	if !ok {
+		var err error
+		if count, err = s.repo.CountOperationsByURL(ctx, operation.URL); err != nil {
+			return nil, "", err
+		}
+	}
+	remaining[operation.URL] = count - 1
+
+	pageURL := ""
+	if count <= 1 {
+		pageURL = operation.URL
+	}
+
+	usage, err := s.downloader.OperationFileUsage(ctx, operation.ID, pageURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &models.PurgedOperation{
+		OperationID: operation.ID,
+		URL:         operation.URL,
+		Status:      operation.Status,
+		CreatedAt:   operation.CreatedAt,
+		Reason:      reason,
+		Files:       usage.Files,
+		Bytes:       usage.Bytes,
+	}, pageURL, nil
+}
+
+// remove удаляет операцию из БД, а затем ее файлы. Файлы удаляются после записей,
+// чтобы API не ссылался на удаленные файлы существующей операции
+func (s *retentionService) remove(ctx context.Context, purged *models.PurgedOperation, pageURL string) error {
+	blocks, err := s.repo.DeleteOperation(ctx, purged.OperationID)
+	if err != nil {
+		return err
+	}
+	purged.Blocks = blocks
+
+	return s.downloader.DeleteOperationFiles(ctx, purged.OperationID, pageURL)
+}
+
+// monitorBaselines возвращает последние операции мониторов: с ними сравнивается следующая проверка
+func (s *retentionService) monitorBaselines(ctx context.Context) (map[uuid.UUID]bool, error) {
+	monitors, err := s.monitors.GetAllMonitors(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	baselines := make(map[uuid.UUID]bool)
+	for _, monitor := range monitors {
+		if monitor.LastOperationID != nil {
+			baselines[*monitor.LastOperationID] = true
+		}
+	}
+	return baselines, nil
+}
+
+// finished проверяет, что разбор операции завершен и ее можно удалить
+func finished(status models.OperationStatus) bool {
+	return status == models.StatusCompleted || status == models.StatusError
+}
+
+// Start запускает фоновую очистку, если задан срок хранения или допустимое место в хранилище
+func (s *retentionService) Start() {
+	interval := s.cfg.Retention.Interval
+	if interval <= 0 || (s.cfg.Retention.MaxAge <= 0 && s.cfg.Retention.MaxDiskMB <= 0) {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.run()
+
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close останавливает фоновую очистку
+func (s *retentionService) Close() {
+	s.once.Do(func() {
+		s.cancel()
+		s.wg.Wait()
+	})
+}
+
+// run применяет политику хранения и записывает результат в журнал
+func (s *retentionService) run() {
+	dryRun := s.cfg.Retention.DryRun
+
+	report, err := s.Apply(s.ctx, dryRun)
+	if err != nil {
+		if s.ctx.Err() == nil {
+			log.Printf("Error applying retention policy: %v", err)
+		}
+		return
+	}
+
+	if len(report.Operations) == 0 {
+		return
+	}
+	if dryRun {
+		log.Printf("Retention dry run: %d operations (%d blocks, %d files, %d bytes) would be purged",
+			len(report.Operations), report.Blocks, report.Files, report.Bytes)
+		return
+	}
+	log.Printf("Retention: purged %d operations (%d blocks, %d files, %d bytes)",
+		len(report.Operations), report.Blocks, report.Files, report.Bytes)
+}
+
+// Module регистрирует сервис хранения и фоновую очистку
+var Module = fx.Module("retention",
+	fx.Provide(
+		NewRetentionService,
+		func(service *retentionService) RetentionService {
+			return service
+		},
+	),
+	fx.Invoke(func(lc fx.Lifecycle, service *retentionService) {
+		lc.Append(fx.Hook{
+			OnStart: func(ctx context.Context) error {
+				service.Start()
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				service.Close()
+				return nil
+			},
+		})
+	}),
+)
@@ -0,0 +1,282 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FilesPath задает путь API, по которому локальное хранилище отдает файлы по подписанным ссылкам
+const FilesPath = "/api/v1/files/"
+
+// ErrInvalidLink возвращается для ссылки с неверной подписью или истекшим сроком действия
+var ErrInvalidLink = errors.New("ссылка недействительна или устарела")
+
+// LinkVerifier проверяет подписанные ссылки хранилища, файлы которого отдает сам сервис
+type LinkVerifier interface {
+	// VerifyLink проверяет подпись и срок действия ссылки на файл и возвращает имя файла для скачивания
+	VerifyLink(key string, query url.Values) (string, error)
+}
+
+// Local хранит файлы в директории на диске. Подходит для одного экземпляра сервиса:
+// ссылки на скачивание ведут на сам сервис и подписываются HMAC
+type Local struct {
+	root       string
+	publicURL  string
+	signingKey []byte
+}
+
+// NewLocal создает хранилище в директории root
+func NewLocal(root, publicURL, signingKey string) (*Local, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("ошибка создания директории %s: %w", root, err)
+	}
+
+	key := []byte(signingKey)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("ошибка создания ключа подписи: %w", err)
+		}
+		log.Printf("STORAGE_SIGNING_KEY не задан: ссылки на файлы перестанут действовать после перезапуска")
+	}
+
+	return &Local{
+		root:       root,
+		publicURL:  strings.TrimRight(publicURL, "/"),
+		signingKey: key,
+	}, nil
+}
+
+// path возвращает путь к файлу на диске
+func (l *Local) path(key string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
+
+// Put сохраняет файл во временный файл рядом и переименовывает его, чтобы читатели
+// не увидели недописанный файл
+func (l *Local) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	filePath, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("ошибка создания директории: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".tmp-"+filepath.Base(filePath)+"-*")
+	if err != nil {
+		return fmt.Errorf("ошибка создания файла %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("ошибка записи файла %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("ошибка записи файла %s: %w", key, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("ошибка записи файла %s: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return fmt.Errorf("ошибка записи файла %s: %w", key, err)
+	}
+	return nil
+}
+
+// Get открывает файл на чтение
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	filePath, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return nil, fmt.Errorf("ошибка открытия файла %s: %w", key, err)
+	}
+	return file, nil
+}
+
+// Stat возвращает сведения о файле
+func (l *Local) Stat(ctx context.Context, key string) (*Object, error) {
+	filePath, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil || info.IsDir() {
+		if err == nil || os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return nil, fmt.Errorf("ошибка чтения файла %s: %w", key, err)
+	}
+	return &Object{Key: key, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// List обходит директорию, в которой лежат файлы с префиксом prefix
+func (l *Local) List(ctx context.Context, prefix string) ([]Object, error) {
+	dir := l.root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dirPath, err := l.path(prefix[:i])
+		if err != nil {
+			return nil, err
+		}
+		dir = dirPath
+	}
+
+	var objects []Object
+	err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".tmp-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.root, filePath)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		objects = append(objects, Object{Key: key, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения директории %s: %w", dir, err)
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// Delete удаляет файл
+func (l *Local) Delete(ctx context.Context, key string) error {
+	filePath, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("ошибка удаления %s: %w", key, err)
+	}
+	return nil
+}
+
+// DeletePrefix удаляет файлы с префиксом. Префикс, заканчивающийся на "/", удаляется
+// вместе с директорией
+func (l *Local) DeletePrefix(ctx context.Context, prefix string) error {
+	if dir := strings.TrimSuffix(prefix, "/"); dir != prefix {
+		dirPath, err := l.path(dir)
+		if err != nil {
+			return err
+		}
+		if err := os.RemoveAll(dirPath); err != nil {
+			return fmt.Errorf("ошибка удаления %s: %w", prefix, err)
+		}
+		return nil
+	}
+
+	objects, err := l.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if err := l.Delete(ctx, object.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PresignGet возвращает ссылку на файл через API сервиса
+func (l *Local) PresignGet(ctx context.Context, key string, expires time.Duration, filename string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+
+	expiresAt := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expiresAt)
+	if filename != "" {
+		query.Set("filename", filename)
+	}
+	query.Set("signature", l.sign(key, expiresAt, filename))
+
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return l.publicURL + FilesPath + strings.Join(segments, "/") + "?" + query.Encode(), nil
+}
+
+// VerifyLink проверяет ссылку, выданную PresignGet
+func (l *Local) VerifyLink(key string, query url.Values) (string, error) {
+	expiresAt := query.Get("expires")
+	filename := query.Get("filename")
+
+	expires, err := strconv.ParseInt(expiresAt, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return "", ErrInvalidLink
+	}
+
+	signature, err := hex.DecodeString(query.Get("signature"))
+	if err != nil {
+		return "", ErrInvalidLink
+	}
+	expected, _ := hex.DecodeString(l.sign(key, expiresAt, filename))
+	if !hmac.Equal(signature, expected) {
+		return "", ErrInvalidLink
+	}
+
+	if filename == "" {
+		filename = path.Base(key)
+	}
+	return filename, nil
+}
+
+// sign подписывает ключ файла вместе со сроком действия и именем файла для скачивания
+func (l *Local) sign(key, expiresAt, filename string) string {
+	mac := hmac.New(sha256.New, l.signingKey)
+	mac.Write([]byte(key + "\n" + expiresAt + "\n" + filename))
+	return hex.EncodeToString(mac.Sum(nil))
+}
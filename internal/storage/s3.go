@@ -0,0 +1,420 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// s3Algorithm задает алгоритм подписи запросов AWS Signature Version 4
+	s3Algorithm = "AWS4-HMAC-SHA256"
+	// s3UnsignedPayload отключает подпись тела: файл передается потоком без предварительного хеширования
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	// s3EmptyPayload содержит SHA-256 пустого тела запроса
+	s3EmptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	// s3MaxPresignExpiry ограничивает срок действия подписанной ссылки, как в S3
+	s3MaxPresignExpiry = 7 * 24 * time.Hour
+	// s3ErrorBodyLimit ограничивает размер текста ошибки, читаемого из ответа
+	s3ErrorBodyLimit = 4 << 10
+)
+
+// S3Options задает подключение к S3-совместимому хранилищу
+type S3Options struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool
+}
+
+// S3 хранит файлы в бакете S3-совместимого хранилища: AWS S3, MinIO и других.
+// Запросы подписываются AWS Signature Version 4, ссылки на скачивание выдает само хранилище
+type S3 struct {
+	opts     S3Options
+	endpoint *url.URL
+	prefix   string
+	client   *http.Client
+}
+
+// s3ListResult представляет ответ ListObjectsV2
+type s3ListResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// s3Error представляет ошибку в ответе хранилища
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// NewS3 создает хранилище в бакете S3
+func NewS3(opts S3Options) (*S3, error) {
+	if opts.Endpoint == "" || opts.Bucket == "" {
+		return nil, fmt.Errorf("для хранилища s3 нужны STORAGE_S3_ENDPOINT и STORAGE_S3_BUCKET")
+	}
+
+	endpoint, err := url.Parse(opts.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("неверный адрес хранилища s3 %q", opts.Endpoint)
+	}
+	endpoint.Path = strings.TrimRight(endpoint.Path, "/")
+
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+
+	prefix := strings.Trim(opts.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	return &S3{
+		opts:     opts,
+		endpoint: endpoint,
+		prefix:   prefix,
+		client:   &http.Client{},
+	}, nil
+}
+
+// Put загружает файл в бакет. Файл неизвестного размера сначала читается в память,
+// так как S3 требует Content-Length
+func (s *S3) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+
+	if size < 0 {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return fmt.Errorf("ошибка чтения файла %s: %w", key, err)
+		}
+		body, size = bytes.NewReader(data), int64(len(data))
+	}
+
+	resp, err := s.do(ctx, http.MethodPut, key, nil, body, size, contentType)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get скачивает файл из бакета
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Stat запрашивает сведения о файле без его содержимого
+func (s *S3) Stat(ctx context.Context, key string) (*Object, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(ctx, http.MethodHead, key, nil, nil, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	object := &Object{Key: key, Size: resp.ContentLength}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		object.ModTime = modified
+	}
+	return object, nil
+}
+
+// List перебирает файлы бакета по страницам ListObjectsV2
+func (s *S3) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+
+	query := map[string]string{
+		"list-type": "2",
+		"prefix":    s.prefix + prefix,
+	}
+	for {
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, 0, "")
+		if err != nil {
+			return nil, err
+		}
+
+		var result s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("ошибка разбора списка файлов s3: %w", err)
+		}
+
+		for _, content := range result.Contents {
+			objects = append(objects, Object{
+				Key:     strings.TrimPrefix(content.Key, s.prefix),
+				Size:    content.Size,
+				ModTime: content.LastModified,
+			})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		query["continuation-token"] = result.NextContinuationToken
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// Delete удаляет файл из бакета
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, 0, "")
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// DeletePrefix удаляет файлы с префиксом по одному
+func (s *S3) DeletePrefix(ctx context.Context, prefix string) error {
+	objects, err := s.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if err := s.Delete(ctx, object.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PresignGet возвращает подписанную ссылку на скачивание файла напрямую из хранилища
+func (s *S3) PresignGet(ctx context.Context, key string, expires time.Duration, filename string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	if expires <= 0 || expires > s3MaxPresignExpiry {
+		return "", fmt.Errorf("срок действия ссылки должен быть от 1 секунды до %s", s3MaxPresignExpiry)
+	}
+	return s.presign(key, expires, filename, time.Now().UTC()), nil
+}
+
+// presign подписывает ссылку на скачивание файла временем now
+func (s *S3) presign(key string, expires time.Duration, filename string, now time.Time) string {
+	amzDate := now.Format("20060102T150405Z")
+	scope := s.scope(amzDate)
+	target := s.objectURL(key)
+
+	query := map[string]string{
+		"X-Amz-Algorithm":     s3Algorithm,
+		"X-Amz-Credential":    s.opts.AccessKey + "/" + scope,
+		"X-Amz-Date":          amzDate,
+		"X-Amz-Expires":       strconv.Itoa(int(expires / time.Second)),
+		"X-Amz-SignedHeaders": "host",
+	}
+	if filename != "" {
+		query["response-content-disposition"] = mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+	}
+	canonicalQuery := canonicalQueryString(query)
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		target.EscapedPath(),
+		canonicalQuery,
+		"host:" + target.Host + "\n",
+		"host",
+		s3UnsignedPayload,
+	}, "\n")
+
+	signature := s.signature(amzDate, scope, canonicalRequest)
+	target.RawQuery = canonicalQuery + "&X-Amz-Signature=" + signature
+	return target.String()
+}
+
+// do выполняет подписанный запрос к файлу или, при пустом ключе, к бакету.
+// Ответ вне 2xx превращается в ошибку, отсутствующий файл — в ErrNotFound
+func (s *S3) do(ctx context.Context, method, key string, query map[string]string, body io.Reader, size int64, contentType string) (*http.Response, error) {
+	target := s.objectURL(key)
+	target.RawQuery = canonicalQueryString(query)
+
+	// Пустой файл передается без тела: иначе клиент не знает, что тело пусто, и отправляет его
+	// по частям без Content-Length, чего S3 не принимает
+	if body != nil && size == 0 {
+		body = http.NoBody
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса s3: %w", err)
+	}
+
+	payload := s3EmptyPayload
+	if body != nil {
+		payload = s3UnsignedPayload
+		req.ContentLength = size
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+	}
+	s.sign(req, target, payload, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса s3 %s %s: %w", method, key, err)
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var s3Err s3Error
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, s3ErrorBodyLimit))
+	xml.Unmarshal(data, &s3Err)
+
+	if resp.StatusCode == http.StatusNotFound && s3Err.Code != "NoSuchBucket" {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if s3Err.Code != "" {
+		return nil, fmt.Errorf("ошибка s3 %s %s: %s: %s", method, key, s3Err.Code, s3Err.Message)
+	}
+	return nil, fmt.Errorf("ошибка s3 %s %s: %s", method, key, resp.Status)
+}
+
+// sign добавляет к запросу заголовки подписи Signature Version 4 временем now
+func (s *S3) sign(req *http.Request, target *url.URL, payload string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	scope := s.scope(amzDate)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payload)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		target.EscapedPath(),
+		target.RawQuery,
+		"host:" + target.Host + "\n" +
+			"x-amz-content-sha256:" + payload + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payload,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.opts.AccessKey, scope, signedHeaders, s.signature(amzDate, scope, canonicalRequest)))
+}
+
+// scope возвращает область действия подписи: дату, регион и сервис
+func (s *S3) scope(amzDate string) string {
+	return amzDate[:8] + "/" + s.opts.Region + "/s3/aws4_request"
+}
+
+// signature подписывает канонический запрос ключом, производным от секретного ключа, даты и региона
+func (s *S3) signature(amzDate, scope, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := s3Algorithm + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.opts.SecretKey), amzDate[:8])
+	key = hmacSHA256(key, s.opts.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// objectURL возвращает адрес файла или, при пустом ключе, бакета
+func (s *S3) objectURL(key string) *url.URL {
+	target := *s.endpoint
+
+	objectPath := "/"
+	if key != "" {
+		objectPath += s.prefix + key
+	}
+	if s.opts.PathStyle {
+		objectPath = "/" + s.opts.Bucket + objectPath
+		if key == "" {
+			objectPath = strings.TrimSuffix(objectPath, "/")
+		}
+	} else {
+		target.Host = s.opts.Bucket + "." + target.Host
+	}
+
+	target.Path = s.endpoint.Path + objectPath
+	target.RawPath = uriEncode(s.endpoint.Path, false) + uriEncode(objectPath, false)
+	return &target
+}
+
+// canonicalQueryString кодирует параметры запроса так, как их подписывает S3: по порядку имен
+// и с кодированием всех символов, кроме незарезервированных
+func canonicalQueryString(query map[string]string) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = uriEncode(name, true) + "=" + uriEncode(query[name], true)
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode кодирует строку по правилам Signature Version 4. Символ "/" кодируется только в параметрах
+func uriEncode(value string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			encoded.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			encoded.WriteByte(c)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+	return encoded.String()
+}
+
+// hmacSHA256 возвращает HMAC-SHA256 данных
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
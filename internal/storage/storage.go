@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.uber.org/fx"
+
+	"website-scraper/internal/config"
+)
+
+// ErrNotFound возвращается, если файла с ключом нет в хранилище
+var ErrNotFound = errors.New("файл не найден")
+
+// Object описывает файл в хранилище
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Storage интерфейс хранилища файлов: HTML страниц, файлов блоков, снимков и архивов.
+// Ключи разделяются символом "/", например blocks/{operation_id}/page.png
+type Storage interface {
+	// Put сохраняет файл, перезаписывая существующий. size равен -1, если размер заранее неизвестен
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get открывает файл на чтение. Для отсутствующего файла возвращает ErrNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Stat возвращает сведения о файле. Для отсутствующего файла возвращает ErrNotFound
+	Stat(ctx context.Context, key string) (*Object, error)
+	// List возвращает все файлы, ключи которых начинаются с prefix, в порядке ключей
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// Delete удаляет файл. Удаление отсутствующего файла ошибкой не считается
+	Delete(ctx context.Context, key string) error
+	// DeletePrefix удаляет все файлы, ключи которых начинаются с prefix
+	DeletePrefix(ctx context.Context, prefix string) error
+	// PresignGet возвращает ссылку на скачивание файла, действующую expires.
+	// filename задает имя файла при скачивании, пустое имя оставляет имя из ключа
+	PresignGet(ctx context.Context, key string, expires time.Duration, filename string) (string, error)
+}
+
+// New создает хранилище, выбранное в конфигурации
+func New(cfg *config.Config) (Storage, error) {
+	switch cfg.Storage.Backend {
+	case "", "local":
+		return NewLocal(cfg.Downloader.OutputDir, cfg.Storage.PublicURL, cfg.Storage.SigningKey)
+	case "s3":
+		return NewS3(S3Options{
+			Endpoint:  cfg.Storage.S3Endpoint,
+			Region:    cfg.Storage.S3Region,
+			Bucket:    cfg.Storage.S3Bucket,
+			Prefix:    cfg.Storage.S3Prefix,
+			AccessKey: cfg.Storage.S3AccessKey,
+			SecretKey: cfg.Storage.S3SecretKey,
+			PathStyle: cfg.Storage.S3PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("неизвестное хранилище %q, поддерживаются local и s3", cfg.Storage.Backend)
+	}
+}
+
+// PutBytes сохраняет содержимое файла целиком
+func PutBytes(ctx context.Context, s Storage, key string, data []byte, contentType string) error {
+	return s.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType)
+}
+
+// ReadAll читает файл целиком
+func ReadAll(ctx context.Context, s Storage, key string) ([]byte, error) {
+	body, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// validKey проверяет, что ключ относительный и не выходит за пределы хранилища
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("недопустимый ключ файла %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("недопустимый ключ файла %q", key)
+		}
+	}
+	return nil
+}
+
+// Module регистрирует хранилище файлов
+var Module = fx.Module("storage",
+	fx.Provide(
+		New,
+	),
+)
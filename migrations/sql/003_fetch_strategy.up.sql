@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Способ загрузки страницы, которым фактически выполнена операция: http или browser
+ALTER TABLE operations
+    ADD COLUMN IF NOT EXISTS fetch_strategy VARCHAR(20) NULL
+        CHECK (fetch_strategy IN ('http', 'browser'));
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE operations DROP COLUMN IF EXISTS fetch_strategy;
+
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Метаданные ответа на запрос документа: статус, итоговый URL, заголовки, TLS и тайминги
+ALTER TABLE operations
+    ADD COLUMN IF NOT EXISTS response JSONB NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE operations DROP COLUMN IF EXISTS response;
+
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Источник HTML операции и исходная операция для повторного разбора
+ALTER TABLE operations
+    ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'fetch'
+        CHECK (source IN ('fetch', 'reparse')),
+    ADD COLUMN IF NOT EXISTS parent_id UUID NULL
+        REFERENCES operations(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_operations_parent_id ON operations(parent_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_operations_parent_id;
+ALTER TABLE operations
+    DROP COLUMN IF EXISTS parent_id,
+    DROP COLUMN IF EXISTS source;
+
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Операции могут разбирать HTML, загруженный пользователем
+ALTER TABLE operations DROP CONSTRAINT IF EXISTS operations_source_check;
+ALTER TABLE operations
+    ADD CONSTRAINT operations_source_check CHECK (source IN ('fetch', 'reparse', 'upload'));
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE operations DROP CONSTRAINT IF EXISTS operations_source_check;
+ALTER TABLE operations
+    ADD CONSTRAINT operations_source_check CHECK (source IN ('fetch', 'reparse'));
+
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Устройство, эмулированное при загрузке страницы
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS device JSONB;
+
+-- Блоки помечаются устройством, на котором разобрана страница
+ALTER TABLE blocks
+    ADD COLUMN IF NOT EXISTS viewport VARCHAR(20) NOT NULL DEFAULT 'desktop'
+        CHECK (viewport IN ('desktop', 'tablet', 'mobile', 'custom'));
+
+CREATE INDEX IF NOT EXISTS idx_blocks_viewport ON blocks(viewport);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_blocks_viewport;
+ALTER TABLE blocks DROP COLUMN IF EXISTS viewport;
+ALTER TABLE operations DROP COLUMN IF EXISTS device;
+
+-- +goose StatementEnd
@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Мониторы, периодически разбирающие страницу и сравнивающие блоки с прошлой проверкой
+CREATE TABLE IF NOT EXISTS monitors (
+    id                UUID                     PRIMARY KEY DEFAULT uuid_generate_v4(),
+    url               TEXT                     NOT NULL,
+    schedule          VARCHAR(100)             NOT NULL,
+    options           JSONB                    NOT NULL DEFAULT '{}',
+    enabled           BOOLEAN                  NOT NULL DEFAULT TRUE,
+    last_operation_id UUID                     REFERENCES operations(id) ON DELETE SET NULL,
+    last_run_at       TIMESTAMP WITH TIME ZONE,
+    last_changed_at   TIMESTAMP WITH TIME ZONE,
+    next_run_at       TIMESTAMP WITH TIME ZONE,
+    last_error        TEXT                     NOT NULL DEFAULT '',
+    created_at        TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at        TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_monitors_next_run_at ON monitors(next_run_at) WHERE enabled;
+
+CREATE TRIGGER update_monitors_updated_at
+    BEFORE UPDATE ON monitors
+    FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+-- История изменений, найденных мониторами
+CREATE TABLE IF NOT EXISTS monitor_changes (
+    id                    UUID                     PRIMARY KEY DEFAULT uuid_generate_v4(),
+    monitor_id            UUID                     NOT NULL
+        REFERENCES monitors(id) ON DELETE CASCADE,
+    operation_id          UUID                     REFERENCES operations(id) ON DELETE SET NULL,
+    previous_operation_id UUID                     REFERENCES operations(id) ON DELETE SET NULL,
+    summary               JSONB                    NOT NULL,
+    changes               JSONB                    NOT NULL,
+    created_at            TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_monitor_changes_monitor_id ON monitor_changes(monitor_id, created_at DESC);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS monitor_changes;
+DROP TRIGGER IF EXISTS update_monitors_updated_at ON monitors;
+DROP TABLE IF EXISTS monitors;
+
+-- +goose StatementEnd
@@ -0,0 +1,65 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- HTML блоков хранится один раз для всех одинаковых блоков, ключ — SHA-256 HTML
+CREATE TABLE IF NOT EXISTS block_html (
+    hash       CHAR(64)                 PRIMARY KEY,
+    html       TEXT                     NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Группы одинаковых и почти одинаковых блоков. Отпечаток simhash разбит на восемь частей
+-- по 8 бит: у отпечатков, различающихся не более чем в семи битах, совпадает хотя бы одна часть
+CREATE TABLE IF NOT EXISTS block_groups (
+    id          UUID                     PRIMARY KEY DEFAULT uuid_generate_v4(),
+    block_type  VARCHAR(20)              NOT NULL,
+    fingerprint BIGINT                   NOT NULL,
+    band0       INT                      NOT NULL,
+    band1       INT                      NOT NULL,
+    band2       INT                      NOT NULL,
+    band3       INT                      NOT NULL,
+    band4       INT                      NOT NULL,
+    band5       INT                      NOT NULL,
+    band6       INT                      NOT NULL,
+    band7       INT                      NOT NULL,
+    html_hash   CHAR(64)                 REFERENCES block_html(hash),
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_block_groups_band0 ON block_groups(block_type, band0);
+CREATE INDEX IF NOT EXISTS idx_block_groups_band1 ON block_groups(block_type, band1);
+CREATE INDEX IF NOT EXISTS idx_block_groups_band2 ON block_groups(block_type, band2);
+CREATE INDEX IF NOT EXISTS idx_block_groups_band3 ON block_groups(block_type, band3);
+CREATE INDEX IF NOT EXISTS idx_block_groups_band4 ON block_groups(block_type, band4);
+CREATE INDEX IF NOT EXISTS idx_block_groups_band5 ON block_groups(block_type, band5);
+CREATE INDEX IF NOT EXISTS idx_block_groups_band6 ON block_groups(block_type, band6);
+CREATE INDEX IF NOT EXISTS idx_block_groups_band7 ON block_groups(block_type, band7);
+
+-- Новые блоки ссылаются на HTML в block_html, колонка html остается пустой
+ALTER TABLE blocks
+    ADD COLUMN IF NOT EXISTS html_hash   CHAR(64) REFERENCES block_html(hash),
+    ADD COLUMN IF NOT EXISTS fingerprint BIGINT,
+    ADD COLUMN IF NOT EXISTS group_id    UUID REFERENCES block_groups(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_blocks_group_id  ON blocks(group_id);
+CREATE INDEX IF NOT EXISTS idx_blocks_html_hash ON blocks(html_hash);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- Возвращаем HTML в блоки перед удалением общего хранилища
+UPDATE blocks b SET html = h.html FROM block_html h WHERE b.html_hash = h.hash;
+
+DROP INDEX IF EXISTS idx_blocks_html_hash;
+DROP INDEX IF EXISTS idx_blocks_group_id;
+ALTER TABLE blocks
+    DROP COLUMN IF EXISTS group_id,
+    DROP COLUMN IF EXISTS fingerprint,
+    DROP COLUMN IF EXISTS html_hash;
+
+DROP TABLE IF EXISTS block_groups;
+DROP TABLE IF EXISTS block_html;
+
+-- +goose StatementEnd
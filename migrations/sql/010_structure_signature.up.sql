@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Структурная подпись блока: simhash дерева тегов и словаря классов без текста и изображений.
+-- structure_bands содержит восемь частей подписи по 8 бит (номер части * 256 + значение):
+-- у подписей, различающихся не более чем в семи битах, совпадает хотя бы одна часть
+ALTER TABLE blocks
+    ADD COLUMN IF NOT EXISTS structure_signature BIGINT,
+    ADD COLUMN IF NOT EXISTS structure_bands     INT[];
+
+CREATE INDEX IF NOT EXISTS idx_blocks_structure_bands ON blocks USING GIN (structure_bands);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_blocks_structure_bands;
+ALTER TABLE blocks
+    DROP COLUMN IF EXISTS structure_bands,
+    DROP COLUMN IF EXISTS structure_signature;
+
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Полнотекстовый индекс блоков: текст, текстовые атрибуты и токены разметки блока в русской и английской
+-- конфигурациях. Для сохраненных блоков индекс строится сервисом в фоне тем же разбором HTML, что и для новых
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+
+CREATE INDEX IF NOT EXISTS idx_blocks_search_vector ON blocks USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_blocks_created_at    ON blocks(created_at);
+
+-- Домен операции без www, как в отборе операций сайта
+CREATE INDEX IF NOT EXISTS idx_operations_domain ON operations (
+    lower(substring(url from '^[a-zA-Z]+://(?:[^@/]*@)?(?:www\.)?([^/:?#]+)'))
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_operations_domain;
+DROP INDEX IF EXISTS idx_blocks_created_at;
+DROP INDEX IF EXISTS idx_blocks_search_vector;
+ALTER TABLE blocks DROP COLUMN IF EXISTS search_vector;
+
+-- +goose StatementEnd